@@ -7,7 +7,8 @@ import (
 )
 
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound     = errors.New("not found")
+	ErrLimitReached = errors.New("limit reached")
 )
 
 // Manager is a Unit of Work that can be used to access all tables of the underlying database.
@@ -15,12 +16,26 @@ type Manager interface {
 	Users() stores.UsersStore
 	Restaurants() stores.RestaurantsStore
 	Reviews() stores.ReviewsStore
+	RefreshTokens() stores.RefreshTokensStore
+	EmailOutbox() stores.EmailOutboxStore
+	RestaurantImages() stores.RestaurantImagesStore
+	ReviewMedia() stores.ReviewMediaStore
+	OpeningHours() stores.OpeningHoursStore
+	Tags() stores.TagsStore
+	Favorites() stores.FavoritesStore
 }
 
 type manager struct {
-	users       stores.UsersStore
-	restaurants stores.RestaurantsStore
-	reviews     stores.ReviewsStore
+	users            stores.UsersStore
+	restaurants      stores.RestaurantsStore
+	reviews          stores.ReviewsStore
+	refreshTokens    stores.RefreshTokensStore
+	emailOutbox      stores.EmailOutboxStore
+	restaurantImages stores.RestaurantImagesStore
+	reviewMedia      stores.ReviewMediaStore
+	openingHours     stores.OpeningHoursStore
+	tags             stores.TagsStore
+	favorites        stores.FavoritesStore
 }
 
 func (m *manager) Users() stores.UsersStore {
@@ -35,10 +50,56 @@ func (m *manager) Reviews() stores.ReviewsStore {
 	return m.reviews
 }
 
-func NewManager(users stores.UsersStore, restaurants stores.RestaurantsStore, reviews stores.ReviewsStore) Manager {
+func (m *manager) RefreshTokens() stores.RefreshTokensStore {
+	return m.refreshTokens
+}
+
+func (m *manager) EmailOutbox() stores.EmailOutboxStore {
+	return m.emailOutbox
+}
+
+func (m *manager) RestaurantImages() stores.RestaurantImagesStore {
+	return m.restaurantImages
+}
+
+func (m *manager) ReviewMedia() stores.ReviewMediaStore {
+	return m.reviewMedia
+}
+
+func (m *manager) OpeningHours() stores.OpeningHoursStore {
+	return m.openingHours
+}
+
+func (m *manager) Tags() stores.TagsStore {
+	return m.tags
+}
+
+func (m *manager) Favorites() stores.FavoritesStore {
+	return m.favorites
+}
+
+func NewManager(
+	users stores.UsersStore,
+	restaurants stores.RestaurantsStore,
+	reviews stores.ReviewsStore,
+	refreshTokens stores.RefreshTokensStore,
+	emailOutbox stores.EmailOutboxStore,
+	restaurantImages stores.RestaurantImagesStore,
+	reviewMedia stores.ReviewMediaStore,
+	openingHours stores.OpeningHoursStore,
+	tags stores.TagsStore,
+	favorites stores.FavoritesStore,
+) Manager {
 	return &manager{
-		users:       users,
-		restaurants: restaurants,
-		reviews:     reviews,
+		users:            users,
+		restaurants:      restaurants,
+		reviews:          reviews,
+		refreshTokens:    refreshTokens,
+		emailOutbox:      emailOutbox,
+		restaurantImages: restaurantImages,
+		reviewMedia:      reviewMedia,
+		openingHours:     openingHours,
+		tags:             tags,
+		favorites:        favorites,
 	}
 }
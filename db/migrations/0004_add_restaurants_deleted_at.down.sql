@@ -0,0 +1,10 @@
+DROP INDEX idx_owner_id;
+
+DROP INDEX idx_average_rating;
+
+CREATE INDEX idx_owner_id ON restaurants (owner_id, average_rating DESC);
+
+CREATE INDEX idx_average_rating ON restaurants (average_rating DESC);
+
+ALTER TABLE restaurants
+    DROP COLUMN deleted_at;
@@ -0,0 +1,10 @@
+ALTER TABLE restaurants
+ADD COLUMN deleted_at timestamp;
+
+DROP INDEX idx_owner_id;
+
+DROP INDEX idx_average_rating;
+
+CREATE INDEX idx_owner_id ON restaurants (owner_id, average_rating DESC) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_average_rating ON restaurants (average_rating DESC) WHERE deleted_at IS NULL;
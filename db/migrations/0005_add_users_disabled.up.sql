@@ -0,0 +1,2 @@
+ALTER TABLE users
+ADD COLUMN disabled boolean NOT NULL DEFAULT false;
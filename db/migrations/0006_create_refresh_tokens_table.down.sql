@@ -0,0 +1,6 @@
+DROP INDEX idx_refresh_tokens_user_id;
+
+DROP TABLE refresh_tokens;
+
+ALTER TABLE users
+    DROP COLUMN token_version;
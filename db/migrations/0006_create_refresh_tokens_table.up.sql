@@ -0,0 +1,14 @@
+ALTER TABLE users
+ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE refresh_tokens (
+    id uuid PRIMARY KEY,
+    user_id uuid REFERENCES users (id) NOT NULL,
+    hashed_token VARCHAR (64) UNIQUE NOT NULL,
+    created_at timestamp NOT NULL,
+    expires_at timestamp NOT NULL,
+    revoked_at timestamp,
+    replaced_by uuid REFERENCES refresh_tokens (id)
+);
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN password_reset_token,
+    DROP COLUMN password_reset_token_expires_at;
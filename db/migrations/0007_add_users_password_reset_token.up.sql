@@ -0,0 +1,3 @@
+ALTER TABLE users
+ADD COLUMN password_reset_token VARCHAR (30),
+ADD COLUMN password_reset_token_expires_at timestamp;
@@ -0,0 +1,2 @@
+ALTER TABLE users
+    DROP COLUMN email_confirmation_token_created_at;
@@ -0,0 +1,6 @@
+ALTER TABLE users
+ADD COLUMN email_confirmation_token_created_at timestamp;
+
+UPDATE users
+SET email_confirmation_token_created_at = now() at time zone 'utc'
+WHERE email_confirmation_token IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX idx_email_outbox_pending;
+
+DROP TABLE email_outbox;
+
+DROP TYPE email_status;
@@ -0,0 +1,16 @@
+CREATE TYPE email_status AS ENUM ('pending', 'sent', 'dead');
+
+CREATE TABLE email_outbox (
+    id uuid PRIMARY KEY,
+    recipient VARCHAR (64) NOT NULL,
+    subject VARCHAR (200) NOT NULL,
+    message TEXT NOT NULL,
+    status email_status NOT NULL,
+    attempts INTEGER NOT NULL,
+    next_attempt_at timestamp NOT NULL,
+    last_error TEXT,
+    created_at timestamp NOT NULL,
+    sent_at timestamp
+);
+
+CREATE INDEX idx_email_outbox_pending ON email_outbox (next_attempt_at) WHERE status = 'pending';
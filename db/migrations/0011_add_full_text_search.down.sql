@@ -0,0 +1,9 @@
+DROP INDEX idx_reviews_search_vector;
+
+ALTER TABLE reviews
+    DROP COLUMN search_vector;
+
+DROP INDEX idx_restaurants_search_vector;
+
+ALTER TABLE restaurants
+    DROP COLUMN search_vector;
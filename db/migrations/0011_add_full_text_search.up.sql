@@ -0,0 +1,13 @@
+ALTER TABLE restaurants
+ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', name), 'A') ||
+    setweight(to_tsvector('english', city), 'B') ||
+    setweight(to_tsvector('english', description), 'C')
+) STORED;
+
+CREATE INDEX idx_restaurants_search_vector ON restaurants USING gin (search_vector);
+
+ALTER TABLE reviews
+ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (to_tsvector('english', comment)) STORED;
+
+CREATE INDEX idx_reviews_search_vector ON reviews USING gin (search_vector);
@@ -0,0 +1,12 @@
+DROP INDEX idx_reviews_unanswered;
+
+DROP INDEX idx_restaurants_created_at;
+
+DROP INDEX idx_restaurants_name;
+
+DROP INDEX idx_restaurants_ratings_count;
+
+DROP INDEX idx_restaurants_city;
+
+ALTER TABLE restaurants
+    DROP COLUMN created_at;
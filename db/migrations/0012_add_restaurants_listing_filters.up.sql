@@ -0,0 +1,12 @@
+ALTER TABLE restaurants
+ADD COLUMN created_at timestamp NOT NULL DEFAULT (now() AT TIME ZONE 'utc');
+
+CREATE INDEX idx_restaurants_city ON restaurants (lower(city) text_pattern_ops) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_restaurants_ratings_count ON restaurants (ratings_count DESC, id) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_restaurants_name ON restaurants (name, id) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_restaurants_created_at ON restaurants (created_at DESC, id) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_reviews_unanswered ON reviews (restaurant_id) WHERE answer IS NULL;
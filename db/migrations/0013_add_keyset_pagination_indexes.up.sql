@@ -0,0 +1,7 @@
+DROP INDEX idx_average_rating;
+
+CREATE INDEX idx_average_rating ON restaurants (average_rating DESC, id) WHERE deleted_at IS NULL;
+
+DROP INDEX idx_restaurant_id;
+
+CREATE INDEX idx_restaurant_id ON reviews (restaurant_id, timestamp DESC, id);
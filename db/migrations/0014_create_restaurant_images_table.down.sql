@@ -0,0 +1 @@
+DROP TABLE restaurant_images;
@@ -0,0 +1,16 @@
+CREATE TABLE restaurant_images (
+    id uuid PRIMARY KEY,
+    restaurant_id uuid REFERENCES restaurants (id) ON DELETE CASCADE NOT NULL,
+    storage_key VARCHAR (200) NOT NULL,
+    thumbnail_key VARCHAR (200) NOT NULL,
+    content_type VARCHAR (30) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size INTEGER NOT NULL,
+    is_cover boolean NOT NULL DEFAULT false,
+    created_at timestamp NOT NULL
+);
+
+CREATE INDEX idx_restaurant_images_restaurant_id ON restaurant_images (restaurant_id, created_at);
+
+CREATE UNIQUE INDEX idx_restaurant_images_cover ON restaurant_images (restaurant_id) WHERE is_cover;
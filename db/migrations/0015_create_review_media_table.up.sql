@@ -0,0 +1,13 @@
+CREATE TABLE review_media (
+    id uuid PRIMARY KEY,
+    review_id uuid REFERENCES reviews (id) ON DELETE CASCADE NOT NULL,
+    storage_key VARCHAR (200) NOT NULL,
+    thumbnail_key VARCHAR (200) NOT NULL,
+    content_type VARCHAR (30) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size INTEGER NOT NULL,
+    created_at timestamp NOT NULL
+);
+
+CREATE INDEX idx_review_media_review_id ON review_media (review_id, created_at);
@@ -0,0 +1,27 @@
+ALTER TABLE restaurants
+DROP COLUMN average_rating,
+DROP COLUMN food_average_rating,
+DROP COLUMN food_ratings_total,
+DROP COLUMN food_ratings_count,
+DROP COLUMN service_average_rating,
+DROP COLUMN service_ratings_total,
+DROP COLUMN service_ratings_count,
+DROP COLUMN ambience_average_rating,
+DROP COLUMN ambience_ratings_total,
+DROP COLUMN ambience_ratings_count,
+DROP COLUMN value_average_rating,
+DROP COLUMN value_ratings_total,
+DROP COLUMN value_ratings_count;
+
+ALTER TABLE restaurants
+ADD COLUMN average_rating REAL GENERATED ALWAYS AS (ratings_total / greatest(ratings_count, 1)) STORED;
+
+CREATE INDEX idx_owner_id ON restaurants (owner_id, average_rating DESC) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_average_rating ON restaurants (average_rating DESC, id) WHERE deleted_at IS NULL;
+
+ALTER TABLE reviews
+DROP COLUMN food_rating,
+DROP COLUMN service_rating,
+DROP COLUMN ambience_rating,
+DROP COLUMN value_rating;
@@ -0,0 +1,29 @@
+ALTER TABLE reviews
+ADD COLUMN food_rating SMALLINT CHECK (food_rating > 0 AND food_rating < 6),
+ADD COLUMN service_rating SMALLINT CHECK (service_rating > 0 AND service_rating < 6),
+ADD COLUMN ambience_rating SMALLINT CHECK (ambience_rating > 0 AND ambience_rating < 6),
+ADD COLUMN value_rating SMALLINT CHECK (value_rating > 0 AND value_rating < 6);
+
+-- A generated column cannot be changed, so the average rating is added again with a real division instead of an integer one
+ALTER TABLE restaurants
+DROP COLUMN average_rating;
+
+ALTER TABLE restaurants
+ADD COLUMN average_rating REAL GENERATED ALWAYS AS (ratings_total::real / greatest(ratings_count, 1)) STORED,
+ADD COLUMN food_ratings_total INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN food_ratings_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN food_average_rating REAL GENERATED ALWAYS AS (food_ratings_total::real / NULLIF(food_ratings_count, 0)) STORED,
+ADD COLUMN service_ratings_total INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN service_ratings_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN service_average_rating REAL GENERATED ALWAYS AS (service_ratings_total::real / NULLIF(service_ratings_count, 0)) STORED,
+ADD COLUMN ambience_ratings_total INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN ambience_ratings_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN ambience_average_rating REAL GENERATED ALWAYS AS (ambience_ratings_total::real / NULLIF(ambience_ratings_count, 0)) STORED,
+ADD COLUMN value_ratings_total INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN value_ratings_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN value_average_rating REAL GENERATED ALWAYS AS (value_ratings_total::real / NULLIF(value_ratings_count, 0)) STORED;
+
+-- The indexes on the average rating were dropped together with the column
+CREATE INDEX idx_owner_id ON restaurants (owner_id, average_rating DESC) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_average_rating ON restaurants (average_rating DESC, id) WHERE deleted_at IS NULL;
@@ -0,0 +1,10 @@
+DROP INDEX idx_restaurants_location;
+
+ALTER TABLE restaurants
+DROP CONSTRAINT restaurants_location_complete,
+DROP COLUMN latitude,
+DROP COLUMN longitude;
+
+DROP EXTENSION IF EXISTS earthdistance;
+
+DROP EXTENSION IF EXISTS cube;
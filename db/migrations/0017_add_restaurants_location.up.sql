@@ -0,0 +1,11 @@
+-- earthdistance provides ll_to_earth and earth_box, which allow radius searches on a GiST index without PostGIS
+CREATE EXTENSION IF NOT EXISTS cube;
+
+CREATE EXTENSION IF NOT EXISTS earthdistance;
+
+ALTER TABLE restaurants
+ADD COLUMN latitude DOUBLE PRECISION CHECK (latitude >= -90 AND latitude <= 90),
+ADD COLUMN longitude DOUBLE PRECISION CHECK (longitude >= -180 AND longitude <= 180),
+ADD CONSTRAINT restaurants_location_complete CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+CREATE INDEX idx_restaurants_location ON restaurants USING gist (ll_to_earth(latitude, longitude)) WHERE deleted_at IS NULL AND latitude IS NOT NULL;
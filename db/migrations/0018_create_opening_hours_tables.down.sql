@@ -0,0 +1,5 @@
+DROP TABLE restaurant_schedule_exceptions;
+
+DROP TABLE restaurant_opening_hours;
+
+ALTER TABLE restaurants DROP COLUMN time_zone;
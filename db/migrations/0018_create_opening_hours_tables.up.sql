@@ -0,0 +1,27 @@
+ALTER TABLE restaurants ADD COLUMN time_zone VARCHAR (64) NOT NULL DEFAULT 'UTC';
+
+-- The times are stored as minutes from midnight in the time zone of the restaurant.
+-- An interval that does not close after it opens ends on the next day, e.g. 18:00-02:00, and one with equal times lasts 24 hours.
+CREATE TABLE restaurant_opening_hours (
+    restaurant_id uuid REFERENCES restaurants (id) ON DELETE CASCADE NOT NULL,
+    weekday SMALLINT NOT NULL CHECK (weekday >= 0 AND weekday <= 6),
+    opens_minute SMALLINT NOT NULL CHECK (opens_minute >= 0 AND opens_minute < 1440),
+    closes_minute SMALLINT NOT NULL CHECK (closes_minute >= 0 AND closes_minute < 1440)
+);
+
+CREATE INDEX idx_restaurant_opening_hours_restaurant_id ON restaurant_opening_hours (restaurant_id, weekday);
+
+-- Exceptions replace the weekly hours on the dates between start_date and end_date. Exceptions without hours are closures.
+CREATE TABLE restaurant_schedule_exceptions (
+    id uuid PRIMARY KEY,
+    restaurant_id uuid REFERENCES restaurants (id) ON DELETE CASCADE NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    opens_minute SMALLINT CHECK (opens_minute >= 0 AND opens_minute < 1440),
+    closes_minute SMALLINT CHECK (closes_minute >= 0 AND closes_minute < 1440),
+    reason VARCHAR (100) NOT NULL DEFAULT '',
+    CHECK (end_date >= start_date),
+    CHECK ((opens_minute IS NULL) = (closes_minute IS NULL))
+);
+
+CREATE INDEX idx_restaurant_schedule_exceptions_restaurant_id ON restaurant_schedule_exceptions (restaurant_id, start_date, end_date);
@@ -0,0 +1,3 @@
+DROP TABLE restaurant_tags;
+
+DROP TABLE tags;
@@ -0,0 +1,16 @@
+CREATE TABLE tags (
+    id uuid PRIMARY KEY,
+    slug VARCHAR (40) UNIQUE NOT NULL,
+    name VARCHAR (60) NOT NULL,
+    kind VARCHAR (20) NOT NULL CHECK (kind IN ('cuisine', 'tag')),
+    created_at timestamp NOT NULL
+);
+
+CREATE TABLE restaurant_tags (
+    restaurant_id uuid REFERENCES restaurants (id) ON DELETE CASCADE NOT NULL,
+    tag_id uuid REFERENCES tags (id) ON DELETE CASCADE NOT NULL,
+    PRIMARY KEY (restaurant_id, tag_id)
+);
+
+-- The primary key serves the tags of a restaurant, while this index serves the restaurants with a tag
+CREATE INDEX idx_restaurant_tags_tag_id ON restaurant_tags (tag_id, restaurant_id);
@@ -0,0 +1,11 @@
+ALTER TABLE restaurants
+DROP COLUMN price_level,
+DROP COLUMN wheelchair_access,
+DROP COLUMN outdoor_seating,
+DROP COLUMN vegan_options,
+DROP COLUMN parking,
+DROP COLUMN phone,
+DROP COLUMN website,
+DROP COLUMN facebook_url,
+DROP COLUMN instagram_url,
+DROP COLUMN twitter_url;
@@ -0,0 +1,12 @@
+-- The price level ranges from 1 ($) to 4 ($$$$). Restaurants that have not published it have no price level.
+ALTER TABLE restaurants
+ADD COLUMN price_level SMALLINT CHECK (price_level >= 1 AND price_level <= 4),
+ADD COLUMN wheelchair_access BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN outdoor_seating BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN vegan_options BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN parking BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN phone VARCHAR(16) NOT NULL DEFAULT '',
+ADD COLUMN website VARCHAR(200) NOT NULL DEFAULT '',
+ADD COLUMN facebook_url VARCHAR(200) NOT NULL DEFAULT '',
+ADD COLUMN instagram_url VARCHAR(200) NOT NULL DEFAULT '',
+ADD COLUMN twitter_url VARCHAR(200) NOT NULL DEFAULT '';
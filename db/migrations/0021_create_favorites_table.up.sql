@@ -0,0 +1,12 @@
+CREATE TABLE favorites (
+    user_id uuid REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    restaurant_id uuid REFERENCES restaurants (id) ON DELETE CASCADE NOT NULL,
+    created_at timestamp NOT NULL,
+    PRIMARY KEY (user_id, restaurant_id)
+);
+
+-- The primary key serves the checks of single restaurants, while this index serves the listing of the newest favorites of a user
+CREATE INDEX idx_favorites_user_id_created_at ON favorites (user_id, created_at DESC, restaurant_id);
+
+-- Deleting a restaurant looks up its favorites through the foreign key
+CREATE INDEX idx_favorites_restaurant_id ON favorites (restaurant_id);
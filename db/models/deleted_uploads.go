@@ -0,0 +1,8 @@
+package models
+
+// DeletedUploads are the restaurant images and review media whose records are deleted together with their restaurants or reviews.
+// Only their file keys are loaded, so that the files can be deleted once the records are gone.
+type DeletedUploads struct {
+	Images []RestaurantImage
+	Media  []ReviewMedia
+}
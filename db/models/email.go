@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+type EmailStatus string
+
+const (
+	// EmailPending is the status of an email that is waiting to be sent or retried
+	EmailPending EmailStatus = "pending"
+	// EmailSent is the status of an email that has been delivered to the mail server
+	EmailSent EmailStatus = "sent"
+	// EmailDead is the status of an email that could not be sent after the maximum number of attempts
+	EmailDead EmailStatus = "dead"
+)
+
+// OutboxEmail is an outgoing email that is stored in the database until a background worker sends it
+type OutboxEmail struct {
+	Id            string
+	Recipient     string
+	Subject       string
+	Message       string
+	Status        EmailStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     *string
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
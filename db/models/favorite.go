@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// Favorite is a restaurant saved by a user, e.g. one they want to try
+type Favorite struct {
+	UserId       string
+	RestaurantId string
+	CreatedAt    time.Time
+}
+
+// FavoriteRestaurant is a favorite restaurant of a user together with the time it was saved
+type FavoriteRestaurant struct {
+	Restaurant  Restaurant
+	FavoritedAt time.Time
+}
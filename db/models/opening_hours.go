@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// MinutesPerDay is the number of minutes in a day. The opening hours are kept as minutes from midnight, i.e. in [0, MinutesPerDay).
+const MinutesPerDay = 24 * 60
+
+// OpeningHours is an interval of the weekly schedule of a restaurant in its time zone. An interval that does not close after it opens
+// ends on the next day, e.g. 18:00-02:00, and one that closes when it opens lasts 24 hours. A day can have several intervals.
+type OpeningHours struct {
+	RestaurantId string
+	Weekday      time.Weekday
+	OpensMinute  int
+	ClosesMinute int
+}
+
+// ScheduleException replaces the weekly schedule of a restaurant on the dates between StartDate and EndDate (inclusive),
+// e.g. for holidays or temporary closures. The restaurant is closed on these dates unless the exception has opening hours.
+// The hours of all exceptions covering a date are combined.
+type ScheduleException struct {
+	Id           string
+	RestaurantId string
+	StartDate    time.Time
+	EndDate      time.Time
+	OpensMinute  *int
+	ClosesMinute *int
+	Reason       string
+}
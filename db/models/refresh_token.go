@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken is a long-lived token that can be exchanged for a new access token.
+// Only a hash of the token is stored, so that leaked database records cannot be used to authenticate.
+type RefreshToken struct {
+	Id          string
+	UserId      string
+	HashedToken string
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	RevokedAt   *time.Time
+	ReplacedBy  *string
+}
@@ -1,19 +1,45 @@
 package models
 
+import (
+	"time"
+)
+
 type Restaurant struct {
-	Id            string
-	OwnerId       string
-	Owner         *User
-	MinReviewId   *string
-	MinReview     *Review
-	MaxReviewId   *string
-	MaxReview     *Review
-	Name          string
-	City          string
-	Address       string
-	Img           string
-	Description   string
+	Id          string
+	OwnerId     string
+	Owner       *User
+	MinReviewId *string
+	MinReview   *Review
+	MaxReviewId *string
+	MaxReview   *Review
+	Name        string
+	City        string
+	Address     string
+	Img         string
+	Description string
+	// Latitude and Longitude are either both set or both nil
+	Latitude  *float64
+	Longitude *float64
+	// TimeZone is the IANA time zone of the opening hours
+	TimeZone string
+	// PriceLevel is between MinPriceLevel ($) and MaxPriceLevel ($$$$) or nil if it is not published
+	PriceLevel *int
+	Amenities
+	ContactDetails
 	RatingsTotal  int
 	RatingsCount  int
 	AverageRating float32
+	SubRatingAverages
+	// Tags are loaded only by the operations that document it
+	Tags []Tag
+	// IsFavorite tells whether the restaurant is a favorite of the user that requested it. It is not stored with the restaurant.
+	IsFavorite bool
+	CreatedAt  time.Time
+	DeletedAt  *time.Time
+}
+
+// NearbyRestaurant is a restaurant within some distance from a location
+type NearbyRestaurant struct {
+	Restaurant Restaurant
+	DistanceKm float64
 }
@@ -0,0 +1,53 @@
+package models
+
+const (
+	MinPriceLevel = 1
+	MaxPriceLevel = 4
+)
+
+// Amenity is a facility or an option that a restaurant can offer
+type Amenity string
+
+const (
+	AmenityWheelchairAccess Amenity = "wheelchair_access"
+	AmenityOutdoorSeating   Amenity = "outdoor_seating"
+	AmenityVeganOptions     Amenity = "vegan_options"
+	AmenityParking          Amenity = "parking"
+)
+
+// AllAmenities are all amenities in the order they are shown
+var AllAmenities = []Amenity{AmenityWheelchairAccess, AmenityOutdoorSeating, AmenityVeganOptions, AmenityParking}
+
+// Amenities are the amenities offered by a restaurant
+type Amenities struct {
+	WheelchairAccess bool
+	OutdoorSeating   bool
+	VeganOptions     bool
+	Parking          bool
+}
+
+// Has returns whether the amenity is offered
+func (a *Amenities) Has(amenity Amenity) bool {
+	switch amenity {
+	case AmenityWheelchairAccess:
+		return a.WheelchairAccess
+	case AmenityOutdoorSeating:
+		return a.OutdoorSeating
+	case AmenityVeganOptions:
+		return a.VeganOptions
+	case AmenityParking:
+		return a.Parking
+	default:
+		return false
+	}
+}
+
+// ContactDetails are the published contacts of a restaurant. Empty values mean that the contact is not published.
+type ContactDetails struct {
+	// Phone is in the E.164 format, e.g. +359888123456
+	Phone        string
+	Website      string
+	FacebookUrl  string
+	InstagramUrl string
+	TwitterUrl   string
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// RestaurantImage is a photo uploaded for a restaurant. The image and its thumbnail are kept in a file storage under the given keys.
+// The cover image of a restaurant is shown in the listings.
+type RestaurantImage struct {
+	Id           string
+	RestaurantId string
+	StorageKey   string
+	ThumbnailKey string
+	ContentType  string
+	Width        int
+	Height       int
+	Size         int
+	IsCover      bool
+	CreatedAt    time.Time
+}
@@ -11,7 +11,8 @@ type Review struct {
 	ReviewerId   string
 	Reviewer     *User
 	Rating       uint8
-	Timestamp    time.Time
-	Comment      string
-	Answer       *string
+	SubRatings
+	Timestamp time.Time
+	Comment   string
+	Answer    *string
 }
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// ReviewMedia is a photo attached to a review by its reviewer. The photo and its thumbnail are kept in a file storage under the given keys.
+type ReviewMedia struct {
+	Id           string
+	ReviewId     string
+	StorageKey   string
+	ThumbnailKey string
+	ContentType  string
+	Width        int
+	Height       int
+	Size         int
+	CreatedAt    time.Time
+}
@@ -34,13 +34,22 @@ func (r *Role) Scan(value interface{}) error {
 		return errors.New("role is not a byte array")
 	}
 
-	valueString := string(valueByte)
+	role, err := ParseRole(string(valueByte))
+	if err != nil {
+		return err
+	}
+
+	*r = role
+	return nil
+}
+
+// ParseRole returns the role that has the given string representation
+func ParseRole(value string) (Role, error) {
 	for i, role := range roles {
-		if role == valueString {
-			*r = Role(uint8(i))
-			return nil
+		if role == value {
+			return Role(uint8(i)), nil
 		}
 	}
 
-	return errors.New("invalid role")
+	return Regular, errors.New("invalid role")
 }
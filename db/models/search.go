@@ -0,0 +1,22 @@
+package models
+
+const (
+	// HighlightStart and HighlightStop surround the matched words in search snippets.
+	// Control characters are used, so that they cannot be confused with the user content.
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+// RestaurantSearchResult is a restaurant that matches a full-text search query
+type RestaurantSearchResult struct {
+	Restaurant Restaurant
+	Rank       float32
+	Snippet    string
+}
+
+// ReviewSearchResult is a review that matches a full-text search query
+type ReviewSearchResult struct {
+	Review  Review
+	Rank    float32
+	Snippet string
+}
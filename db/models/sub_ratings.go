@@ -0,0 +1,46 @@
+package models
+
+// RatingDimension is an aspect of a restaurant that reviews can rate separately from the overall rating
+type RatingDimension string
+
+const (
+	RatingFood     RatingDimension = "food"
+	RatingService  RatingDimension = "service"
+	RatingAmbience RatingDimension = "ambience"
+	RatingValue    RatingDimension = "value"
+)
+
+// RatingDimensions are all dimensions in the order they are shown
+var RatingDimensions = []RatingDimension{RatingFood, RatingService, RatingAmbience, RatingValue}
+
+// SubRatings are the optional ratings of a review per dimension. A nil rating means that the reviewer did not rate the dimension.
+type SubRatings struct {
+	FoodRating     *uint8
+	ServiceRating  *uint8
+	AmbienceRating *uint8
+	ValueRating    *uint8
+}
+
+// ForDimension returns the rating of the dimension
+func (sr *SubRatings) ForDimension(dimension RatingDimension) *uint8 {
+	switch dimension {
+	case RatingFood:
+		return sr.FoodRating
+	case RatingService:
+		return sr.ServiceRating
+	case RatingAmbience:
+		return sr.AmbienceRating
+	case RatingValue:
+		return sr.ValueRating
+	default:
+		return nil
+	}
+}
+
+// SubRatingAverages are the average ratings of a restaurant per dimension. A nil average means that no review has rated the dimension.
+type SubRatingAverages struct {
+	FoodAverageRating     *float32
+	ServiceAverageRating  *float32
+	AmbienceAverageRating *float32
+	ValueAverageRating    *float32
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// TagKind groups the tags, so that the cuisines can be shown separately from the other tags
+type TagKind string
+
+const (
+	TagKindCuisine TagKind = "cuisine"
+	TagKindTag     TagKind = "tag"
+)
+
+// Tag is an entry of the taxonomy managed by the admins. The slug identifies the tag in the requests and the filters.
+type Tag struct {
+	Id        string
+	Slug      string
+	Name      string
+	Kind      TagKind
+	CreatedAt time.Time
+}
+
+// RestaurantTag is a tag of a particular restaurant
+type RestaurantTag struct {
+	RestaurantId string
+	Tag
+}
+
+// RestaurantFacets are the numbers of restaurants matching a filter per tag and per city
+type RestaurantFacets struct {
+	Tags   []TagFacet
+	Cities []CityFacet
+}
+
+type TagFacet struct {
+	Tag   Tag
+	Count int64
+}
+
+type CityFacet struct {
+	City  string
+	Count int64
+}
@@ -1,10 +1,21 @@
 package models
 
+import (
+	"time"
+)
+
 type User struct {
-	Id                     string
-	Email                  string
-	EmailConfirmed         bool
-	EmailConfirmationToken *string
-	HashedPassword         string
-	Role                   Role
+	Id                              string
+	Email                           string
+	EmailConfirmed                  bool
+	EmailConfirmationToken          *string
+	EmailConfirmationTokenCreatedAt *time.Time
+	HashedPassword                  string
+	Role                            Role
+	Disabled                        bool
+	TokenVersion                    int
+	PasswordResetToken              *string
+	PasswordResetTokenExpiresAt     *time.Time
+	// Locale is the language of the emails sent to the user, derived from the Accept-Language header during registration
+	Locale string
 }
@@ -0,0 +1,105 @@
+package dbr
+
+import (
+	"time"
+
+	"github.com/gocraft/dbr/v2"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/db/stores"
+)
+
+const emailOutboxTable = "email_outbox"
+
+type emailOutboxStore struct {
+	session *dbr.Session
+}
+
+// NewEmailOutboxStore returns an EmailOutboxStore that uses the DBR driver
+func NewEmailOutboxStore(session *dbr.Session) stores.EmailOutboxStore {
+	return &emailOutboxStore{
+		session: session,
+	}
+}
+
+// Insert generates a new ID for the email and adds it to the outbox as pending, so that it is sent as soon as possible
+func (es *emailOutboxStore) Insert(email *models.OutboxEmail) error {
+	if email.Id == "" {
+		email.Id = uuid.NewV4().String()
+	}
+
+	now := time.Now().UTC()
+	email.Status = models.EmailPending
+	email.Attempts = 0
+	email.NextAttemptAt = now
+	email.CreatedAt = now
+
+	_, err := es.session.
+		InsertInto(emailOutboxTable).
+		Columns("id", "recipient", "subject", "message", "status", "attempts", "next_attempt_at", "created_at").
+		Record(email).
+		Exec()
+
+	return errors.Wrap(err, "could not insert email in outbox")
+}
+
+// ClaimDue returns up to limit pending emails whose next attempt is due and postpones their next attempt until leaseUntil.
+// This way an email that is being sent is not picked up by another worker, but it is retried if the worker dies before
+// marking it as sent or failed. Rows locked by other workers are skipped.
+func (es *emailOutboxStore) ClaimDue(limit uint64, leaseUntil time.Time) ([]models.OutboxEmail, error) {
+	emails := make([]models.OutboxEmail, 0, limit)
+
+	err := es.session.UpdateBySql(`
+		UPDATE email_outbox
+		SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED)
+		RETURNING id, recipient, subject, message, status, attempts, next_attempt_at, created_at`,
+		leaseUntil, models.EmailPending, time.Now().UTC(), limit).
+		Load(&emails)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not claim due emails")
+	}
+
+	return emails, nil
+}
+
+// MarkSent marks the email as successfully sent
+func (es *emailOutboxStore) MarkSent(id string) error {
+	_, err := es.session.
+		Update(emailOutboxTable).
+		Set("status", models.EmailSent).
+		Set("sent_at", time.Now().UTC()).
+		Set("last_error", nil).
+		IncrBy("attempts", 1).
+		Where("id = ?", id).
+		Exec()
+
+	return errors.Wrap(err, "could not mark email as sent")
+}
+
+// MarkFailed records a failed attempt to send the email. The email is either scheduled for another attempt at nextAttemptAt
+// or, if dead is set, it is never attempted again.
+func (es *emailOutboxStore) MarkFailed(id, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := models.EmailPending
+	if dead {
+		status = models.EmailDead
+	}
+
+	_, err := es.session.
+		Update(emailOutboxTable).
+		Set("status", status).
+		Set("last_error", lastError).
+		Set("next_attempt_at", nextAttemptAt).
+		IncrBy("attempts", 1).
+		Where("id = ?", id).
+		Exec()
+
+	return errors.Wrap(err, "could not mark email as failed")
+}
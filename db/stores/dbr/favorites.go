@@ -0,0 +1,109 @@
+package dbr
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gocraft/dbr/v2"
+	"github.com/pkg/errors"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/db/stores"
+)
+
+const (
+	favoritesTable = "favorites"
+	userId         = "user_id"
+)
+
+type favoritesStore struct {
+	session *dbr.Session
+}
+
+// NewFavoritesStore returns a FavoritesStore that uses the DBR driver
+func NewFavoritesStore(session *dbr.Session) stores.FavoritesStore {
+	return &favoritesStore{
+		session: session,
+	}
+}
+
+// Insert saves a restaurant as a favorite of a user. Saving it again does nothing and keeps the time it was first saved.
+func (fs *favoritesStore) Insert(favorite *models.Favorite) error {
+	_, err := fs.session.
+		InsertBySql(fmt.Sprintf("INSERT INTO %s (%s, %s, %s) VALUES (?, ?, ?) ON CONFLICT DO NOTHING", favoritesTable, userId, restaurantId, createdAt),
+			favorite.UserId, favorite.RestaurantId, favorite.CreatedAt).
+		Exec()
+
+	return errors.Wrap(err, "could not insert favorite")
+}
+
+// Delete removes a restaurant from the favorites of a user. Removing a restaurant that is not a favorite does nothing.
+func (fs *favoritesStore) Delete(uId, restId string) error {
+	_, err := fs.session.
+		DeleteFrom(favoritesTable).
+		Where(fmt.Sprintf("%s = ? AND %s = ?", userId, restaurantId), uId, restId).
+		Exec()
+
+	return errors.Wrap(err, "could not delete favorite")
+}
+
+// ListForUser returns the favorite restaurants of a user, the most recently saved first. Soft deleted restaurants are not returned.
+func (fs *favoritesStore) ListForUser(uId string, top, skip uint64) ([]models.FavoriteRestaurant, error) {
+	query := fs.session.
+		Select(prefixedColumns(restaurantsTable, restaurantListColumns)...).
+		From(dbr.I(favoritesTable).As("f")).
+		Join(restaurantsTable, fmt.Sprintf("%s.%s = f.%s", restaurantsTable, id, restaurantId)).
+		Where(fmt.Sprintf("f.%s = ? AND %s.%s IS NULL", userId, restaurantsTable, deletedAt), uId).
+		OrderDesc("f." + createdAt).
+		OrderDesc(restaurantsTable + "." + id).
+		Limit(top).
+		Offset(skip)
+
+	query.Column = append(query.Column, fmt.Sprintf("f.%s AS favorited_at", createdAt))
+
+	rows := make([]struct {
+		models.Restaurant
+		FavoritedAt time.Time
+	}, 0, top)
+
+	if _, err := query.Load(&rows); err != nil {
+		return nil, errors.Wrap(err, "could not load favorite restaurants")
+	}
+
+	favorites := make([]models.FavoriteRestaurant, len(rows))
+	for i, r := range rows {
+		favorites[i] = models.FavoriteRestaurant{Restaurant: r.Restaurant, FavoritedAt: r.FavoritedAt}
+	}
+
+	return favorites, nil
+}
+
+// CountForUser returns the number of favorite restaurants of a user that are not soft deleted
+func (fs *favoritesStore) CountForUser(uId string) (int64, error) {
+	var count int64
+
+	err := fs.session.
+		Select("count(*)").
+		From(dbr.I(favoritesTable).As("f")).
+		Join(restaurantsTable, fmt.Sprintf("%s.%s = f.%s", restaurantsTable, id, restaurantId)).
+		Where(fmt.Sprintf("f.%s = ? AND %s.%s IS NULL", userId, restaurantsTable, deletedAt), uId).
+		LoadOne(&count)
+
+	return count, errors.Wrap(err, "could not count favorite restaurants")
+}
+
+// ListFavoriteIds returns the ids of the given restaurants that are favorites of a user
+func (fs *favoritesStore) ListFavoriteIds(uId string, restIds []string) ([]string, error) {
+	ids := make([]string, 0)
+	if len(restIds) == 0 {
+		return ids, nil
+	}
+
+	_, err := fs.session.
+		Select(restaurantId).
+		From(favoritesTable).
+		Where(fmt.Sprintf("%s = ? AND %s IN ?", userId, restaurantId), uId, restIds).
+		Load(&ids)
+
+	return ids, errors.Wrap(err, "could not load favorite restaurant ids")
+}
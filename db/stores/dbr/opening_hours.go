@@ -0,0 +1,187 @@
+package dbr
+
+import (
+	"fmt"
+
+	"github.com/gocraft/dbr/v2"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/db/stores"
+)
+
+const (
+	openingHoursTable       = "restaurant_opening_hours"
+	scheduleExceptionsTable = "restaurant_schedule_exceptions"
+	timeZone                = "time_zone"
+	weekday                 = "weekday"
+	opensMinute             = "opens_minute"
+	closesMinute            = "closes_minute"
+	startDate               = "start_date"
+	endDate                 = "end_date"
+	dateLayout              = "2006-01-02"
+)
+
+type openingHoursStore struct {
+	session *dbr.Session
+}
+
+// NewOpeningHoursStore returns an OpeningHoursStore that uses the DBR driver
+func NewOpeningHoursStore(session *dbr.Session) stores.OpeningHoursStore {
+	return &openingHoursStore{
+		session: session,
+	}
+}
+
+// ListForRestaurant returns the weekly schedule of a restaurant ordered by weekday (starting from Sunday) and opening time
+func (ohs *openingHoursStore) ListForRestaurant(restId string) ([]models.OpeningHours, error) {
+	hours := make([]models.OpeningHours, 0)
+
+	_, err := ohs.session.
+		Select(restaurantId, weekday, opensMinute, closesMinute).
+		From(openingHoursTable).
+		Where(fmt.Sprintf("%s = ?", restaurantId), restId).
+		OrderAsc(weekday).
+		OrderAsc(opensMinute).
+		Load(&hours)
+
+	return hours, errors.Wrap(err, "could not load opening hours")
+}
+
+// Replace starts a new transaction that sets the time zone of a restaurant and replaces its weekly schedule with the given hours
+func (ohs *openingHoursStore) Replace(restId, tz string, hours []models.OpeningHours) error {
+	tx, err := ohs.session.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	result, err := tx.
+		Update(restaurantsTable).
+		Set(timeZone, tz).
+		Where(fmt.Sprintf("%s = ? AND %s IS NULL", id, deletedAt), restId).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not set restaurant time zone")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "could not get number of updated restaurants")
+	}
+
+	if affected == 0 {
+		return db.ErrNotFound
+	}
+
+	_, err = tx.
+		DeleteFrom(openingHoursTable).
+		Where(fmt.Sprintf("%s = ?", restaurantId), restId).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not delete previous opening hours")
+	}
+
+	if len(hours) > 0 {
+		insert := tx.
+			InsertInto(openingHoursTable).
+			Columns(restaurantId, weekday, opensMinute, closesMinute)
+
+		for _, h := range hours {
+			insert = insert.Values(restId, int(h.Weekday), h.OpensMinute, h.ClosesMinute)
+		}
+
+		if _, err = insert.Exec(); err != nil {
+			return errors.Wrap(err, "could not insert opening hours")
+		}
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit transaction")
+}
+
+// TimeZoneExists checks if the database knows a time zone. The opening hours are evaluated by the database,
+// so its time zone names are the ones that can be used.
+func (ohs *openingHoursStore) TimeZoneExists(name string) (bool, error) {
+	exists := false
+
+	err := ohs.session.
+		SelectBySql("SELECT EXISTS (SELECT 1 FROM pg_timezone_names WHERE name = ?)", name).
+		LoadOne(&exists)
+
+	return exists, errors.Wrap(err, "could not check time zone")
+}
+
+// InsertException generates a new ID for the exception, unless it already has one, and inserts it in the database
+func (ohs *openingHoursStore) InsertException(exception *models.ScheduleException) error {
+	if exception.Id == "" {
+		exception.Id = uuid.NewV4().String()
+	}
+
+	_, err := ohs.session.
+		InsertInto(scheduleExceptionsTable).
+		Columns(id, restaurantId, startDate, endDate, opensMinute, closesMinute, "reason").
+		Values(exception.Id, exception.RestaurantId, exception.StartDate.Format(dateLayout), exception.EndDate.Format(dateLayout),
+			exception.OpensMinute, exception.ClosesMinute, exception.Reason).
+		Exec()
+
+	return errors.Wrap(err, "could not insert schedule exception")
+}
+
+func (ohs *openingHoursStore) GetException(exceptionId string) (*models.ScheduleException, error) {
+	exception := new(models.ScheduleException)
+
+	err := ohs.session.
+		Select("*").
+		From(scheduleExceptionsTable).
+		Where(fmt.Sprintf("%s = ?", id), exceptionId).
+		LoadOne(exception)
+
+	if err != nil {
+		if err == dbr.ErrNotFound {
+			return nil, db.ErrNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not load schedule exception")
+	}
+
+	return exception, nil
+}
+
+// ListExceptions returns the schedule exceptions of a restaurant ordered by their start date
+func (ohs *openingHoursStore) ListExceptions(restId string) ([]models.ScheduleException, error) {
+	exceptions := make([]models.ScheduleException, 0)
+
+	_, err := ohs.session.
+		Select("*").
+		From(scheduleExceptionsTable).
+		Where(fmt.Sprintf("%s = ?", restaurantId), restId).
+		OrderAsc(startDate).
+		OrderAsc(opensMinute).
+		Load(&exceptions)
+
+	return exceptions, errors.Wrap(err, "could not load schedule exceptions")
+}
+
+func (ohs *openingHoursStore) DeleteException(exceptionId string) error {
+	result, err := ohs.session.
+		DeleteFrom(scheduleExceptionsTable).
+		Where(fmt.Sprintf("%s = ?", id), exceptionId).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not delete schedule exception")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "could not get number of deleted schedule exceptions")
+	}
+
+	if affected == 0 {
+		return db.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package dbr
+
+import (
+	"time"
+
+	"github.com/gocraft/dbr/v2"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/db/stores"
+)
+
+const refreshTokensTable = "refresh_tokens"
+
+type refreshTokensStore struct {
+	session *dbr.Session
+}
+
+// NewRefreshTokensStore returns a RefreshTokensStore that uses the DBR driver
+func NewRefreshTokensStore(session *dbr.Session) stores.RefreshTokensStore {
+	return &refreshTokensStore{
+		session: session,
+	}
+}
+
+// Insert generates a new ID for the refresh token and inserts it in the database
+func (rts *refreshTokensStore) Insert(token *models.RefreshToken) error {
+	if token.Id == "" {
+		token.Id = uuid.NewV4().String()
+	}
+
+	_, err := rts.session.
+		InsertInto(refreshTokensTable).
+		Columns("id", "user_id", "hashed_token", "created_at", "expires_at").
+		Record(token).
+		Exec()
+
+	return errors.Wrap(err, "could not insert refresh token")
+}
+
+// GetByHash returns a refresh token by its hash or ErrNotFound if it doesn't exist
+func (rts *refreshTokensStore) GetByHash(hashedToken string) (*models.RefreshToken, error) {
+	token := new(models.RefreshToken)
+	err := rts.session.
+		Select("*").
+		From(refreshTokensTable).
+		Where("hashed_token = ?", hashedToken).
+		LoadOne(token)
+
+	if err != nil {
+		if err == dbr.ErrNotFound {
+			return nil, db.ErrNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not load refresh token")
+	}
+
+	return token, nil
+}
+
+// Rotate starts a new transaction that inserts the new refresh token and revokes the old one, marking it as replaced by the new one.
+// If the old token has already been revoked (e.g. by a concurrent rotation), ErrNotFound is returned and nothing is changed.
+func (rts *refreshTokensStore) Rotate(oldId string, newToken *models.RefreshToken) error {
+	if newToken.Id == "" {
+		newToken.Id = uuid.NewV4().String()
+	}
+
+	tx, err := rts.session.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	_, err = tx.
+		InsertInto(refreshTokensTable).
+		Columns("id", "user_id", "hashed_token", "created_at", "expires_at").
+		Record(newToken).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not insert refresh token")
+	}
+
+	result, err := tx.
+		Update(refreshTokensTable).
+		Set("revoked_at", time.Now().UTC()).
+		Set("replaced_by", newToken.Id).
+		Where("id = ? AND revoked_at IS NULL", oldId).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not revoke old refresh token")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "could not get number of revoked refresh tokens")
+	}
+
+	if affected == 0 {
+		return db.ErrNotFound
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit transaction")
+}
+
+// Revoke marks a single refresh token as revoked, so that it can no longer be used
+func (rts *refreshTokensStore) Revoke(id string) error {
+	_, err := rts.session.
+		Update(refreshTokensTable).
+		Set("revoked_at", time.Now().UTC()).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Exec()
+
+	return errors.Wrap(err, "could not revoke refresh token")
+}
+
+// RevokeAllForUser starts a new transaction that revokes all refresh tokens of a user and increments their token version,
+// so that all access tokens that have already been issued to the user are no longer accepted either.
+func (rts *refreshTokensStore) RevokeAllForUser(userId string) error {
+	tx, err := rts.session.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	_, err = tx.
+		Update(refreshTokensTable).
+		Set("revoked_at", time.Now().UTC()).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not revoke refresh tokens")
+	}
+
+	_, err = tx.
+		Update(usersTable).
+		IncrBy("token_version", 1).
+		Where("id = ?", userId).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not increment token version")
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit transaction")
+}
@@ -0,0 +1,224 @@
+package dbr
+
+import (
+	"fmt"
+
+	"github.com/gocraft/dbr/v2"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/db/stores"
+)
+
+const (
+	restaurantImagesTable = "restaurant_images"
+	isCover               = "is_cover"
+)
+
+type restaurantImagesStore struct {
+	session *dbr.Session
+}
+
+// NewRestaurantImagesStore returns a RestaurantImagesStore that uses the DBR driver
+func NewRestaurantImagesStore(session *dbr.Session) stores.RestaurantImagesStore {
+	return &restaurantImagesStore{
+		session: session,
+	}
+}
+
+// Insert generates a new ID for the image, unless it already has one, and starts a new transaction that inserts it in the database
+// if the restaurant has fewer than maxPerRestaurant images. The restaurant row is locked, so concurrent uploads cannot exceed the limit.
+// It returns the number of images the restaurant had before the insert or db.ErrLimitReached.
+func (ris *restaurantImagesStore) Insert(image *models.RestaurantImage, maxPerRestaurant int) (int, error) {
+	if image.Id == "" {
+		image.Id = uuid.NewV4().String()
+	}
+
+	tx, err := ris.session.Begin()
+	if err != nil {
+		return 0, errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	lockedId := ""
+	err = tx.
+		Select(id).
+		From(restaurantsTable).
+		Where(fmt.Sprintf("%s = ?", id), image.RestaurantId).
+		Suffix("FOR UPDATE").
+		LoadOne(&lockedId)
+	if err != nil {
+		if err == dbr.ErrNotFound {
+			return 0, db.ErrNotFound
+		}
+
+		return 0, errors.Wrap(err, "could not lock restaurant")
+	}
+
+	count := 0
+	err = tx.
+		Select("count(*)").
+		From(restaurantImagesTable).
+		Where(fmt.Sprintf("%s = ?", restaurantId), image.RestaurantId).
+		LoadOne(&count)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not count restaurant images")
+	}
+
+	if count >= maxPerRestaurant {
+		return count, db.ErrLimitReached
+	}
+
+	_, err = tx.
+		InsertInto(restaurantImagesTable).
+		Columns(id, restaurantId, "storage_key", "thumbnail_key", "content_type", "width", "height", "size", createdAt).
+		Record(image).
+		Exec()
+	if err != nil {
+		return 0, errors.Wrap(err, "could not insert restaurant image")
+	}
+
+	return count, errors.Wrap(tx.Commit(), "could not commit transaction")
+}
+
+func (ris *restaurantImagesStore) GetById(imageId string) (*models.RestaurantImage, error) {
+	image := new(models.RestaurantImage)
+
+	err := ris.session.
+		Select("*").
+		From(restaurantImagesTable).
+		Where(fmt.Sprintf("%s = ?", id), imageId).
+		LoadOne(image)
+
+	if err != nil {
+		if err == dbr.ErrNotFound {
+			return nil, db.ErrNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not load restaurant image")
+	}
+
+	return image, nil
+}
+
+// ListForRestaurant returns the images of a restaurant starting with the cover image, followed by the rest in the order of uploading
+func (ris *restaurantImagesStore) ListForRestaurant(restId string) ([]models.RestaurantImage, error) {
+	images := make([]models.RestaurantImage, 0)
+
+	_, err := ris.session.
+		Select("*").
+		From(restaurantImagesTable).
+		Where(fmt.Sprintf("%s = ?", restaurantId), restId).
+		OrderDesc(isCover).
+		OrderAsc(createdAt).
+		Load(&images)
+
+	return images, errors.Wrap(err, "could not load restaurant images")
+}
+
+func (ris *restaurantImagesStore) CountForRestaurant(restId string) (int, error) {
+	count := 0
+
+	err := ris.session.
+		Select("count(*)").
+		From(restaurantImagesTable).
+		Where(fmt.Sprintf("%s = ?", restaurantId), restId).
+		LoadOne(&count)
+
+	return count, errors.Wrap(err, "could not count restaurant images")
+}
+
+// deleteRestaurantImages deletes the images of the restaurants selected by the query and returns their file keys. The images would be
+// deleted together with their restaurants, but the rows deleted by the cascade cannot be returned.
+func deleteRestaurantImages(tx *dbr.Tx, restaurantsQuery string, value ...interface{}) ([]models.RestaurantImage, error) {
+	images := make([]models.RestaurantImage, 0)
+
+	_, err := tx.
+		SelectBySql(fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s) RETURNING storage_key, thumbnail_key", restaurantImagesTable, restaurantId, restaurantsQuery), value...).
+		Load(&images)
+
+	return images, errors.Wrap(err, "could not delete restaurant images")
+}
+
+// SetCover starts a new transaction that makes the image the only cover image of its restaurant and sets the img of the restaurant
+// to the given URL, so that the listings show the cover image without joining the images table.
+func (ris *restaurantImagesStore) SetCover(image *models.RestaurantImage, url string) error {
+	tx, err := ris.session.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	_, err = tx.
+		Update(restaurantImagesTable).
+		Set(isCover, false).
+		Where(fmt.Sprintf("%s = ? AND %s", restaurantId, isCover), image.RestaurantId).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not unset previous cover image")
+	}
+
+	_, err = tx.
+		Update(restaurantImagesTable).
+		Set(isCover, true).
+		Where(fmt.Sprintf("%s = ?", id), image.Id).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not set cover image")
+	}
+
+	_, err = tx.
+		Update(restaurantsTable).
+		Set(img, url).
+		Where(fmt.Sprintf("%s = ?", id), image.RestaurantId).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not set restaurant img")
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit transaction")
+}
+
+// Delete starts a new transaction that deletes the image. If it is the cover image, the img of its restaurant is cleared as well.
+func (ris *restaurantImagesStore) Delete(image *models.RestaurantImage) error {
+	tx, err := ris.session.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	result, err := tx.
+		DeleteFrom(restaurantImagesTable).
+		Where(fmt.Sprintf("%s = ?", id), image.Id).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not delete restaurant image")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "could not get number of deleted images")
+	}
+
+	if affected == 0 {
+		return db.ErrNotFound
+	}
+
+	if image.IsCover {
+		_, err = tx.
+			Update(restaurantsTable).
+			Set(img, "").
+			Where(fmt.Sprintf("%s = ?", id), image.RestaurantId).
+			Exec()
+		if err != nil {
+			return errors.Wrap(err, "could not clear restaurant img")
+		}
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit transaction")
+}
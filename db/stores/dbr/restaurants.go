@@ -3,6 +3,7 @@ package dbr
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gocraft/dbr/v2"
@@ -23,13 +24,95 @@ const (
 	address          = "address"
 	img              = "img"
 	description      = "description"
+	latitude         = "latitude"
+	longitude        = "longitude"
+	priceLevel       = "price_level"
+	phone            = "phone"
+	website          = "website"
+	facebookUrl      = "facebook_url"
+	instagramUrl     = "instagram_url"
+	twitterUrl       = "twitter_url"
 	ratingsTotal     = "ratings_total"
 	ratingsCount     = "ratings_count"
 	averageRating    = "average_rating"
 	minReviewId      = "min_review_id"
 	maxReviewId      = "max_review_id"
+	deletedAt        = "deleted_at"
+	createdAt        = "created_at"
 )
 
+// restaurantsSortColumn is a column that restaurants can be ordered by together with its type,
+// which is used to cast the keyset values back from text
+type restaurantsSortColumn struct {
+	name    string
+	sqlType string
+}
+
+// restaurantsSortColumns maps the supported sort keys to the columns that the restaurants are ordered by.
+// Only these columns can be used for ordering and each of them is backed by an index together with the id.
+var restaurantsSortColumns = map[stores.RestaurantsSortKey]restaurantsSortColumn{
+	stores.SortRestaurantsByRating:      {name: averageRating, sqlType: "real"},
+	stores.SortRestaurantsByReviewCount: {name: ratingsCount, sqlType: "integer"},
+	stores.SortRestaurantsByName:        {name: name, sqlType: "varchar"},
+	stores.SortRestaurantsByNewest:      {name: createdAt, sqlType: "timestamp"},
+}
+
+// restaurantListColumns are the columns of the restaurants returned by the listings
+var restaurantListColumns = append([]string{id, name, city, address, img, description, latitude, longitude, priceLevel, averageRating, ratingsCount, createdAt},
+	subRatingAverageColumns...)
+
+// amenityColumns are the boolean columns of the restaurants table with the amenities, in the order of models.AllAmenities
+var amenityColumns = []string{"wheelchair_access", "outdoor_seating", "vegan_options", "parking"}
+
+// amenityColumn returns the column of the restaurants table that tells if a restaurant offers the amenity
+func amenityColumn(amenity models.Amenity) (string, bool) {
+	for i, a := range models.AllAmenities {
+		if a == amenity {
+			return amenityColumns[i], true
+		}
+	}
+
+	return "", false
+}
+
+// amenitiesPtrs returns the destinations for scanning the columns of amenityColumns
+func amenitiesPtrs(a *models.Amenities) []interface{} {
+	return []interface{}{&a.WheelchairAccess, &a.OutdoorSeating, &a.VeganOptions, &a.Parking}
+}
+
+// earthLocation is the point of a restaurant on the surface of the earth. It matches the expression of the GiST index on the location.
+var earthLocation = fmt.Sprintf("ll_to_earth(%s, %s)", latitude, longitude)
+
+// openAtCondition checks if a restaurant is open at a time according to its opening hours in its time zone. The intervals of a day
+// are the ones of the schedule exceptions covering it or, if there are none, the weekly hours of its weekday. The time is checked
+// against the intervals of its local day and of the previous day, as it can fall in an overnight interval that started the day before.
+// Minutes are counted from the start of the day of the interval, so overnight intervals simply end after MinutesPerDay.
+var openAtCondition = fmt.Sprintf(`EXISTS (
+	SELECT 1
+	FROM (SELECT CAST(? AS timestamptz) AT TIME ZONE %[1]s.time_zone AS t) l
+	CROSS JOIN (VALUES (0), (1)) AS back(days)
+	CROSS JOIN LATERAL (
+		SELECT CAST(l.t AS date) - back.days AS local_day,
+			CAST(extract(hour FROM l.t) * 60 + extract(minute FROM l.t) AS integer) + back.days * %[2]d AS local_minute
+	) d
+	CROSS JOIN LATERAL (
+		SELECT e.opens_minute, e.closes_minute FROM %[3]s e
+		WHERE e.restaurant_id = %[1]s.id AND d.local_day BETWEEN e.start_date AND e.end_date AND e.opens_minute IS NOT NULL
+		UNION ALL
+		SELECT h.opens_minute, h.closes_minute FROM %[4]s h
+		WHERE h.restaurant_id = %[1]s.id AND h.weekday = extract(dow FROM d.local_day)
+			AND NOT EXISTS (SELECT 1 FROM %[3]s e WHERE e.restaurant_id = %[1]s.id AND d.local_day BETWEEN e.start_date AND e.end_date)
+	) i
+	WHERE i.opens_minute <= d.local_minute
+		AND d.local_minute < CASE WHEN i.closes_minute > i.opens_minute THEN i.closes_minute ELSE i.closes_minute + %[2]d END
+)`, restaurantsTable, models.MinutesPerDay, scheduleExceptionsTable, openingHoursTable)
+
+// maxCityFacets is the maximum number of cities returned by Facets
+const maxCityFacets = 50
+
+// headlineOptions are the options of ts_headline used to generate search snippets with highlighted words
+var headlineOptions = fmt.Sprintf("StartSel=%s, StopSel=%s, MaxFragments=2, MaxWords=20, MinWords=5", models.HighlightStart, models.HighlightStop)
+
 type restaurantsStore struct {
 	session *dbr.Session
 }
@@ -41,40 +124,165 @@ func NewRestaurantsStore(session *dbr.Session) stores.RestaurantsStore {
 	}
 }
 
-// Insert generates a new ID for the restaurant and inserts it in the database. The ID can then be used by the callers of this method
-// in case an error is not returned.
+// Insert generates a new ID for the restaurant and inserts it in the database together with its tags. The ID can then be used by
+// the callers of this method in case an error is not returned.
 func (rs *restaurantsStore) Insert(restaurant *models.Restaurant) error {
 	if restaurant.Id == "" {
 		restaurant.Id = uuid.NewV4().String()
 	}
 
-	_, err := rs.session.
+	tx, err := rs.session.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	_, err = tx.
 		InsertInto(restaurantsTable).
-		Columns(id, ownerId, name, city, address, img, description, ratingsTotal, ratingsCount, minReviewId, maxReviewId).
+		Columns(append(append([]string{id, ownerId, name, city, address, img, description, latitude, longitude, priceLevel}, amenityColumns...),
+			phone, website, facebookUrl, instagramUrl, twitterUrl, ratingsTotal, ratingsCount, minReviewId, maxReviewId)...).
 		Record(restaurant).
 		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not insert into restaurants table")
+	}
+
+	if err = setRestaurantTags(tx, restaurant.Id, restaurant.Tags); err != nil {
+		return err
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit transaction")
+}
+
+// Update updates the name, city, address, img, description, location, price level, amenities, contact details and tags
+// of a given restaurant by its id.
+// The rating statistics and the min and max reviews are maintained by the reviews store and are not changed here.
+func (rs *restaurantsStore) Update(restaurant *models.Restaurant) error {
+	tx, err := rs.session.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	update := tx.
+		Update(restaurantsTable).
+		Set(name, restaurant.Name).
+		Set(city, restaurant.City).
+		Set(address, restaurant.Address).
+		Set(img, restaurant.Img).
+		Set(description, restaurant.Description).
+		Set(latitude, restaurant.Latitude).
+		Set(longitude, restaurant.Longitude).
+		Set(priceLevel, restaurant.PriceLevel).
+		Set(phone, restaurant.Phone).
+		Set(website, restaurant.Website).
+		Set(facebookUrl, restaurant.FacebookUrl).
+		Set(instagramUrl, restaurant.InstagramUrl).
+		Set(twitterUrl, restaurant.TwitterUrl)
+
+	for _, a := range models.AllAmenities {
+		column, _ := amenityColumn(a)
+		update = update.Set(column, restaurant.Amenities.Has(a))
+	}
+
+	result, err := update.
+		Where(fmt.Sprintf("%s = ? AND %s IS NULL", id, deletedAt), restaurant.Id).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not update restaurant")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "could not get number of updated restaurants")
+	}
+
+	if affected == 0 {
+		return db.ErrNotFound
+	}
+
+	if err = setRestaurantTags(tx, restaurant.Id, restaurant.Tags); err != nil {
+		return err
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit transaction")
+}
+
+// lockRows locks the rows selected by the query until the end of the transaction
+func lockRows(tx *dbr.Tx, query string, value ...interface{}) error {
+	ids := make([]string, 0)
+	_, err := tx.SelectBySql(query+" FOR UPDATE", value...).Load(&ids)
+
+	return errors.Wrap(err, "could not lock rows")
+}
+
+// setRestaurantTags replaces the tags of a restaurant within a transaction
+func setRestaurantTags(tx *dbr.Tx, restId string, tags []models.Tag) error {
+	_, err := tx.
+		DeleteFrom(restaurantTagsTable).
+		Where(fmt.Sprintf("%s = ?", restaurantId), restId).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not delete restaurant tags")
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	insert := tx.
+		InsertInto(restaurantTagsTable).
+		Columns(restaurantId, restaurantTagId)
+
+	for _, t := range tags {
+		insert = insert.Values(restId, t.Id)
+	}
 
-	return errors.Wrap(err, "could not insert into restaurants table")
+	_, err = insert.Exec()
+	return errors.Wrap(err, "could not insert restaurant tags")
 }
 
-// GetByRating returns a list of restaurants ordered by average rating, applying a number of filters (pagination, rating range, specific owner)
-// There is an index on the averageRating column so that this query executes faster.
-func (rs *restaurantsStore) GetByRating(top, skip int, forOwnerId *string, minRating, maxRating float32) ([]models.Restaurant, error) {
+// List returns a list of restaurants applying the filters, the ordering and the pagination of the given filter.
+// Restaurants with equal sort values are ordered by id, so that pages are stable. When the filter has a keyset,
+// the restaurants after it are returned instead of skipping rows, which keeps deep pages fast. Soft deleted restaurants are not returned.
+func (rs *restaurantsStore) List(filter stores.RestaurantsFilter) ([]models.Restaurant, error) {
+	sortColumn, ok := restaurantsSortColumns[filter.SortBy]
+	if !ok {
+		return nil, errors.Errorf("unsupported restaurants sort key %q", filter.SortBy)
+	}
+
 	query := rs.session.
-		Select(id, name, city, address, img, description, averageRating).
+		Select(restaurantListColumns...).
 		From(restaurantsTable).
-		Where(fmt.Sprintf("%s >= ? AND %s <= ?", averageRating, averageRating), minRating, maxRating).
-		OrderDesc(averageRating).
-		Offset(uint64(skip)).
-		Limit(uint64(top))
+		OrderDir(sortColumn.name, filter.SortAsc).
+		OrderDir(id, filter.SortAsc).
+		Limit(filter.Top)
 
-	if forOwnerId != nil {
-		query = query.Where(fmt.Sprintf("%s = ?", ownerId), forOwnerId)
+	query, err := applyRestaurantsFilter(query, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.After != nil {
+		if filter.After.SortBy != filter.SortBy {
+			return nil, errors.Errorf("keyset for sort key %q cannot be used when sorting by %q", filter.After.SortBy, filter.SortBy)
+		}
+
+		operator := "<"
+		if filter.SortAsc {
+			operator = ">"
+		}
+
+		query = query.Where(fmt.Sprintf("(%s, %s) %s (?::%s, ?)", sortColumn.name, id, operator, sortColumn.sqlType), filter.After.Value, filter.After.Id)
+	} else {
+		query = query.Offset(filter.Skip)
 	}
 
-	restaurants := make([]models.Restaurant, 0, top)
+	restaurants := make([]models.Restaurant, 0, filter.Top)
 
-	_, err := query.Load(&restaurants)
+	_, err = query.Load(&restaurants)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not get restaurants from db")
 	}
@@ -82,14 +290,250 @@ func (rs *restaurantsStore) GetByRating(top, skip int, forOwnerId *string, minRa
 	return restaurants, nil
 }
 
-// Exists check if a restaurant with a given ID exists.
+// Count returns the number of restaurants matching the filters of the given filter. The pagination and the keyset are not applied.
+func (rs *restaurantsStore) Count(filter stores.RestaurantsFilter) (int64, error) {
+	query := rs.session.
+		Select("count(*)").
+		From(restaurantsTable)
+
+	query, err := applyRestaurantsFilter(query, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	if err := query.LoadOne(&count); err != nil {
+		return 0, errors.Wrap(err, "could not count restaurants")
+	}
+
+	return count, nil
+}
+
+// Facets returns the numbers of restaurants matching the filters of the given filter per tag and per city, the most common first.
+// Only the maxCityFacets most common cities are returned. The pagination and the keyset are not applied.
+func (rs *restaurantsStore) Facets(filter stores.RestaurantsFilter) (*models.RestaurantFacets, error) {
+	facets := &models.RestaurantFacets{
+		Tags:   make([]models.TagFacet, 0),
+		Cities: make([]models.CityFacet, 0),
+	}
+
+	tagsQuery := rs.session.
+		Select("t.id", "t.slug", "t.name", "t.kind", "t.created_at", "count(*) AS count").
+		From(restaurantsTable).
+		Join(dbr.I(restaurantTagsTable).As("rt"), fmt.Sprintf("rt.%s = %s.%s", restaurantId, restaurantsTable, id)).
+		Join(dbr.I(tagsTable).As("t"), fmt.Sprintf("t.%s = rt.%s", id, restaurantTagId)).
+		GroupBy("t.id").
+		OrderDesc("count").
+		OrderAsc("t.name")
+
+	tagsQuery, err := applyRestaurantsFilter(tagsQuery, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = tagsQuery.Load(&facets.Tags); err != nil {
+		return nil, errors.Wrap(err, "could not count restaurants per tag")
+	}
+
+	citiesQuery := rs.session.
+		Select(city, "count(*) AS count").
+		From(restaurantsTable).
+		GroupBy(city).
+		OrderDesc("count").
+		OrderAsc(city).
+		Limit(maxCityFacets)
+
+	citiesQuery, err = applyRestaurantsFilter(citiesQuery, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = citiesQuery.Load(&facets.Cities); err != nil {
+		return nil, errors.Wrap(err, "could not count restaurants per city")
+	}
+
+	return facets, nil
+}
+
+// ListNearby returns the restaurants within filter.Near ordered by their distance from its center, the closest first.
+// Restaurants at the same distance are ordered by id, so that pages are stable. The sort key and the keyset of the filter are not applied.
+func (rs *restaurantsStore) ListNearby(filter stores.RestaurantsFilter) ([]models.NearbyRestaurant, error) {
+	if filter.Near == nil {
+		return nil, errors.New("a location is required to list nearby restaurants")
+	}
+
+	query := rs.session.
+		Select(restaurantListColumns...).
+		From(restaurantsTable).
+		OrderAsc("distance_km").
+		OrderAsc(id).
+		Limit(filter.Top).
+		Offset(filter.Skip)
+
+	query.Column = append(query.Column, dbr.Expr(fmt.Sprintf("earth_distance(ll_to_earth(?, ?), %s) / 1000 AS distance_km", earthLocation), filter.Near.Latitude, filter.Near.Longitude))
+
+	query, err := applyRestaurantsFilter(query, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]struct {
+		models.Restaurant
+		DistanceKm float64
+	}, 0, filter.Top)
+
+	if _, err = query.Load(&rows); err != nil {
+		return nil, errors.Wrap(err, "could not get nearby restaurants from db")
+	}
+
+	restaurants := make([]models.NearbyRestaurant, len(rows))
+	for i, r := range rows {
+		restaurants[i] = models.NearbyRestaurant{Restaurant: r.Restaurant, DistanceKm: r.DistanceKm}
+	}
+
+	return restaurants, nil
+}
+
+// applyRestaurantsFilter adds the conditions of the filter to a query selecting from the restaurants table.
+// All conditions are written against indexed columns or expressions, so that they can be combined without full scans:
+// the city filters use the lower(city) index, the location filter uses the GiST index on the location
+// and the unanswered reviews check uses the partial index on unanswered reviews.
+// The only exceptions are the ranges of the sub-rating averages, the price levels, the amenities and the opening hours, which are checked
+// on the rows selected by the other conditions. The opening hours and the schedule exceptions are looked up by their restaurant_id indexes.
+func applyRestaurantsFilter(query *dbr.SelectStmt, filter stores.RestaurantsFilter) (*dbr.SelectStmt, error) {
+	query = query.
+		Where(fmt.Sprintf("%s.%s IS NULL", restaurantsTable, deletedAt)).
+		Where(fmt.Sprintf("%s >= ? AND %s <= ?", averageRating, averageRating), filter.MinRating, filter.MaxRating)
+
+	for _, r := range filter.SubRatings {
+		column, ok := subRatingAverageColumn(r.Dimension)
+		if !ok {
+			return nil, errors.Errorf("unsupported rating dimension %q", r.Dimension)
+		}
+
+		query = query.Where(fmt.Sprintf("%s >= ? AND %s <= ?", column, column), r.Min, r.Max)
+	}
+
+	if filter.OwnerId != nil {
+		query = query.Where(fmt.Sprintf("%s = ?", ownerId), *filter.OwnerId)
+	}
+
+	if filter.City != "" {
+		query = query.Where(fmt.Sprintf("lower(%s) = ?", city), strings.ToLower(filter.City))
+	}
+
+	if filter.CityPrefix != "" {
+		query = query.Where(fmt.Sprintf("lower(%s) LIKE ?", city), likeEscaper.Replace(strings.ToLower(filter.CityPrefix))+"%")
+	}
+
+	if filter.MinReviews > 0 {
+		query = query.Where(fmt.Sprintf("%s >= ?", ratingsCount), filter.MinReviews)
+	}
+
+	if filter.Near != nil {
+		// earth_box is a bounding cube that can use the GiST index, but it contains some points outside of the radius,
+		// so the exact distance is checked as well
+		radiusMeters := filter.Near.RadiusKm * 1000
+		query = query.Where(fmt.Sprintf("earth_box(ll_to_earth(?, ?), ?) @> %s AND earth_distance(ll_to_earth(?, ?), %s) <= ?", earthLocation, earthLocation),
+			filter.Near.Latitude, filter.Near.Longitude, radiusMeters, filter.Near.Latitude, filter.Near.Longitude, radiusMeters)
+	}
+
+	if len(filter.PriceLevels) > 0 {
+		query = query.Where(fmt.Sprintf("%s IN ?", priceLevel), filter.PriceLevels)
+	}
+
+	for _, a := range filter.Amenities {
+		column, ok := amenityColumn(a)
+		if !ok {
+			return nil, errors.Errorf("unsupported amenity %q", a)
+		}
+
+		query = query.Where(column)
+	}
+
+	if len(filter.Tags) > 0 && filter.MatchAllTags {
+		query = query.Where(fmt.Sprintf(`%s.%s IN (
+			SELECT rt.%s FROM %s rt JOIN %s t ON t.%s = rt.%s WHERE t.%s IN ? GROUP BY rt.%s HAVING count(*) = ?)`,
+			restaurantsTable, id, restaurantId, restaurantTagsTable, tagsTable, id, restaurantTagId, slug, restaurantId), filter.Tags, len(filter.Tags))
+	} else if len(filter.Tags) > 0 {
+		query = query.Where(fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM %s rt JOIN %s t ON t.%s = rt.%s WHERE rt.%s = %s.%s AND t.%s IN ?)`,
+			restaurantTagsTable, tagsTable, id, restaurantTagId, restaurantId, restaurantsTable, id, slug), filter.Tags)
+	}
+
+	if filter.OpenAt != nil {
+		// The time is passed as text with its offset, so that it does not depend on the time zone of the database session
+		query = query.Where(openAtCondition, filter.OpenAt.UTC().Format(time.RFC3339))
+	}
+
+	if filter.HasUnansweredReviews {
+		query = query.Where(fmt.Sprintf("EXISTS (SELECT 1 FROM %s rv WHERE rv.%s = %s.%s AND rv.answer IS NULL)", reviewsTable, restaurantId, restaurantsTable, id))
+	}
+
+	return query, nil
+}
+
+// Search returns the restaurants that match a full-text query (in websearch syntax, e.g. "pizza -pineapple") ordered by relevance.
+// Matches in the name weigh more than matches in the city, which weigh more than matches in the description.
+// The snippet contains the best matching fragments of the description with the matched words highlighted.
+// It uses the GIN index on the search_vector column. Soft deleted restaurants are not returned.
+func (rs *restaurantsStore) Search(query, city string, forOwnerId *string, top, skip uint64) ([]models.RestaurantSearchResult, error) {
+	sqlQuery := strings.Builder{}
+	sqlQuery.WriteString(`
+		SELECT res.id, res.name, res.city, res.address, res.img, res.description, res.latitude, res.longitude, res.price_level, res.average_rating, ` + strings.Join(prefixedColumns("res", subRatingAverageColumns), ", ") + `,
+			ts_rank_cd(res.search_vector, q.query) AS rank,
+			ts_headline('english', res.description, q.query, ?) AS snippet
+		FROM restaurants res, websearch_to_tsquery('english', ?) q(query)
+		WHERE res.search_vector @@ q.query AND res.deleted_at IS NULL`)
+
+	args := []interface{}{headlineOptions, query}
+
+	if city != "" {
+		sqlQuery.WriteString(" AND lower(res.city) = lower(?)")
+		args = append(args, city)
+	}
+
+	if forOwnerId != nil {
+		sqlQuery.WriteString(" AND res.owner_id = ?")
+		args = append(args, *forOwnerId)
+	}
+
+	sqlQuery.WriteString(" ORDER BY rank DESC, res.id LIMIT ? OFFSET ?")
+	args = append(args, top, skip)
+
+	rows, err := rs.session.SelectBySql(sqlQuery.String(), args...).Rows()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not search restaurants")
+	}
+
+	defer rows.Close()
+
+	results := make([]models.RestaurantSearchResult, 0, top)
+	for rows.Next() {
+		r := models.RestaurantSearchResult{}
+
+		dest := []interface{}{&r.Restaurant.Id, &r.Restaurant.Name, &r.Restaurant.City, &r.Restaurant.Address, &r.Restaurant.Img,
+			&r.Restaurant.Description, &r.Restaurant.Latitude, &r.Restaurant.Longitude, &r.Restaurant.PriceLevel, &r.Restaurant.AverageRating}
+		dest = append(dest, subRatingAveragesPtrs(&r.Restaurant.SubRatingAverages)...)
+		err = rows.Scan(append(dest, &r.Rank, &r.Snippet)...)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot scan row")
+		}
+
+		results = append(results, r)
+	}
+
+	return results, errors.Wrap(rows.Err(), "could not iterate over search results")
+}
+
+// Exists check if a restaurant with a given ID exists and is not soft deleted.
 func (rs *restaurantsStore) Exists(restId string) (bool, error) {
 	idFoo := ""
 
 	err := rs.session.
 		Select(id).
 		From(restaurantsTable).
-		Where("id = ?", restId).
+		Where(fmt.Sprintf("%s = ? AND %s IS NULL", id, deletedAt), restId).
 		LoadOne(&idFoo)
 
 	if err != nil {
@@ -103,19 +547,110 @@ func (rs *restaurantsStore) Exists(restId string) (bool, error) {
 	return true, nil
 }
 
-func (rs *restaurantsStore) Delete(restId string) error {
-	_, err := rs.session.
+// Delete starts a new transaction and makes the following changes:
+// 1. Removes restaurant.min_review_id and restaurant.max_review_id so that they don't reference the reviews that will be deleted
+// 2. Locks the reviews of the restaurant, so that no media is added to them concurrently (the restaurant is locked by the first update)
+// 3. Deletes the images of the restaurant and the media of its reviews
+// 4. Deletes all reviews of the restaurant
+// 5. Deletes the restaurant itself
+// The number of deleted reviews and the deleted images and media are returned. Soft deleted restaurants can be deleted this way as well.
+func (rs *restaurantsStore) Delete(restId string) (int64, *models.DeletedUploads, error) {
+	tx, err := rs.session.Begin()
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	result, err := tx.
+		Update(restaurantsTable).
+		Set(minReviewId, nil).
+		Set(maxReviewId, nil).
+		Where(fmt.Sprintf("%s = ?", id), restId).
+		Exec()
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "could not clear min and max reviews")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "could not get number of updated restaurants")
+	}
+
+	if affected == 0 {
+		return 0, nil, db.ErrNotFound
+	}
+
+	reviewsOfRestaurant := "SELECT id FROM reviews WHERE restaurant_id = ?"
+	if err = lockRows(tx, reviewsOfRestaurant, restId); err != nil {
+		return 0, nil, err
+	}
+
+	uploads := new(models.DeletedUploads)
+	if uploads.Media, err = deleteReviewMedia(tx, reviewsOfRestaurant, restId); err != nil {
+		return 0, nil, err
+	}
+
+	if uploads.Images, err = deleteRestaurantImages(tx, "SELECT id FROM restaurants WHERE id = ?", restId); err != nil {
+		return 0, nil, err
+	}
+
+	result, err = tx.
+		DeleteFrom(reviewsTable).
+		Where(fmt.Sprintf("%s = ?", restaurantId), restId).
+		Exec()
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "could not delete reviews of restaurant")
+	}
+
+	deletedReviews, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "could not get number of deleted reviews")
+	}
+
+	_, err = tx.
 		DeleteFrom(restaurantsTable).
-		Where("id = ?", restId).
+		Where(fmt.Sprintf("%s = ?", id), restId).
 		Exec()
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "could not delete restaurant")
+	}
 
-	return errors.Wrap(err, "could not delete restaurant")
+	if err = tx.Commit(); err != nil {
+		return 0, nil, errors.Wrap(err, "could not commit transaction")
+	}
+
+	return deletedReviews, uploads, nil
+}
+
+// SoftDelete marks the restaurant as deleted, so that it is hidden from listings and can no longer be reviewed or updated.
+// Its reviews and rating statistics are kept in the database.
+func (rs *restaurantsStore) SoftDelete(restId string) error {
+	result, err := rs.session.
+		Update(restaurantsTable).
+		Set(deletedAt, time.Now().UTC()).
+		Where(fmt.Sprintf("%s = ? AND %s IS NULL", id, deletedAt), restId).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not soft delete restaurant")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "could not get number of soft deleted restaurants")
+	}
+
+	if affected == 0 {
+		return db.ErrNotFound
+	}
+
+	return nil
 }
 
 // GetSingle returns a restaurant by id, populating its min_review and max_review fields. This operation is extremely optimized
 // as the min_review_id and max_review_id are stored within the restaurant record and updated only when new reviews are added to the
 // restaurant. This allows for getting the restaurant and its worst and best reviews using a single query without searching in the
-// reviews table every time.
+// reviews table every time. The tags of the restaurant are loaded with a second query. Soft deleted restaurants are not returned.
 func (rs *restaurantsStore) GetSingle(resId string) (*models.Restaurant, error) {
 	r := struct {
 		Id                 string
@@ -125,31 +660,53 @@ func (rs *restaurantsStore) GetSingle(resId string) (*models.Restaurant, error)
 		Address            string
 		Img                string
 		Description        string
+		TimeZone           string
+		Latitude           *float64
+		Longitude          *float64
+		PriceLevel         *int
+		Amenities          models.Amenities
+		ContactDetails     models.ContactDetails
 		AverageRating      float32
+		SubRatingAverages  models.SubRatingAverages
 		MinReviewId        *string
 		MinReviewRating    *uint8
 		MinReviewTimestamp *time.Time
 		MinReviewComment   *string
 		MinReviewAnswer    *string
 		MinReviewReviewer  *string
+		MinSubRatings      models.SubRatings
 		MaxReviewId        *string
 		MaxReviewRating    *uint8
 		MaxReviewTimestamp *time.Time
 		MaxReviewComment   *string
 		MaxReviewAnswer    *string
 		MaxReviewReviewer  *string
+		MaxSubRatings      models.SubRatings
 	}{}
 
 	// Get the restaurant with its min and max reviews
-	err := rs.session.QueryRow(`
-			SELECT res.id, res.owner_id, res.name, res.city, res.address, res.img, res.description, res.average_rating, min_rv.id, min_rv.rating, min_rv.timestamp, min_rv.comment, min_rv.answer, min_usr.email, max_rv.id, max_rv.rating, max_rv.timestamp, max_rv.comment, max_rv.answer, max_usr.email
+	query := `
+			SELECT res.id, res.owner_id, res.name, res.city, res.address, res.img, res.description, res.time_zone, res.latitude, res.longitude, res.price_level,
+				` + strings.Join(prefixedColumns("res", amenityColumns), ", ") + `, res.phone, res.website, res.facebook_url, res.instagram_url, res.twitter_url, res.average_rating, ` + strings.Join(prefixedColumns("res", subRatingAverageColumns), ", ") + `,
+				min_rv.id, min_rv.rating, min_rv.timestamp, min_rv.comment, min_rv.answer, min_usr.email, ` + strings.Join(prefixedColumns("min_rv", subRatingColumns), ", ") + `,
+				max_rv.id, max_rv.rating, max_rv.timestamp, max_rv.comment, max_rv.answer, max_usr.email, ` + strings.Join(prefixedColumns("max_rv", subRatingColumns), ", ") + `
 			FROM restaurants res
 			LEFT JOIN reviews min_rv ON res.min_review_id = min_rv.id
 			LEFT JOIN users min_usr ON min_rv.reviewer_id = min_usr.id
 			LEFT JOIN reviews max_rv ON res.max_review_id = max_rv.id
 			LEFT JOIN users max_usr ON max_rv.reviewer_id = max_usr.id 
-			WHERE res.id = $1`, resId).
-		Scan(&r.Id, &r.OwnerId, &r.Name, &r.City, &r.Address, &r.Img, &r.Description, &r.AverageRating, &r.MinReviewId, &r.MinReviewRating, &r.MinReviewTimestamp, &r.MinReviewComment, &r.MinReviewAnswer, &r.MinReviewReviewer, &r.MaxReviewId, &r.MaxReviewRating, &r.MaxReviewTimestamp, &r.MaxReviewComment, &r.MaxReviewAnswer, &r.MaxReviewReviewer)
+			WHERE res.id = $1 AND res.deleted_at IS NULL`
+
+	dest := []interface{}{&r.Id, &r.OwnerId, &r.Name, &r.City, &r.Address, &r.Img, &r.Description, &r.TimeZone, &r.Latitude, &r.Longitude, &r.PriceLevel}
+	dest = append(dest, amenitiesPtrs(&r.Amenities)...)
+	dest = append(dest, &r.ContactDetails.Phone, &r.ContactDetails.Website, &r.ContactDetails.FacebookUrl, &r.ContactDetails.InstagramUrl, &r.ContactDetails.TwitterUrl, &r.AverageRating)
+	dest = append(dest, subRatingAveragesPtrs(&r.SubRatingAverages)...)
+	dest = append(dest, &r.MinReviewId, &r.MinReviewRating, &r.MinReviewTimestamp, &r.MinReviewComment, &r.MinReviewAnswer, &r.MinReviewReviewer)
+	dest = append(dest, subRatingsPtrs(&r.MinSubRatings)...)
+	dest = append(dest, &r.MaxReviewId, &r.MaxReviewRating, &r.MaxReviewTimestamp, &r.MaxReviewComment, &r.MaxReviewAnswer, &r.MaxReviewReviewer)
+	dest = append(dest, subRatingsPtrs(&r.MaxSubRatings)...)
+
+	err := rs.session.QueryRow(query, resId).Scan(dest...)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -160,16 +717,35 @@ func (rs *restaurantsStore) GetSingle(resId string) (*models.Restaurant, error)
 	}
 
 	restaurant := models.Restaurant{
-		Id:            r.Id,
-		OwnerId:       r.OwnerId,
-		MinReviewId:   r.MinReviewId,
-		MaxReviewId:   r.MaxReviewId,
-		Name:          r.Name,
-		City:          r.City,
-		Address:       r.Address,
-		Img:           r.Img,
-		Description:   r.Description,
-		AverageRating: r.AverageRating,
+		Id:                r.Id,
+		OwnerId:           r.OwnerId,
+		MinReviewId:       r.MinReviewId,
+		MaxReviewId:       r.MaxReviewId,
+		Name:              r.Name,
+		City:              r.City,
+		Address:           r.Address,
+		Img:               r.Img,
+		Description:       r.Description,
+		TimeZone:          r.TimeZone,
+		Latitude:          r.Latitude,
+		Longitude:         r.Longitude,
+		PriceLevel:        r.PriceLevel,
+		Amenities:         r.Amenities,
+		ContactDetails:    r.ContactDetails,
+		AverageRating:     r.AverageRating,
+		SubRatingAverages: r.SubRatingAverages,
+	}
+
+	_, err = rs.session.
+		Select("t.*").
+		From(dbr.I(tagsTable).As("t")).
+		Join(dbr.I(restaurantTagsTable).As("rt"), fmt.Sprintf("rt.%s = t.%s", restaurantTagId, id)).
+		Where(fmt.Sprintf("rt.%s = ?", restaurantId), r.Id).
+		OrderAsc("t." + kind).
+		OrderAsc("t." + name).
+		Load(&restaurant.Tags)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load restaurant tags")
 	}
 
 	if r.MinReviewId != nil {
@@ -177,6 +753,7 @@ func (rs *restaurantsStore) GetSingle(resId string) (*models.Restaurant, error)
 			Id:           *r.MinReviewId,
 			RestaurantId: r.Id,
 			Rating:       *r.MinReviewRating,
+			SubRatings:   r.MinSubRatings,
 			Timestamp:    *r.MinReviewTimestamp,
 			Comment:      *r.MinReviewComment,
 			Answer:       r.MinReviewAnswer,
@@ -191,6 +768,7 @@ func (rs *restaurantsStore) GetSingle(resId string) (*models.Restaurant, error)
 			Id:           *r.MaxReviewId,
 			RestaurantId: r.Id,
 			Rating:       *r.MaxReviewRating,
+			SubRatings:   r.MaxSubRatings,
 			Timestamp:    *r.MaxReviewTimestamp,
 			Comment:      *r.MaxReviewComment,
 			Answer:       r.MaxReviewAnswer,
@@ -0,0 +1,167 @@
+package dbr
+
+import (
+	"fmt"
+
+	"github.com/gocraft/dbr/v2"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/db/stores"
+)
+
+const (
+	reviewMediaTable = "review_media"
+	mediaReviewId    = "review_id"
+)
+
+type reviewMediaStore struct {
+	session *dbr.Session
+}
+
+// NewReviewMediaStore returns a ReviewMediaStore that uses the DBR driver
+func NewReviewMediaStore(session *dbr.Session) stores.ReviewMediaStore {
+	return &reviewMediaStore{
+		session: session,
+	}
+}
+
+// Insert generates a new ID for the media, unless it already has one, and starts a new transaction that inserts it in the database
+// if the review has fewer than maxPerReview media. The review row is locked, so concurrent uploads cannot exceed the limit.
+// db.ErrLimitReached is returned if the review already has maxPerReview media.
+func (rms *reviewMediaStore) Insert(media *models.ReviewMedia, maxPerReview int) error {
+	if media.Id == "" {
+		media.Id = uuid.NewV4().String()
+	}
+
+	tx, err := rms.session.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	lockedId := ""
+	err = tx.
+		Select(reviewId).
+		From(reviewsTable).
+		Where(fmt.Sprintf("%s = ?", reviewId), media.ReviewId).
+		Suffix("FOR UPDATE").
+		LoadOne(&lockedId)
+	if err != nil {
+		if err == dbr.ErrNotFound {
+			return db.ErrNotFound
+		}
+
+		return errors.Wrap(err, "could not lock review")
+	}
+
+	count := 0
+	err = tx.
+		Select("count(*)").
+		From(reviewMediaTable).
+		Where(fmt.Sprintf("%s = ?", mediaReviewId), media.ReviewId).
+		LoadOne(&count)
+	if err != nil {
+		return errors.Wrap(err, "could not count review media")
+	}
+
+	if count >= maxPerReview {
+		return db.ErrLimitReached
+	}
+
+	_, err = tx.
+		InsertInto(reviewMediaTable).
+		Columns(id, mediaReviewId, "storage_key", "thumbnail_key", "content_type", "width", "height", "size", createdAt).
+		Record(media).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not insert review media")
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit transaction")
+}
+
+// deleteReviewMedia deletes the media of the reviews selected by the query and returns their file keys. The media would be
+// deleted together with their reviews, but the rows deleted by the cascade cannot be returned.
+func deleteReviewMedia(tx *dbr.Tx, reviewsQuery string, value ...interface{}) ([]models.ReviewMedia, error) {
+	media := make([]models.ReviewMedia, 0)
+
+	_, err := tx.
+		SelectBySql(fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s) RETURNING storage_key, thumbnail_key", reviewMediaTable, mediaReviewId, reviewsQuery), value...).
+		Load(&media)
+
+	return media, errors.Wrap(err, "could not delete review media")
+}
+
+func (rms *reviewMediaStore) GetById(mediaId string) (*models.ReviewMedia, error) {
+	media := new(models.ReviewMedia)
+
+	err := rms.session.
+		Select("*").
+		From(reviewMediaTable).
+		Where(fmt.Sprintf("%s = ?", id), mediaId).
+		LoadOne(media)
+
+	if err != nil {
+		if err == dbr.ErrNotFound {
+			return nil, db.ErrNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not load review media")
+	}
+
+	return media, nil
+}
+
+// ListForReviews returns the media of all given reviews in the order of uploading
+func (rms *reviewMediaStore) ListForReviews(reviewIds []string) ([]models.ReviewMedia, error) {
+	media := make([]models.ReviewMedia, 0)
+	if len(reviewIds) == 0 {
+		return media, nil
+	}
+
+	_, err := rms.session.
+		Select("*").
+		From(reviewMediaTable).
+		Where(fmt.Sprintf("%s IN ?", mediaReviewId), reviewIds).
+		OrderAsc(createdAt).
+		Load(&media)
+
+	return media, errors.Wrap(err, "could not load review media")
+}
+
+func (rms *reviewMediaStore) CountForReview(revId string) (int, error) {
+	count := 0
+
+	err := rms.session.
+		Select("count(*)").
+		From(reviewMediaTable).
+		Where(fmt.Sprintf("%s = ?", mediaReviewId), revId).
+		LoadOne(&count)
+
+	return count, errors.Wrap(err, "could not count review media")
+}
+
+func (rms *reviewMediaStore) Delete(mediaId string) error {
+	result, err := rms.session.
+		DeleteFrom(reviewMediaTable).
+		Where(fmt.Sprintf("%s = ?", id), mediaId).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not delete review media")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "could not get number of deleted review media")
+	}
+
+	if affected == 0 {
+		return db.ErrNotFound
+	}
+
+	return nil
+}
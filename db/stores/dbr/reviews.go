@@ -2,6 +2,7 @@ package dbr
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/gocraft/dbr/v2"
 	"github.com/pkg/errors"
@@ -23,6 +24,64 @@ const (
 	answer       = "answer"
 )
 
+// subRatingColumns are the columns of the reviews table with the ratings of the dimensions, in the order of models.RatingDimensions
+var subRatingColumns = newSubRatingColumns("%s_rating")
+
+// subRatingsTotalColumns and subRatingsCountColumns are the columns of the restaurants table with the rating statistics of the dimensions,
+// while subRatingAverageColumns are the columns generated from them. They are in the order of models.RatingDimensions.
+var (
+	subRatingsTotalColumns  = newSubRatingColumns("%s_ratings_total")
+	subRatingsCountColumns  = newSubRatingColumns("%s_ratings_count")
+	subRatingAverageColumns = newSubRatingColumns("%s_average_rating")
+)
+
+func newSubRatingColumns(format string) []string {
+	columns := make([]string, len(models.RatingDimensions))
+	for i, d := range models.RatingDimensions {
+		columns[i] = fmt.Sprintf(format, d)
+	}
+
+	return columns
+}
+
+// subRatingAverageColumn returns the column of the restaurants table with the average rating of the dimension
+func subRatingAverageColumn(dimension models.RatingDimension) (string, bool) {
+	for i, d := range models.RatingDimensions {
+		if d == dimension {
+			return subRatingAverageColumns[i], true
+		}
+	}
+
+	return "", false
+}
+
+// prefixedColumns returns the columns prefixed with the name or alias of their table
+func prefixedColumns(table string, columns []string) []string {
+	prefixed := make([]string, len(columns))
+	for i, c := range columns {
+		prefixed[i] = table + "." + c
+	}
+
+	return prefixed
+}
+
+// subRatingsPtrs returns the destinations for scanning the columns of subRatingColumns
+func subRatingsPtrs(sr *models.SubRatings) []interface{} {
+	return []interface{}{&sr.FoodRating, &sr.ServiceRating, &sr.AmbienceRating, &sr.ValueRating}
+}
+
+// subRatingAveragesPtrs returns the destinations for scanning the columns of subRatingAverageColumns
+func subRatingAveragesPtrs(sra *models.SubRatingAverages) []interface{} {
+	return []interface{}{&sra.FoodAverageRating, &sra.ServiceAverageRating, &sra.AmbienceAverageRating, &sra.ValueAverageRating}
+}
+
+// reviewsSortExpressions maps the supported sort keys to the expressions that the reviews are ordered by
+var reviewsSortExpressions = map[stores.ReviewsSortKey]string{
+	stores.SortReviewsByTimestamp: "reviews.timestamp",
+	stores.SortReviewsByRating:    "reviews.rating",
+	stores.SortReviewsByHasAnswer: "(reviews.answer IS NOT NULL)",
+}
+
 type reviewsStore struct {
 	session *dbr.Session
 }
@@ -37,7 +96,8 @@ func NewReviewsStore(session *dbr.Session) stores.ReviewsStore {
 // GetById returns a review by its id or a ErrNotFound if it doesn't exist
 func (rs *reviewsStore) GetById(revId string) (*models.Review, error) {
 	rows, err := rs.session.
-		Select("reviews.id, reviews.restaurant_id, reviews.reviewer_id, reviews.rating, reviews.timestamp, reviews.comment, reviews.answer, restaurants.owner_id").
+		Select("reviews.id, reviews.restaurant_id, reviews.reviewer_id, reviews.rating, reviews.timestamp, reviews.comment, reviews.answer, restaurants.owner_id, "+
+			strings.Join(prefixedColumns(reviewsTable, subRatingColumns), ", ")).
 		From(reviewsTable).
 		Join(restaurantsTable, fmt.Sprintf("%s.%s = %s.%s", reviewsTable, restaurantId, restaurantsTable, id)).
 		Where(fmt.Sprintf("%s.%s = ?", reviewsTable, reviewId), revId).
@@ -55,7 +115,8 @@ func (rs *reviewsStore) GetById(revId string) (*models.Review, error) {
 		Restaurant: &models.Restaurant{},
 	}
 
-	err = rows.Scan(&r.Id, &r.RestaurantId, &r.ReviewerId, &r.Rating, &r.Timestamp, &r.Comment, &r.Answer, &r.Restaurant.OwnerId)
+	dest := []interface{}{&r.Id, &r.RestaurantId, &r.ReviewerId, &r.Rating, &r.Timestamp, &r.Comment, &r.Answer, &r.Restaurant.OwnerId}
+	err = rows.Scan(append(dest, subRatingsPtrs(&r.SubRatings)...)...)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not scan review row")
 	}
@@ -63,24 +124,129 @@ func (rs *reviewsStore) GetById(revId string) (*models.Review, error) {
 	return &r, nil
 }
 
-// Update updates the rating, comment, and answer of a given review by its id.
+// Update starts a new transaction that updates the ratings, comment, and answer of a given review by its id
+// and then recomputes the rating statistics and the min and max reviews of the restaurant, as the rating might have changed.
 func (rs *reviewsStore) Update(review *models.Review) error {
-	_, err := rs.session.
+	tx, err := rs.session.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	restId := ""
+
+	query := tx.
 		Update(reviewsTable).
 		Set(rating, review.Rating).
 		Set(comment, review.Comment).
-		Set(answer, review.Answer).
+		Set(answer, review.Answer)
+
+	for i, d := range models.RatingDimensions {
+		query = query.Set(subRatingColumns[i], review.SubRatings.ForDimension(d))
+	}
+
+	err = query.
 		Where(fmt.Sprintf("%s = ?", reviewId), review.Id).
+		Returning(restaurantId).
+		Load(&restId)
+	if err != nil {
+		return errors.Wrap(err, "could not update review")
+	}
+
+	if restId == "" {
+		return db.ErrNotFound
+	}
+
+	if err = recomputeRestaurantStatistics(tx, restId); err != nil {
+		return err
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit transaction")
+}
+
+// Delete starts a new transaction and makes the following changes:
+// 1. Removes the references to the review from restaurant.min_review_id and restaurant.max_review_id so that the FK is not violated
+// 2. Deletes the review from the reviews table
+// 3. Recomputes the rating statistics and selects new min and max reviews for the restaurant
+func (rs *reviewsStore) Delete(revId string) error {
+	tx, err := rs.session.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	restId := ""
+
+	err = tx.
+		Select(restaurantId).
+		From(reviewsTable).
+		Where(fmt.Sprintf("%s = ?", reviewId), revId).
+		LoadOne(&restId)
+	if err != nil {
+		if err == dbr.ErrNotFound {
+			return db.ErrNotFound
+		}
+
+		return errors.Wrap(err, "could not get restaurant of review")
+	}
+
+	_, err = tx.
+		Update(restaurantsTable).
+		Set(minReviewId, nil).
+		Set(maxReviewId, nil).
+		Where(fmt.Sprintf("%s = ?", id), restId).
 		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not clear min and max reviews")
+	}
+
+	_, err = tx.
+		DeleteFrom(reviewsTable).
+		Where(fmt.Sprintf("%s = ?", reviewId), revId).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not delete review")
+	}
 
-	return errors.Wrap(err, "could not update review")
+	if err = recomputeRestaurantStatistics(tx, restId); err != nil {
+		return err
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit transaction")
+}
+
+// recomputeRestaurantStatistics recalculates the rating statistics of a restaurant (the overall ones and the ones of every dimension)
+// from the reviews table and selects the min and max reviews again. In case of equal ratings the newest review is preferred, same as in Insert.
+// It is meant to be called within a transaction that has changed or removed existing reviews of the restaurant.
+func recomputeRestaurantStatistics(tx *dbr.Tx, restId string) error {
+	query := tx.
+		Update(restaurantsTable).
+		Set(ratingsTotal, dbr.Expr("(SELECT COALESCE(SUM(rating), 0) FROM reviews WHERE restaurant_id = ?)", restId)).
+		Set(ratingsCount, dbr.Expr("(SELECT COUNT(*) FROM reviews WHERE restaurant_id = ?)", restId)).
+		Set(minReviewId, dbr.Expr("(SELECT id FROM reviews WHERE restaurant_id = ? ORDER BY rating ASC, timestamp DESC, id LIMIT 1)", restId)).
+		Set(maxReviewId, dbr.Expr("(SELECT id FROM reviews WHERE restaurant_id = ? ORDER BY rating DESC, timestamp DESC, id LIMIT 1)", restId))
+
+	for i := range models.RatingDimensions {
+		query = query.
+			Set(subRatingsTotalColumns[i], dbr.Expr(fmt.Sprintf("(SELECT COALESCE(SUM(%s), 0) FROM reviews WHERE restaurant_id = ?)", subRatingColumns[i]), restId)).
+			Set(subRatingsCountColumns[i], dbr.Expr(fmt.Sprintf("(SELECT COUNT(%s) FROM reviews WHERE restaurant_id = ?)", subRatingColumns[i]), restId))
+	}
+
+	_, err := query.
+		Where(fmt.Sprintf("%s = ?", id), restId).
+		Exec()
+
+	return errors.Wrap(err, "could not recompute rating statistics for restaurant")
 }
 
 // Insert starts a new transaction and makes the following changes:
 // 1. Inserts the review in the reviews table
 // 2. Swaps the restaurant.min_review with the current review in case it has worse score
 // 3. Swaps the restaurant.max_review with the current review in case it has better score
-// 4. Updates the restaurant.ratings_total and restaurant.ratings_count so that restaurant.average_rating is automatically updated by the DB
+// 4. Updates the restaurant.ratings_total and restaurant.ratings_count so that restaurant.average_rating is automatically updated by the DB.
+// The statistics of the dimensions are updated in the same way for the sub-ratings that the review has.
 func (rs *reviewsStore) Insert(review *models.Review) error {
 	if review.Id == "" {
 		review.Id = uuid.NewV4().String()
@@ -95,7 +261,7 @@ func (rs *reviewsStore) Insert(review *models.Review) error {
 
 	_, err = tx.
 		InsertInto(reviewsTable).
-		Columns(reviewId, restaurantId, reviewerId, rating, timestamp, comment, answer).
+		Columns(append([]string{reviewId, restaurantId, reviewerId, rating, timestamp, comment, answer}, subRatingColumns...)...).
 		Record(review).
 		Exec()
 	if err != nil {
@@ -122,12 +288,22 @@ func (rs *reviewsStore) Insert(review *models.Review) error {
 		return errors.Wrap(err, "could not update max review")
 	}
 
-	_, err = tx.UpdateBySql(`
-		UPDATE restaurants
-		SET ratings_total = ratings_total + ?,
-		ratings_count = ratings_count + 1
-		WHERE id = ?`,
-		review.Rating, review.RestaurantId).Exec()
+	statistics := tx.
+		Update(restaurantsTable).
+		Set(ratingsTotal, dbr.Expr(ratingsTotal+" + ?", review.Rating)).
+		Set(ratingsCount, dbr.Expr(ratingsCount+" + 1"))
+
+	for i, d := range models.RatingDimensions {
+		if subRating := review.SubRatings.ForDimension(d); subRating != nil {
+			statistics = statistics.
+				Set(subRatingsTotalColumns[i], dbr.Expr(subRatingsTotalColumns[i]+" + ?", *subRating)).
+				Set(subRatingsCountColumns[i], dbr.Expr(subRatingsCountColumns[i]+" + 1"))
+		}
+	}
+
+	_, err = statistics.
+		Where(fmt.Sprintf("%s = ?", id), review.RestaurantId).
+		Exec()
 	if err != nil {
 		return errors.Wrap(err, "could not update rating statistics for restaurants")
 	}
@@ -156,34 +332,57 @@ func (rs *reviewsStore) ExistsForUserAndRestaurant(userId, restaurantId string)
 	return true, nil
 }
 
-// ListForRestaurant returns reviews for a particular restaurantId by applying filters (pagination, orderBy, only unanswered reviews)
-func (rs *reviewsStore) ListForRestaurant(restaurantId string, unanswered bool, top, skip uint64, orderBy string, isAsc bool) ([]models.Review, error) {
+// ListForRestaurant returns reviews for a particular restaurant by applying the filters (pagination, ordering, only unanswered reviews).
+// Reviews with equal sort values are ordered by id. When the filter has a keyset, the reviews after it are returned instead of skipping rows.
+func (rs *reviewsStore) ListForRestaurant(filter stores.ReviewsFilter) ([]models.Review, error) {
+	sortExpression, ok := reviewsSortExpressions[filter.SortBy]
+	if !ok {
+		return nil, errors.Errorf("unsupported reviews sort key %q", filter.SortBy)
+	}
+
 	query := rs.session.
-		Select("reviews.id, reviews.rating, reviews.timestamp, reviews.comment, reviews.answer, users.email").
+		Select("reviews.id, reviews.rating, reviews.timestamp, reviews.comment, reviews.answer, users.email, "+
+			strings.Join(prefixedColumns(reviewsTable, subRatingColumns), ", ")).
 		From(reviewsTable).
 		Join(usersTable, "reviews.reviewer_id = users.id").
-		Where("restaurant_id = ?", restaurantId).
-		OrderDir(orderBy, isAsc).
-		Limit(top).
-		Offset(skip)
+		Where("restaurant_id = ?", filter.RestaurantId).
+		OrderDir(sortExpression, filter.SortAsc).
+		OrderDir("reviews.id", filter.SortAsc).
+		Limit(filter.Top)
 
-	if unanswered {
+	if filter.Unanswered {
 		query = query.
 			Where("answer is NULL")
 	}
 
+	if filter.After != nil {
+		if filter.SortBy != stores.SortReviewsByTimestamp {
+			return nil, errors.Errorf("keyset cannot be used when sorting by %q", filter.SortBy)
+		}
+
+		operator := "<"
+		if filter.SortAsc {
+			operator = ">"
+		}
+
+		query = query.Where(fmt.Sprintf("(reviews.timestamp, reviews.id) %s (?, ?)", operator), filter.After.Timestamp, filter.After.Id)
+	} else {
+		query = query.Offset(filter.Skip)
+	}
+
 	rows, err := query.Rows()
 	if err != nil {
 		return nil, errors.Wrap(err, "could not query for reviews")
 	}
 
-	reviews := make([]models.Review, 0, top)
+	reviews := make([]models.Review, 0, filter.Top)
 	for rows.Next() {
 		r := models.Review{
 			Reviewer: &models.User{},
 		}
 
-		err = rows.Scan(&r.Id, &r.Rating, &r.Timestamp, &r.Comment, &r.Answer, &r.Reviewer.Email)
+		dest := []interface{}{&r.Id, &r.Rating, &r.Timestamp, &r.Comment, &r.Answer, &r.Reviewer.Email}
+		err = rows.Scan(append(dest, subRatingsPtrs(&r.SubRatings)...)...)
 		if err != nil {
 			return nil, errors.Wrap(err, "cannot scan row")
 		}
@@ -193,3 +392,65 @@ func (rs *reviewsStore) ListForRestaurant(restaurantId string, unanswered bool,
 
 	return reviews, nil
 }
+
+// CountForRestaurant returns the number of reviews of a restaurant matching the filter. The pagination and the keyset are not applied.
+func (rs *reviewsStore) CountForRestaurant(filter stores.ReviewsFilter) (int64, error) {
+	query := rs.session.
+		Select("count(*)").
+		From(reviewsTable).
+		Where("restaurant_id = ?", filter.RestaurantId)
+
+	if filter.Unanswered {
+		query = query.
+			Where("answer is NULL")
+	}
+
+	var count int64
+	if err := query.LoadOne(&count); err != nil {
+		return 0, errors.Wrap(err, "could not count reviews")
+	}
+
+	return count, nil
+}
+
+// Search returns the reviews of a restaurant whose comment matches a full-text query (in websearch syntax) ordered by relevance.
+// The snippet contains the best matching fragments of the comment with the matched words highlighted.
+func (rs *reviewsStore) Search(restaurantId, query string, top, skip uint64) ([]models.ReviewSearchResult, error) {
+	rows, err := rs.session.SelectBySql(`
+		SELECT rv.id, rv.rating, rv.timestamp, rv.comment, rv.answer, usr.email, `+strings.Join(prefixedColumns("rv", subRatingColumns), ", ")+`,
+			ts_rank_cd(rv.search_vector, q.query) AS rank,
+			ts_headline('english', rv.comment, q.query, ?) AS snippet
+		FROM reviews rv
+		JOIN users usr ON rv.reviewer_id = usr.id,
+		websearch_to_tsquery('english', ?) q(query)
+		WHERE rv.restaurant_id = ? AND rv.search_vector @@ q.query
+		ORDER BY rank DESC, rv.id
+		LIMIT ? OFFSET ?`,
+		headlineOptions, query, restaurantId, top, skip).Rows()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not search reviews")
+	}
+
+	defer rows.Close()
+
+	results := make([]models.ReviewSearchResult, 0, top)
+	for rows.Next() {
+		r := models.ReviewSearchResult{
+			Review: models.Review{
+				RestaurantId: restaurantId,
+				Reviewer:     &models.User{},
+			},
+		}
+
+		dest := []interface{}{&r.Review.Id, &r.Review.Rating, &r.Review.Timestamp, &r.Review.Comment, &r.Review.Answer, &r.Review.Reviewer.Email}
+		dest = append(dest, subRatingsPtrs(&r.Review.SubRatings)...)
+		err = rows.Scan(append(dest, &r.Rank, &r.Snippet)...)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot scan row")
+		}
+
+		results = append(results, r)
+	}
+
+	return results, errors.Wrap(rows.Err(), "could not iterate over search results")
+}
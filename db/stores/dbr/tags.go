@@ -0,0 +1,173 @@
+package dbr
+
+import (
+	"fmt"
+
+	"github.com/gocraft/dbr/v2"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/db/stores"
+)
+
+const (
+	tagsTable           = "tags"
+	restaurantTagsTable = "restaurant_tags"
+	restaurantTagId     = "tag_id"
+	slug                = "slug"
+	kind                = "kind"
+)
+
+type tagsStore struct {
+	session *dbr.Session
+}
+
+// NewTagsStore returns a TagsStore that uses the DBR driver
+func NewTagsStore(session *dbr.Session) stores.TagsStore {
+	return &tagsStore{
+		session: session,
+	}
+}
+
+// Insert generates a new ID for the tag, unless it already has one, and inserts it in the database
+func (ts *tagsStore) Insert(tag *models.Tag) error {
+	if tag.Id == "" {
+		tag.Id = uuid.NewV4().String()
+	}
+
+	_, err := ts.session.
+		InsertInto(tagsTable).
+		Columns(id, slug, name, kind, createdAt).
+		Record(tag).
+		Exec()
+
+	return errors.Wrap(err, "could not insert tag")
+}
+
+// Update updates the slug, the name and the kind of a tag by its id
+func (ts *tagsStore) Update(tag *models.Tag) error {
+	result, err := ts.session.
+		Update(tagsTable).
+		Set(slug, tag.Slug).
+		Set(name, tag.Name).
+		Set(kind, tag.Kind).
+		Where(fmt.Sprintf("%s = ?", id), tag.Id).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not update tag")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "could not get number of updated tags")
+	}
+
+	if affected == 0 {
+		return db.ErrNotFound
+	}
+
+	return nil
+}
+
+func (ts *tagsStore) GetById(tagId string) (*models.Tag, error) {
+	return ts.getBy(id, tagId)
+}
+
+func (ts *tagsStore) GetBySlug(tagSlug string) (*models.Tag, error) {
+	return ts.getBy(slug, tagSlug)
+}
+
+func (ts *tagsStore) getBy(column, value string) (*models.Tag, error) {
+	tag := new(models.Tag)
+
+	err := ts.session.
+		Select("*").
+		From(tagsTable).
+		Where(fmt.Sprintf("%s = ?", column), value).
+		LoadOne(tag)
+
+	if err != nil {
+		if err == dbr.ErrNotFound {
+			return nil, db.ErrNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not load tag")
+	}
+
+	return tag, nil
+}
+
+// List returns all tags ordered by kind and name
+func (ts *tagsStore) List() ([]models.Tag, error) {
+	tags := make([]models.Tag, 0)
+
+	_, err := ts.session.
+		Select("*").
+		From(tagsTable).
+		OrderAsc(kind).
+		OrderAsc(name).
+		Load(&tags)
+
+	return tags, errors.Wrap(err, "could not load tags")
+}
+
+// ListBySlugs returns the tags with the given slugs. Unknown slugs are ignored.
+func (ts *tagsStore) ListBySlugs(slugs []string) ([]models.Tag, error) {
+	tags := make([]models.Tag, 0, len(slugs))
+	if len(slugs) == 0 {
+		return tags, nil
+	}
+
+	_, err := ts.session.
+		Select("*").
+		From(tagsTable).
+		Where(fmt.Sprintf("%s IN ?", slug), slugs).
+		OrderAsc(kind).
+		OrderAsc(name).
+		Load(&tags)
+
+	return tags, errors.Wrap(err, "could not load tags by slugs")
+}
+
+// ListForRestaurants returns the tags of several restaurants at once, ordered by kind and name
+func (ts *tagsStore) ListForRestaurants(restIds []string) ([]models.RestaurantTag, error) {
+	tags := make([]models.RestaurantTag, 0)
+	if len(restIds) == 0 {
+		return tags, nil
+	}
+
+	_, err := ts.session.
+		Select("rt.restaurant_id", "t.*").
+		From(dbr.I(restaurantTagsTable).As("rt")).
+		Join(dbr.I(tagsTable).As("t"), fmt.Sprintf("t.%s = rt.%s", id, restaurantTagId)).
+		Where(fmt.Sprintf("rt.%s IN ?", restaurantId), restIds).
+		OrderAsc("t." + kind).
+		OrderAsc("t." + name).
+		Load(&tags)
+
+	return tags, errors.Wrap(err, "could not load tags of restaurants")
+}
+
+// Delete deletes a tag and removes it from all restaurants
+func (ts *tagsStore) Delete(tagId string) error {
+	result, err := ts.session.
+		DeleteFrom(tagsTable).
+		Where(fmt.Sprintf("%s = ?", id), tagId).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not delete tag")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "could not get number of deleted tags")
+	}
+
+	if affected == 0 {
+		return db.ErrNotFound
+	}
+
+	return nil
+}
@@ -1,6 +1,9 @@
 package dbr
 
 import (
+	"strings"
+	"time"
+
 	"github.com/gocraft/dbr/v2"
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
@@ -12,6 +15,9 @@ import (
 
 const usersTable = "users"
 
+// likeEscaper escapes the special characters of a LIKE pattern, so that user input is matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 type usersStore struct {
 	session *dbr.Session
 }
@@ -31,7 +37,7 @@ func (us *usersStore) Insert(user *models.User) error {
 
 	_, err := us.session.
 		InsertInto(usersTable).
-		Columns("email", "email_confirmed", "email_confirmation_token", "hashed_password", "role").
+		Columns("id", "email", "email_confirmed", "email_confirmation_token", "email_confirmation_token_created_at", "hashed_password", "role", "locale").
 		Record(user).
 		Exec()
 
@@ -58,14 +64,311 @@ func (us *usersStore) GetByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
+// GetById returns a user by its id
+func (us *usersStore) GetById(id string) (*models.User, error) {
+	user := new(models.User)
+	err := us.session.
+		Select("*").
+		From(usersTable).
+		Where("id = ?", id).
+		LoadOne(user)
+
+	if err != nil {
+		if err == dbr.ErrNotFound {
+			return nil, db.ErrNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not load user")
+	}
+
+	return user, nil
+}
+
+// List returns users ordered by email, applying pagination. If emailSearch is not empty, only users whose email
+// contains it (case insensitive) are returned.
+func (us *usersStore) List(top, skip uint64, emailSearch string) ([]models.User, error) {
+	query := us.session.
+		Select("id", "email", "email_confirmed", "role", "disabled").
+		From(usersTable).
+		OrderAsc("email").
+		Limit(top).
+		Offset(skip)
+
+	if emailSearch != "" {
+		query = query.Where("email ILIKE ?", "%"+likeEscaper.Replace(emailSearch)+"%")
+	}
+
+	users := make([]models.User, 0, top)
+
+	_, err := query.Load(&users)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get users from db")
+	}
+
+	return users, nil
+}
+
+// Update updates the role and the disabled status of a given user by its id.
+// The token version is incremented as well, so that access tokens issued with the old role are no longer accepted.
+func (us *usersStore) Update(user *models.User) error {
+	result, err := us.session.
+		Update(usersTable).
+		Set("role", user.Role).
+		Set("disabled", user.Disabled).
+		IncrBy("token_version", 1).
+		Where("id = ?", user.Id).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not update user")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "could not get number of updated users")
+	}
+
+	if affected == 0 {
+		return db.ErrNotFound
+	}
+
+	user.TokenVersion++
+
+	return nil
+}
+
+// Delete starts a new transaction and removes the user together with everything they own:
+// 1. All restaurants owned by the user are deleted together with all of their reviews
+// 2. All reviews written by the user are deleted and the rating statistics of the reviewed restaurants are recomputed
+// 3. All refresh tokens of the user are deleted
+// 4. The user itself is deleted
+// The images of the owned restaurants and the media of the deleted reviews are returned, so that their files can be deleted as well.
+func (us *usersStore) Delete(id string) (*models.DeletedUploads, error) {
+	tx, err := us.session.Begin()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	ownedRestaurants := "SELECT id FROM restaurants WHERE owner_id = ?"
+	deletedReviews := "SELECT id FROM reviews WHERE reviewer_id = ? OR restaurant_id IN (SELECT id FROM restaurants WHERE owner_id = ?)"
+
+	// Nothing can be added to locked rows, so no images or media are uploaded after they are deleted below
+	if err = lockRows(tx, "SELECT id FROM users WHERE id = ?", id); err != nil {
+		return nil, err
+	}
+
+	if err = lockRows(tx, ownedRestaurants, id); err != nil {
+		return nil, err
+	}
+
+	if err = lockRows(tx, deletedReviews, id, id); err != nil {
+		return nil, err
+	}
+
+	uploads := new(models.DeletedUploads)
+	if uploads.Media, err = deleteReviewMedia(tx, deletedReviews, id, id); err != nil {
+		return nil, err
+	}
+
+	if uploads.Images, err = deleteRestaurantImages(tx, ownedRestaurants, id); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.UpdateBySql(`
+		UPDATE restaurants
+		SET min_review_id = NULL, max_review_id = NULL
+		WHERE owner_id = ?`,
+		id).Exec()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not clear min and max reviews of owned restaurants")
+	}
+
+	_, err = tx.DeleteBySql(`
+		DELETE FROM reviews
+		WHERE restaurant_id IN (SELECT id FROM restaurants WHERE owner_id = ?)`,
+		id).Exec()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not delete reviews of owned restaurants")
+	}
+
+	_, err = tx.
+		DeleteFrom(restaurantsTable).
+		Where("owner_id = ?", id).
+		Exec()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not delete owned restaurants")
+	}
+
+	reviewedRestaurants := make([]string, 0)
+
+	_, err = tx.
+		Select(restaurantId).
+		From(reviewsTable).
+		Where("reviewer_id = ?", id).
+		Load(&reviewedRestaurants)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get reviewed restaurants")
+	}
+
+	if len(reviewedRestaurants) > 0 {
+		_, err = tx.
+			Update(restaurantsTable).
+			Set(minReviewId, nil).
+			Set(maxReviewId, nil).
+			Where("id IN ?", reviewedRestaurants).
+			Exec()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not clear min and max reviews of reviewed restaurants")
+		}
+
+		_, err = tx.
+			DeleteFrom(reviewsTable).
+			Where("reviewer_id = ?", id).
+			Exec()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not delete reviews of user")
+		}
+
+		for _, restId := range reviewedRestaurants {
+			if err = recomputeRestaurantStatistics(tx, restId); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	_, err = tx.
+		DeleteFrom(refreshTokensTable).
+		Where("user_id = ?", id).
+		Exec()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not delete refresh tokens of user")
+	}
+
+	result, err := tx.
+		DeleteFrom(usersTable).
+		Where("id = ?", id).
+		Exec()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not delete user")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get number of deleted users")
+	}
+
+	if affected == 0 {
+		return nil, db.ErrNotFound
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "could not commit transaction")
+	}
+
+	return uploads, nil
+}
+
 // ConfirmEmail sets the user email as confirmed and removes the confirmation token from the DB
 func (us *usersStore) ConfirmEmail(id string) error {
 	_, err := us.session.
 		Update(usersTable).
 		Set("email_confirmed", true).
 		Set("email_confirmation_token", nil).
+		Set("email_confirmation_token_created_at", nil).
 		Where("id = ?", id).
 		Exec()
 
 	return errors.Wrap(err, "could not update user fields")
 }
+
+// SetEmailConfirmationToken replaces the email confirmation token of the user with a new one created at the given time,
+// but only if the previous token was created by previousCreatedBy, which limits how often tokens are issued.
+// It returns false if the token is not set because the previous one is too recent.
+func (us *usersStore) SetEmailConfirmationToken(id, token string, createdAt, previousCreatedBy time.Time) (bool, error) {
+	result, err := us.session.
+		Update(usersTable).
+		Set("email_confirmation_token", token).
+		Set("email_confirmation_token_created_at", createdAt).
+		Where("id = ? AND (email_confirmation_token_created_at IS NULL OR email_confirmation_token_created_at <= ?)", id, previousCreatedBy).
+		Exec()
+	if err != nil {
+		return false, errors.Wrap(err, "could not set email confirmation token")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "could not get number of updated users")
+	}
+
+	return affected > 0, nil
+}
+
+// SetPasswordResetToken stores a password reset token for the user that can be used until it expires. Any previous password
+// reset token of the user is overwritten, but only if it expires by previousExpiresBy, which limits how often tokens are issued.
+// It returns false if the token is not set because the previous one is too recent.
+func (us *usersStore) SetPasswordResetToken(id, token string, expiresAt, previousExpiresBy time.Time) (bool, error) {
+	result, err := us.session.
+		Update(usersTable).
+		Set("password_reset_token", token).
+		Set("password_reset_token_expires_at", expiresAt).
+		Where("id = ? AND (password_reset_token_expires_at IS NULL OR password_reset_token_expires_at <= ?)", id, previousExpiresBy).
+		Exec()
+	if err != nil {
+		return false, errors.Wrap(err, "could not set password reset token")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "could not get number of updated users")
+	}
+
+	return affected > 0, nil
+}
+
+// ResetPassword starts a new transaction that changes the password of the user, removes the password reset token,
+// and revokes all sessions of the user (both refresh tokens and already issued access tokens). The password is changed only
+// if the token is still the valid token of the user, so a token cannot be used twice even by concurrent requests.
+// db.ErrNotFound is returned otherwise.
+func (us *usersStore) ResetPassword(id, token, hashedPassword string) error {
+	tx, err := us.session.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer tx.RollbackUnlessCommitted()
+
+	now := time.Now().UTC()
+
+	result, err := tx.
+		Update(usersTable).
+		Set("hashed_password", hashedPassword).
+		Set("password_reset_token", nil).
+		Set("password_reset_token_expires_at", nil).
+		IncrBy("token_version", 1).
+		Where("id = ? AND password_reset_token = ? AND password_reset_token_expires_at > ?", id, token, now).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not update password")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "could not get number of updated users")
+	}
+
+	if affected == 0 {
+		return db.ErrNotFound
+	}
+
+	_, err = tx.
+		Update(refreshTokensTable).
+		Set("revoked_at", now).
+		Where("user_id = ? AND revoked_at IS NULL", id).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not revoke refresh tokens")
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit transaction")
+}
@@ -0,0 +1,148 @@
+package stores
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+)
+
+// RestaurantsSortKey is one of the supported keys that restaurants can be ordered by
+type RestaurantsSortKey string
+
+const (
+	SortRestaurantsByRating      RestaurantsSortKey = "rating"
+	SortRestaurantsByReviewCount RestaurantsSortKey = "reviews"
+	SortRestaurantsByName        RestaurantsSortKey = "name"
+	SortRestaurantsByNewest      RestaurantsSortKey = "newest"
+)
+
+// RestaurantsSortKeys are all supported restaurant sort keys
+var RestaurantsSortKeys = []RestaurantsSortKey{
+	SortRestaurantsByRating,
+	SortRestaurantsByReviewCount,
+	SortRestaurantsByName,
+	SortRestaurantsByNewest,
+}
+
+// DefaultAscending returns the natural direction of the sort key: names are ordered alphabetically,
+// while the best rated, the most reviewed and the newest restaurants come first.
+func (k RestaurantsSortKey) DefaultAscending() bool {
+	return k == SortRestaurantsByName
+}
+
+// GeoCircle is an area within RadiusKm kilometers from a location
+type GeoCircle struct {
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+}
+
+// SubRatingRange is an inclusive range of the average rating of a restaurant in a dimension
+type SubRatingRange struct {
+	Dimension models.RatingDimension
+	Min       float32
+	Max       float32
+}
+
+// RestaurantsFilter holds all filters, the ordering and the pagination applied when listing restaurants.
+// The zero values of the optional filters mean that they are not applied.
+type RestaurantsFilter struct {
+	Top  uint64
+	Skip uint64
+
+	// OwnerId limits the restaurants to the ones owned by a particular user
+	OwnerId   *string
+	MinRating float32
+	MaxRating float32
+	// SubRatings limit the average ratings of the restaurants in some dimensions.
+	// Restaurants without ratings in these dimensions are not returned.
+	SubRatings []SubRatingRange
+	// City matches the city case-insensitively
+	City string
+	// CityPrefix matches the beginning of the city case-insensitively
+	CityPrefix string
+	MinReviews int
+	// HasUnansweredReviews limits the restaurants to the ones with at least one review without an answer
+	HasUnansweredReviews bool
+	// PriceLevels limit the restaurants to the ones with any of these price levels
+	PriceLevels []int
+	// Amenities limit the restaurants to the ones offering all of these amenities
+	Amenities []models.Amenity
+	// Tags limit the restaurants to the ones with any of the tags with these slugs, or with all of them when MatchAllTags is set
+	Tags         []string
+	MatchAllTags bool
+	// OpenAt limits the restaurants to the ones that are open at the given time according to their opening hours and schedule exceptions
+	OpenAt *time.Time
+	// Near limits the restaurants to the ones within a radius from a location. Restaurants without a location are not returned.
+	Near *GeoCircle
+
+	SortBy  RestaurantsSortKey
+	SortAsc bool
+	// After is the position of the last restaurant of the previous page. When set, Skip is not applied.
+	After *RestaurantsKeyset
+}
+
+// RestaurantsKeyset is the position of a restaurant in a listing ordered by a sort key.
+// The value of the sort key is kept as text, so that it can be compared with the column without losing precision.
+type RestaurantsKeyset struct {
+	SortBy RestaurantsSortKey
+	Value  string
+	Id     string
+}
+
+// NewRestaurantsKeyset returns the position of the restaurant in a listing ordered by the given sort key
+func NewRestaurantsKeyset(sortBy RestaurantsSortKey, restaurant *models.Restaurant) RestaurantsKeyset {
+	keyset := RestaurantsKeyset{
+		SortBy: sortBy,
+		Id:     restaurant.Id,
+	}
+
+	switch sortBy {
+	case SortRestaurantsByRating:
+		keyset.Value = strconv.FormatFloat(float64(restaurant.AverageRating), 'g', -1, 32)
+	case SortRestaurantsByReviewCount:
+		keyset.Value = strconv.Itoa(restaurant.RatingsCount)
+	case SortRestaurantsByName:
+		keyset.Value = restaurant.Name
+	case SortRestaurantsByNewest:
+		keyset.Value = restaurant.CreatedAt.Format(time.RFC3339Nano)
+	}
+
+	return keyset
+}
+
+// ReviewsSortKey is one of the supported keys that reviews can be ordered by
+type ReviewsSortKey string
+
+const (
+	SortReviewsByTimestamp ReviewsSortKey = "timestamp"
+	SortReviewsByRating    ReviewsSortKey = "rating"
+	SortReviewsByHasAnswer ReviewsSortKey = "hasAnswer"
+)
+
+// ReviewsSortKeys are all supported review sort keys
+var ReviewsSortKeys = []ReviewsSortKey{
+	SortReviewsByTimestamp,
+	SortReviewsByRating,
+	SortReviewsByHasAnswer,
+}
+
+// ReviewsFilter holds the filters, the ordering and the pagination applied when listing the reviews of a restaurant
+type ReviewsFilter struct {
+	RestaurantId string
+	Unanswered   bool
+	Top          uint64
+	Skip         uint64
+	SortBy       ReviewsSortKey
+	SortAsc      bool
+	// After is the position of the last review of the previous page. It can be used only when the reviews are ordered by timestamp.
+	// When set, Skip is not applied.
+	After *ReviewsKeyset
+}
+
+// ReviewsKeyset is the position of a review in a listing ordered by timestamp
+type ReviewsKeyset struct {
+	Timestamp time.Time
+	Id        string
+}
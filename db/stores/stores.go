@@ -1,27 +1,106 @@
 package stores
 
 import (
+	"time"
+
 	"github.com/hrist0stoichev/ReviewsSystem/db/models"
 )
 
 type UsersStore interface {
 	Insert(user *models.User) error
 	GetByEmail(email string) (*models.User, error)
+	GetById(id string) (*models.User, error)
+	List(top, skip uint64, emailSearch string) ([]models.User, error)
 	ConfirmEmail(id string) error
+	SetEmailConfirmationToken(id, token string, createdAt, previousCreatedBy time.Time) (bool, error)
+	SetPasswordResetToken(id, token string, expiresAt, previousExpiresBy time.Time) (bool, error)
+	ResetPassword(id, token, hashedPassword string) error
+	Update(user *models.User) error
+	Delete(id string) (*models.DeletedUploads, error)
+}
+
+type RefreshTokensStore interface {
+	Insert(token *models.RefreshToken) error
+	GetByHash(hashedToken string) (*models.RefreshToken, error)
+	Rotate(oldId string, newToken *models.RefreshToken) error
+	Revoke(id string) error
+	RevokeAllForUser(userId string) error
+}
+
+type EmailOutboxStore interface {
+	Insert(email *models.OutboxEmail) error
+	ClaimDue(limit uint64, leaseUntil time.Time) ([]models.OutboxEmail, error)
+	MarkSent(id string) error
+	MarkFailed(id, lastError string, nextAttemptAt time.Time, dead bool) error
 }
 
 type RestaurantsStore interface {
 	Insert(restaurant *models.Restaurant) error
-	GetByRating(top, skip int, forOwnerId *string, minRating, maxRating float32) ([]models.Restaurant, error)
+	Update(restaurant *models.Restaurant) error
+	List(filter RestaurantsFilter) ([]models.Restaurant, error)
+	Count(filter RestaurantsFilter) (int64, error)
+	ListNearby(filter RestaurantsFilter) ([]models.NearbyRestaurant, error)
+	Facets(filter RestaurantsFilter) (*models.RestaurantFacets, error)
+	Search(query, city string, forOwnerId *string, top, skip uint64) ([]models.RestaurantSearchResult, error)
 	GetSingle(id string) (*models.Restaurant, error)
 	Exists(id string) (bool, error)
-	Delete(restId string) error
+	Delete(restId string) (int64, *models.DeletedUploads, error)
+	SoftDelete(restId string) error
+}
+
+type RestaurantImagesStore interface {
+	Insert(image *models.RestaurantImage, maxPerRestaurant int) (int, error)
+	GetById(id string) (*models.RestaurantImage, error)
+	ListForRestaurant(restId string) ([]models.RestaurantImage, error)
+	CountForRestaurant(restId string) (int, error)
+	SetCover(image *models.RestaurantImage, url string) error
+	Delete(image *models.RestaurantImage) error
+}
+
+type ReviewMediaStore interface {
+	Insert(media *models.ReviewMedia, maxPerReview int) error
+	GetById(id string) (*models.ReviewMedia, error)
+	ListForReviews(reviewIds []string) ([]models.ReviewMedia, error)
+	CountForReview(reviewId string) (int, error)
+	Delete(id string) error
+}
+
+type OpeningHoursStore interface {
+	ListForRestaurant(restId string) ([]models.OpeningHours, error)
+	Replace(restId, timeZone string, hours []models.OpeningHours) error
+	TimeZoneExists(name string) (bool, error)
+	InsertException(exception *models.ScheduleException) error
+	GetException(id string) (*models.ScheduleException, error)
+	ListExceptions(restId string) ([]models.ScheduleException, error)
+	DeleteException(id string) error
+}
+
+type TagsStore interface {
+	Insert(tag *models.Tag) error
+	Update(tag *models.Tag) error
+	GetById(id string) (*models.Tag, error)
+	GetBySlug(slug string) (*models.Tag, error)
+	List() ([]models.Tag, error)
+	ListBySlugs(slugs []string) ([]models.Tag, error)
+	ListForRestaurants(restIds []string) ([]models.RestaurantTag, error)
+	Delete(id string) error
+}
+
+type FavoritesStore interface {
+	Insert(favorite *models.Favorite) error
+	Delete(userId, restId string) error
+	ListForUser(userId string, top, skip uint64) ([]models.FavoriteRestaurant, error)
+	CountForUser(userId string) (int64, error)
+	ListFavoriteIds(userId string, restIds []string) ([]string, error)
 }
 
 type ReviewsStore interface {
 	GetById(revId string) (*models.Review, error)
 	Update(review *models.Review) error
 	Insert(review *models.Review) error
+	Delete(revId string) error
 	ExistsForUserAndRestaurant(userId, restaurantId string) (bool, error)
-	ListForRestaurant(restaurantId string, unanswered bool, top, skip uint64, orderBy string, isAsc bool) ([]models.Review, error)
+	ListForRestaurant(filter ReviewsFilter) ([]models.Review, error)
+	CountForRestaurant(filter ReviewsFilter) (int64, error)
+	Search(restaurantId, query string, top, skip uint64) ([]models.ReviewSearchResult, error)
 }
@@ -19,11 +19,14 @@ type Config struct {
 	FacebookAuth FacebookAuthConfig
 	Email        EmailConfig
 	Admin        AdminConfig
+	Pagination   PaginationConfig
+	Images       ImagesConfig
 }
 
 type TokensConfig struct {
-	ValidFor   time.Duration `env:"TOKENS_VALID_FOR"`
-	SigningKey string        `env:"TOKENS_SIGNING_KEY"`
+	ValidFor        time.Duration `env:"TOKENS_VALID_FOR"`
+	RefreshValidFor time.Duration `env:"TOKENS_REFRESH_VALID_FOR" envDefault:"720h"`
+	SigningKey      string        `env:"TOKENS_SIGNING_KEY"`
 }
 
 type FacebookAuthConfig struct {
@@ -34,13 +37,43 @@ type FacebookAuthConfig struct {
 }
 
 type EmailConfig struct {
-	SMTPHost              string `env:"EMAIL_SMTP_HOST"`
-	SMTPPort              string `env:"EMAIL_SMTP_PORT"`
-	Username              string `env:"EMAIL_SMTP_USERNAME"`
-	Password              string `env:"EMAIL_SMTP_PASSWORD"`
-	ConfirmationEndpoint  string `env:"EMAIL_CONFIRMATION_ENDPOINT"`
-	RedirectionEndpoint   string `env:"EMAIL_REDIRECTION_ENDPOINT"`
-	SkipEmailVerification bool   `env:"SKIP_EMAIL_VERIFICATION"`
+	// Transport is either smtp or maildir
+	Transport             string        `env:"EMAIL_TRANSPORT" envDefault:"smtp" validate:"oneof=smtp maildir"`
+	SMTPSecurity          string        `env:"EMAIL_SMTP_SECURITY" envDefault:"starttls" validate:"oneof=starttls tls none"`
+	MaildirPath           string        `env:"EMAIL_MAILDIR_PATH" envDefault:"maildir"`
+	SMTPHost              string        `env:"EMAIL_SMTP_HOST"`
+	SMTPPort              string        `env:"EMAIL_SMTP_PORT"`
+	Username              string        `env:"EMAIL_SMTP_USERNAME"`
+	Password              string        `env:"EMAIL_SMTP_PASSWORD"`
+	ConfirmationEndpoint  string        `env:"EMAIL_CONFIRMATION_ENDPOINT"`
+	RedirectionEndpoint   string        `env:"EMAIL_REDIRECTION_ENDPOINT"`
+	ConfirmationValidFor  time.Duration `env:"EMAIL_CONFIRMATION_VALID_FOR" envDefault:"24h"`
+	ResendInterval        time.Duration `env:"EMAIL_RESEND_INTERVAL" envDefault:"1m"`
+	PasswordResetEndpoint string        `env:"EMAIL_PASSWORD_RESET_ENDPOINT"`
+	PasswordResetValidFor time.Duration `env:"EMAIL_PASSWORD_RESET_VALID_FOR" envDefault:"1h"`
+	RestaurantEndpoint    string        `env:"EMAIL_RESTAURANT_ENDPOINT"`
+	TemplatesDir          string        `env:"EMAIL_TEMPLATES_DIR" envDefault:"templates/email"`
+	DefaultLocale         string        `env:"EMAIL_DEFAULT_LOCALE" envDefault:"en"`
+	SkipEmailVerification bool          `env:"SKIP_EMAIL_VERIFICATION"`
+	WorkerPollInterval    time.Duration `env:"EMAIL_WORKER_POLL_INTERVAL" envDefault:"5s"`
+	WorkerBatchSize       uint64        `env:"EMAIL_WORKER_BATCH_SIZE" envDefault:"10"`
+	MaxAttempts           int           `env:"EMAIL_MAX_ATTEMPTS" envDefault:"8"`
+	RetryBackoff          time.Duration `env:"EMAIL_RETRY_BACKOFF" envDefault:"30s"`
+	RetryMaxBackoff       time.Duration `env:"EMAIL_RETRY_MAX_BACKOFF" envDefault:"1h"`
+}
+
+type PaginationConfig struct {
+	// CursorsSigningKey is the HMAC key of the cursors. Anyone who knows it can forge cursors, so it must be long and secret.
+	CursorsSigningKey string `env:"PAGINATION_CURSORS_SIGNING_KEY" validate:"required,min=32"`
+}
+
+type ImagesConfig struct {
+	StoragePath      string `env:"IMAGES_STORAGE_PATH" envDefault:"images"`
+	PublicURL        string `env:"IMAGES_PUBLIC_URL" envDefault:"/api/v1/images"`
+	MaxSize          int64  `env:"IMAGES_MAX_SIZE" envDefault:"10485760"`
+	MaxPerRestaurant int    `env:"IMAGES_MAX_PER_RESTAURANT" envDefault:"20"`
+	MaxPerReview     int    `env:"IMAGES_MAX_PER_REVIEW" envDefault:"5"`
+	ThumbnailSize    int    `env:"IMAGES_THUMBNAIL_SIZE" envDefault:"320"`
 }
 
 type AdminConfig struct {
@@ -1,5 +1,11 @@
 import { authenticationService } from "./auth";
 
+// problemMessage returns a readable message from an RFC 7807 problem returned by the API
+function problemMessage(problem) {
+  const fields = (problem.errors || []).map(e => `${e.field} ${e.message}`);
+  return [problem.detail, ...fields].join("\n");
+}
+
 export function handleResponse(response) {
   return response.text().then(text => {
     const contentType = response.headers.get("Content-Type");
@@ -12,9 +18,13 @@ export function handleResponse(response) {
         location.replace("/");
       }
 
+      if (contentType === "application/problem+json") {
+        return Promise.reject(problemMessage(JSON.parse(text)));
+      }
+
       return Promise.reject(data);
     }
 
     return data;
   });
-}
\ No newline at end of file
+}
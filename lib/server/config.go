@@ -6,12 +6,13 @@ import (
 
 // Config contains all properties that can be set-up for an API server
 type Config struct {
-	Addr           string        `env:"SERVER_ADDR" envDefault:":8001"`
-	ReadTimeout    time.Duration `env:"SERVER_READ_TIMEOUT" envDefault:"3s"`
-	WriteTimeout   time.Duration `env:"SERVER_WRITE_TIMEOUT" envDefault:"3s"`
-	IdleTimeout    time.Duration `env:"SERVER_IDLE_TIMEOUT" envDefault:"3s"`
-	TLSCertificate string        `env:"SERVER_TLS_CERTIFICATE"`
-	TLSKey         string        `env:"SERVER_TLS_KEY"`
+	Addr            string        `env:"SERVER_ADDR" envDefault:":8001"`
+	ReadTimeout     time.Duration `env:"SERVER_READ_TIMEOUT" envDefault:"3s"`
+	WriteTimeout    time.Duration `env:"SERVER_WRITE_TIMEOUT" envDefault:"3s"`
+	IdleTimeout     time.Duration `env:"SERVER_IDLE_TIMEOUT" envDefault:"3s"`
+	ShutdownTimeout time.Duration `env:"SERVER_SHUTDOWN_TIMEOUT" envDefault:"30s"`
+	TLSCertificate  string        `env:"SERVER_TLS_CERTIFICATE"`
+	TLSKey          string        `env:"SERVER_TLS_KEY"`
 }
 
 // IsTLSEnabled indicates whether cert and key a provided in the configuration
@@ -12,13 +12,15 @@ import (
 
 type Server interface {
 	ListenAndServe()
+	OnShutdown(hook func(ctx context.Context) error)
 	Shutdown(ctx context.Context)
 }
 
 type apiServer struct {
-	config *Config
-	server *http.Server
-	logger log.Logger
+	config        *Config
+	server        *http.Server
+	shutdownHooks []func(ctx context.Context) error
+	logger        log.Logger
 }
 
 func New(config *Config, handler http.Handler, logger log.Logger) (Server, error) {
@@ -79,12 +81,24 @@ func (s *apiServer) listenAndServe() error {
 	return s.server.ListenAndServe()
 }
 
-// Shutdown shuts down the server gracefully
+// OnShutdown registers a hook that is called during Shutdown, after the server has stopped handling requests.
+// It can be used to drain background workers that are fed by the request handlers. Hooks are called in the order of registration.
+func (s *apiServer) OnShutdown(hook func(ctx context.Context) error) {
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
+// Shutdown shuts down the server gracefully and then calls the registered shutdown hooks
 func (s *apiServer) Shutdown(ctx context.Context) {
 	s.logger.Infoln("API server shutdown started")
 	if err := s.server.Shutdown(ctx); err != nil {
 		s.logger.WithError(err).Errorln("Error while shutting down API server")
 	}
 
+	for _, hook := range s.shutdownHooks {
+		if err := hook(ctx); err != nil {
+			s.logger.WithError(err).Errorln("Error while running shutdown hook")
+		}
+	}
+
 	s.logger.Infoln("API server shutdown completed")
 }
@@ -3,11 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
-	"math/rand"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/go-playground/validator/v10"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -40,6 +38,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	v.RegisterTagNameFunc(controllers.JsonFieldName)
+	if err = controllers.RegisterValidations(v); err != nil {
+		fmt.Printf("could not register validations: %v", err)
+		os.Exit(1)
+	}
+
 	logger, err := log.NewLogrus(&cfg.Logging)
 	if err != nil {
 		fmt.Printf("could not create new logger: %v", err)
@@ -55,15 +59,45 @@ func main() {
 	usersStore := dbr.NewUsersStore(database.Conn().NewSession(nil))
 	restaurantsStore := dbr.NewRestaurantsStore(database.Conn().NewSession(nil))
 	reviewsStore := dbr.NewReviewsStore(database.Conn().NewSession(nil))
+	refreshTokensStore := dbr.NewRefreshTokensStore(database.Conn().NewSession(nil))
+	emailOutboxStore := dbr.NewEmailOutboxStore(database.Conn().NewSession(nil))
+	restaurantImagesStore := dbr.NewRestaurantImagesStore(database.Conn().NewSession(nil))
+	reviewMediaStore := dbr.NewReviewMediaStore(database.Conn().NewSession(nil))
+	openingHoursStore := dbr.NewOpeningHoursStore(database.Conn().NewSession(nil))
+	tagsStore := dbr.NewTagsStore(database.Conn().NewSession(nil))
+	favoritesStore := dbr.NewFavoritesStore(database.Conn().NewSession(nil))
 
-	dbManager := db.NewManager(usersStore, restaurantsStore, reviewsStore)
+	dbManager := db.NewManager(usersStore, restaurantsStore, reviewsStore, refreshTokensStore, emailOutboxStore, restaurantImagesStore, reviewMediaStore, openingHoursStore, tagsStore, favoritesStore)
 
 	usersService := services.NewUserService(dbManager)
-	tokensService := services.NewTokensService(cfg.Tokens.ValidFor, []byte(cfg.Tokens.SigningKey))
+	tokensService := services.NewTokensService(cfg.Tokens.ValidFor, cfg.Tokens.RefreshValidFor, []byte(cfg.Tokens.SigningKey))
+	refreshTokensService := services.NewRefreshTokens(dbManager)
+	cursorsService := services.NewCursorsService([]byte(cfg.Pagination.CursorsSigningKey))
 	encryptionService := services.NewEncryptionService(services.DefaultEncryptionCost)
-	emailService := services.NewEmailsService(cfg.Email.SMTPHost, cfg.Email.SMTPPort, cfg.Email.Username, cfg.Email.Username, cfg.Email.Password, "Confirm you registration", "Click here to confirm your registration", cfg.Email.ConfirmationEndpoint, "token", "email", 30, rand.New(rand.NewSource(time.Now().UnixNano())))
+	emailTemplates, err := services.LoadEmailTemplates(cfg.Email.TemplatesDir, cfg.Email.DefaultLocale)
+	if err != nil {
+		logger.WithError(err).Fatalln("could not load email templates")
+	}
+
+	emailService := services.NewEmailsService(dbManager, emailTemplates, cfg.Email.Username, cfg.Email.ConfirmationEndpoint, cfg.Email.PasswordResetEndpoint, cfg.Email.RestaurantEndpoint, "token", "email", 30)
+	emailSender, err := newEmailSender(&cfg.Email, logger)
+	if err != nil {
+		logger.WithError(err).Fatalln("could not create email sender")
+	}
+
+	emailsWorker := services.NewEmailsWorker(dbManager, emailSender, cfg.Email.WorkerPollInterval, cfg.Email.WorkerBatchSize, cfg.Email.MaxAttempts, cfg.Email.RetryBackoff, cfg.Email.RetryMaxBackoff, logger.WithField("module", "emailsWorker"))
 	restaurantService := services.NewRestaurants(dbManager)
 	reviewsService := services.NewReviews(dbManager)
+	imagesStorage, err := services.NewFilesystemStorage(cfg.Images.StoragePath)
+	if err != nil {
+		logger.WithError(err).Fatalln("could not create images storage")
+	}
+
+	restaurantImagesService := services.NewRestaurantImages(dbManager, imagesStorage, cfg.Images.PublicURL, cfg.Images.MaxPerRestaurant, cfg.Images.ThumbnailSize, logger.WithField("module", "restaurantImagesService"))
+	openingHoursService := services.NewOpeningHours(dbManager)
+	tagsService := services.NewTags(dbManager)
+	favoritesService := services.NewFavorites(dbManager)
+	reviewMediaService := services.NewReviewMedia(dbManager, imagesStorage, cfg.Images.PublicURL, cfg.Images.MaxPerReview, cfg.Images.ThumbnailSize, logger.WithField("module", "reviewMediaService"))
 	facebookAuthService := services.NewOauth2(oauth2.Config{
 		ClientID:     cfg.FacebookAuth.ClientId,
 		ClientSecret: cfg.FacebookAuth.ClientSecret,
@@ -76,24 +110,41 @@ func main() {
 		logger.WithError(err).Fatalln("could not generate default admin user")
 	}
 
-	usersController := controllers.NewUsers(usersService, encryptionService, tokensService, emailService, facebookAuthService, cfg.Email.RedirectionEndpoint, cfg.Email.SkipEmailVerification, logger.WithField("module", "usersController"), v)
-	restaurantsController := controllers.NewRestaurant(restaurantService, logger.WithField("module", "restaurantsController"), v)
-	reviewsController := controllers.NewReviews(reviewsService, restaurantService, logger.WithField("module", "reviewsController"), v)
-
-	apiHandler := api.NewRouter(tokensService, usersController, restaurantsController, reviewsController, logger)
+	usersController := controllers.NewUsers(usersService, encryptionService, tokensService, refreshTokensService, emailService, facebookAuthService, cfg.Email.RedirectionEndpoint, cfg.Email.SkipEmailVerification, cfg.Email.PasswordResetValidFor, cfg.Email.ConfirmationValidFor, cfg.Email.ResendInterval, logger.WithField("module", "usersController"), v)
+	restaurantsController := controllers.NewRestaurant(restaurantService, restaurantImagesService, reviewMediaService, tagsService, favoritesService, cursorsService, logger.WithField("module", "restaurantsController"), v)
+	reviewsController := controllers.NewReviews(reviewsService, reviewMediaService, restaurantService, usersService, emailService, cursorsService, logger.WithField("module", "reviewsController"), v)
+	restaurantImagesController := controllers.NewRestaurantImages(restaurantImagesService, restaurantService, cfg.Images.MaxSize, logger.WithField("module", "restaurantImagesController"), v)
+	reviewMediaController := controllers.NewReviewMedia(reviewMediaService, reviewsService, cfg.Images.MaxSize, logger.WithField("module", "reviewMediaController"), v)
+	openingHoursController := controllers.NewOpeningHours(openingHoursService, restaurantService, logger.WithField("module", "openingHoursController"), v)
+	tagsController := controllers.NewTags(tagsService, logger.WithField("module", "tagsController"), v)
+	favoritesController := controllers.NewFavorites(favoritesService, tagsService, logger.WithField("module", "favoritesController"), v)
+	adminController := controllers.NewAdmin(usersService, restaurantImagesService, reviewMediaService, logger.WithField("module", "adminController"), v)
+
+	apiHandler, err := api.NewRouter(tokensService, usersService, usersController, restaurantsController, reviewsController, restaurantImagesController, reviewMediaController, openingHoursController, tagsController, favoritesController, adminController, logger)
+	if err != nil {
+		logger.WithError(err).Fatalln("could not create router")
+	}
 
 	apiServer, err := server.New(&cfg.Server, apiHandler, logger)
 	if err != nil {
 		logger.WithError(err).Fatalln("could not create server")
 	}
 
+	// The emails worker is stopped after the server, so that the emails queued by the last requests are sent as well
+	apiServer.OnShutdown(emailsWorker.Shutdown)
+
+	go emailsWorker.Run()
 	go apiServer.ListenAndServe()
 
 	sigint := make(chan os.Signal, 1)
 	signal.Notify(sigint, syscall.SIGINT, syscall.SIGTERM)
 
 	<-sigint
-	apiServer.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	apiServer.Shutdown(ctx)
 }
 
 func connectToDatabase(cfg *dbrdb.Config, logger log.Logger) (dbrdb.Database, error) {
@@ -113,6 +164,19 @@ func connectToDatabase(cfg *dbrdb.Config, logger log.Logger) (dbrdb.Database, er
 	return db, nil
 }
 
+// newEmailSender returns the email transport that is selected in the config
+func newEmailSender(cfg *etc.EmailConfig, logger log.Logger) (services.EmailSender, error) {
+	switch cfg.Transport {
+	case "smtp":
+		return services.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.Username, cfg.Username, cfg.Password, services.SMTPSecurity(cfg.SMTPSecurity))
+	case "maildir":
+		logger.WithField("path", cfg.MaildirPath).Warnln("Emails are delivered to a local maildir instead of being sent")
+		return services.NewMaildirSender(cfg.MaildirPath)
+	default:
+		return nil, errors.Errorf("unsupported email transport %q", cfg.Transport)
+	}
+}
+
 func addAdminIfDbEmpty(db dbrdb.Database, usersService services.UsersService, encryptionService services.EncryptionService, logger log.Logger, email, password string) error {
 	numUsers := 1
 	err := db.Conn().NewSession(nil).Select("count(*)").From("users").LoadOne(&numUsers)
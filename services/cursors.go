@@ -0,0 +1,77 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CursorsService encodes the position of the last item of a page into an opaque cursor that is returned to the clients,
+// so that they can request the next page. Cursors are signed, so that clients cannot craft positions on their own.
+type CursorsService interface {
+	Encode(position interface{}) (string, error)
+	Decode(cursor string, position interface{}) error
+}
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+type cursorsService struct {
+	signingKey []byte
+}
+
+func NewCursorsService(signingKey []byte) CursorsService {
+	return &cursorsService{
+		signingKey: signingKey,
+	}
+}
+
+// Encode serializes the position to JSON and returns it together with its HMAC-SHA256 signature, both base64url encoded
+func (cs *cursorsService) Encode(position interface{}) (string, error) {
+	payload, err := json.Marshal(position)
+	if err != nil {
+		return "", errors.Wrap(err, "could not marshal cursor position")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(cs.sign(payload)), nil
+}
+
+// Decode verifies the signature of the cursor and deserializes its position. ErrInvalidCursor is returned
+// for cursors that are malformed or not signed by this service.
+func (cs *cursorsService) Decode(cursor string, position interface{}) error {
+	parts := strings.Split(cursor, ".")
+	if len(parts) != 2 {
+		return ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	if !hmac.Equal(signature, cs.sign(payload)) {
+		return ErrInvalidCursor
+	}
+
+	if err = json.Unmarshal(payload, position); err != nil {
+		return ErrInvalidCursor
+	}
+
+	return nil
+}
+
+func (cs *cursorsService) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, cs.signingKey)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
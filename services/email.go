@@ -1,65 +1,151 @@
 package services
 
 import (
+	"crypto/rand"
 	"fmt"
-	"math/rand"
-	"net/smtp"
+	"math/big"
+	"net/url"
+	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
 )
 
+// EmailsService composes the emails sent by the system from templates in the language of the recipient. The emails are
+// not sent right away, but are added to a durable outbox from which they are sent by the EmailsWorker.
 type EmailsService interface {
-	SendConfirmationEmail(to, token string) error
-	GenerateRandomEmailToken() string
+	SendConfirmationEmail(to, locale, token string) error
+	SendPasswordResetEmail(to, locale, token string) error
+	SendReviewNotificationEmail(to, locale string, restaurant *models.Restaurant, review *models.Review) error
+	PreferredLocale(acceptLanguage string) string
+	GenerateRandomEmailToken() (string, error)
+}
+
+// EmailSender delivers a message that is already formatted according to RFC 5322
+type EmailSender interface {
+	Send(to string, msg []byte) error
 }
 
 const (
-	// The format of the message that will be sent according to RFC 822
-	msgFormat = "From: %s\nTo: %s\nSubject: %s\n\n%s: %s?%s=%s&%s=%s"
 	// The valid charset that can be used unencoded within URLs
 	validCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789~-_.!*()',"
 )
 
 type emailsService struct {
-	addr                    string
+	db                      db.Manager
+	templates               EmailTemplates
 	from                    string
-	subject                 string
-	msg                     string
 	confirmationURL         string
+	passwordResetURL        string
+	restaurantURL           string
 	tokenQueryParameterName string
 	emailQueryParameterName string
 	tokenLength             int
-	randGenerator           *rand.Rand
-	auth                    smtp.Auth
 }
 
-func NewEmailsService(host, port, from, username, password, subject, genericMessage, confirmationURL, tokenQueryParameterName, emailQueryParameterName string, tokenLength int, randGenerator *rand.Rand) EmailsService {
+// The data that is available in the templates of the emails containing a token
+type tokenEmailData struct {
+	Email string
+	Link  string
+}
+
+// The data that is available in the templates of the review notification emails
+type reviewNotificationEmailData struct {
+	Email          string
+	Link           string
+	RestaurantName string
+	Rating         uint8
+	Comment        string
+}
+
+func NewEmailsService(db db.Manager, templates EmailTemplates, from, confirmationURL, passwordResetURL, restaurantURL, tokenQueryParameterName, emailQueryParameterName string, tokenLength int) EmailsService {
 	return &emailsService{
-		addr:                    fmt.Sprintf("%s:%s", host, port),
+		db:                      db,
+		templates:               templates,
 		from:                    from,
-		subject:                 subject,
-		msg:                     genericMessage,
 		confirmationURL:         confirmationURL,
+		passwordResetURL:        passwordResetURL,
+		restaurantURL:           restaurantURL,
 		tokenQueryParameterName: tokenQueryParameterName,
 		emailQueryParameterName: emailQueryParameterName,
 		tokenLength:             tokenLength,
-		randGenerator:           randGenerator,
-		auth:                    smtp.PlainAuth("", username, password, host),
 	}
 }
 
-func (es *emailsService) SendConfirmationEmail(to, token string) error {
-	msg := fmt.Sprintf(msgFormat, es.from, to, es.subject, es.msg, es.confirmationURL, es.emailQueryParameterName, to, es.tokenQueryParameterName, token)
-	err := smtp.SendMail(es.addr, es.auth, es.from, []string{to}, []byte(msg))
+func (es *emailsService) SendConfirmationEmail(to, locale, token string) error {
+	return es.enqueue(to, locale, ConfirmationTemplate, tokenEmailData{
+		Email: to,
+		Link:  es.tokenLink(es.confirmationURL, to, token),
+	})
+}
+
+func (es *emailsService) SendPasswordResetEmail(to, locale, token string) error {
+	return es.enqueue(to, locale, PasswordResetTemplate, tokenEmailData{
+		Email: to,
+		Link:  es.tokenLink(es.passwordResetURL, to, token),
+	})
+}
+
+// SendReviewNotificationEmail notifies the owner of a restaurant that a new review has been written for it
+func (es *emailsService) SendReviewNotificationEmail(to, locale string, restaurant *models.Restaurant, review *models.Review) error {
+	return es.enqueue(to, locale, ReviewNotificationTemplate, reviewNotificationEmailData{
+		Email:          to,
+		Link:           fmt.Sprintf("%s/%s", es.restaurantURL, restaurant.Id),
+		RestaurantName: restaurant.Name,
+		Rating:         review.Rating,
+		Comment:        review.Comment,
+	})
+}
+
+// PreferredLocale returns the locale of the emails that should be sent to a user based on an Accept-Language header
+func (es *emailsService) PreferredLocale(acceptLanguage string) string {
+	return es.templates.MatchLocale(acceptLanguage)
+}
+
+func (es *emailsService) tokenLink(baseURL, email, token string) string {
+	query := url.Values{}
+	query.Set(es.emailQueryParameterName, email)
+	query.Set(es.tokenQueryParameterName, token)
+
+	return fmt.Sprintf("%s?%s", baseURL, query.Encode())
+}
+
+func (es *emailsService) enqueue(to, locale, templateName string, data interface{}) error {
+	email, err := es.templates.Render(templateName, locale, data)
+	if err != nil {
+		return errors.Wrap(err, "could not render email")
+	}
+
+	msg, err := buildMultipartMessage(es.from, to, email, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "could not build email message")
+	}
 
-	return errors.Wrap(err, "could not send mail")
+	err = es.db.EmailOutbox().Insert(&models.OutboxEmail{
+		Recipient: to,
+		Subject:   email.Subject,
+		Message:   string(msg),
+	})
+
+	return errors.Wrap(err, "could not add mail to outbox")
 }
 
-func (es *emailsService) GenerateRandomEmailToken() string {
+// GenerateRandomEmailToken returns a token for confirming an email or resetting a password. The tokens grant access to accounts,
+// so they are generated with a cryptographically secure random generator.
+func (es *emailsService) GenerateRandomEmailToken() (string, error) {
+	charsetLength := big.NewInt(int64(len(validCharset)))
+
 	b := make([]byte, es.tokenLength)
 	for i := range b {
-		b[i] = validCharset[es.randGenerator.Intn(len(validCharset))]
+		n, err := rand.Int(rand.Reader, charsetLength)
+		if err != nil {
+			return "", errors.Wrap(err, "could not generate random number")
+		}
+
+		b[i] = validCharset[n.Int64()]
 	}
 
-	return string(b)
+	return string(b), nil
 }
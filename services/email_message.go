@@ -0,0 +1,80 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// buildMultipartMessage returns an RFC 5322 message with a multipart/alternative body that contains both the plain text
+// and the HTML version of the email, so that every mail client can display one of them.
+func buildMultipartMessage(from, to string, email *RenderedEmail, date time.Time) ([]byte, error) {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	if err := writeQuotedPrintablePart(writer, "text/plain; charset=UTF-8", email.Text); err != nil {
+		return nil, errors.Wrap(err, "could not write text part")
+	}
+
+	if err := writeQuotedPrintablePart(writer, "text/html; charset=UTF-8", email.HTML); err != nil {
+		return nil, errors.Wrap(err, "could not write html part")
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, errors.Wrap(err, "could not close multipart writer")
+	}
+
+	msg := new(bytes.Buffer)
+	headers := [][2]string{
+		{"From", from},
+		{"To", to},
+		{"Subject", mime.QEncoding.Encode("UTF-8", email.Subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", uuid.NewV4().String(), messageIDDomain(from))},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", writer.Boundary())},
+	}
+
+	for _, header := range headers {
+		msg.WriteString(header[0] + ": " + header[1] + "\r\n")
+	}
+
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+func writeQuotedPrintablePart(writer *multipart.Writer, contentType, content string) error {
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not create part")
+	}
+
+	qpWriter := quotedprintable.NewWriter(part)
+	if _, err = qpWriter.Write([]byte(content)); err != nil {
+		return errors.Wrap(err, "could not write part content")
+	}
+
+	return errors.Wrap(qpWriter.Close(), "could not close quoted-printable writer")
+}
+
+// messageIDDomain returns the domain of the sender address, which is used to make the Message-ID globally unique
+func messageIDDomain(from string) string {
+	if i := strings.LastIndex(from, "@"); i >= 0 && i < len(from)-1 {
+		return strings.Trim(from[i+1:], ">")
+	}
+
+	return "localhost"
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// The names of the templates that have to be present for the default locale
+	ConfirmationTemplate       = "confirmation"
+	PasswordResetTemplate      = "password_reset"
+	ReviewNotificationTemplate = "review_notification"
+
+	subjectTemplateExtension = ".subject.txt"
+	textTemplateExtension    = ".txt"
+	htmlTemplateExtension    = ".html"
+)
+
+// EmailTemplates renders the subject, plain text body, and HTML body of an email in the language of the recipient
+type EmailTemplates interface {
+	Render(name, locale string, data interface{}) (*RenderedEmail, error)
+	MatchLocale(acceptLanguage string) string
+}
+
+type RenderedEmail struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type emailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+type emailTemplates struct {
+	defaultLocale string
+	// templates holds the templates by locale and then by name
+	templates map[string]map[string]*emailTemplate
+}
+
+// LoadEmailTemplates loads the templates from a directory that contains a subdirectory for every locale (e.g. en, bg, en-us).
+// Every email consists of three files in the locale directory: <name>.subject.txt, <name>.txt and <name>.html.
+// All templates have to be present for the default locale, while other locales fall back to it for the missing ones.
+func LoadEmailTemplates(dir, defaultLocale string) (EmailTemplates, error) {
+	localeDirs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read email templates directory")
+	}
+
+	et := &emailTemplates{
+		defaultLocale: strings.ToLower(defaultLocale),
+		templates:     map[string]map[string]*emailTemplate{},
+	}
+
+	for _, localeDir := range localeDirs {
+		if !localeDir.IsDir() {
+			continue
+		}
+
+		locale := strings.ToLower(localeDir.Name())
+
+		templates, err := loadLocaleTemplates(filepath.Join(dir, localeDir.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not load email templates for locale %s", locale)
+		}
+
+		et.templates[locale] = templates
+	}
+
+	for _, name := range []string{ConfirmationTemplate, PasswordResetTemplate, ReviewNotificationTemplate} {
+		if _, ok := et.templates[et.defaultLocale][name]; !ok {
+			return nil, errors.Errorf("email template %s is missing for the default locale %s", name, et.defaultLocale)
+		}
+	}
+
+	return et, nil
+}
+
+func loadLocaleTemplates(dir string) (map[string]*emailTemplate, error) {
+	subjectFiles, err := filepath.Glob(filepath.Join(dir, "*"+subjectTemplateExtension))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list subject templates")
+	}
+
+	templates := make(map[string]*emailTemplate, len(subjectFiles))
+
+	for _, subjectFile := range subjectFiles {
+		name := strings.TrimSuffix(filepath.Base(subjectFile), subjectTemplateExtension)
+		basePath := filepath.Join(dir, name)
+
+		subject, err := texttemplate.ParseFiles(subjectFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse subject template of %s", name)
+		}
+
+		text, err := texttemplate.ParseFiles(basePath + textTemplateExtension)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse text template of %s", name)
+		}
+
+		html, err := htmltemplate.ParseFiles(basePath + htmlTemplateExtension)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse html template of %s", name)
+		}
+
+		templates[name] = &emailTemplate{
+			subject: subject,
+			text:    text,
+			html:    html,
+		}
+	}
+
+	return templates, nil
+}
+
+// Render executes the templates with the given name for the locale. If the locale or the template for it doesn't exist,
+// the template for the base language (e.g. en for en-us) or for the default locale is used.
+func (et *emailTemplates) Render(name, locale string, data interface{}) (*RenderedEmail, error) {
+	tmpl := et.find(name, strings.ToLower(locale))
+	if tmpl == nil {
+		return nil, errors.Errorf("email template %s not found", name)
+	}
+
+	subject := new(bytes.Buffer)
+	if err := tmpl.subject.Execute(subject, data); err != nil {
+		return nil, errors.Wrap(err, "could not execute subject template")
+	}
+
+	text := new(bytes.Buffer)
+	if err := tmpl.text.Execute(text, data); err != nil {
+		return nil, errors.Wrap(err, "could not execute text template")
+	}
+
+	html := new(bytes.Buffer)
+	if err := tmpl.html.Execute(html, data); err != nil {
+		return nil, errors.Wrap(err, "could not execute html template")
+	}
+
+	return &RenderedEmail{
+		// The subject is a single header line, so new lines (e.g. at the end of the file) are not allowed
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+func (et *emailTemplates) find(name, locale string) *emailTemplate {
+	for _, l := range []string{locale, baseLanguage(locale), et.defaultLocale} {
+		if tmpl, ok := et.templates[l][name]; ok {
+			return tmpl
+		}
+	}
+
+	return nil
+}
+
+// MatchLocale returns the available locale that is preferred the most in an Accept-Language header value
+// (e.g. "bg-BG,bg;q=0.9,en;q=0.8"). The default locale is returned if none of the languages is available.
+func (et *emailTemplates) MatchLocale(acceptLanguage string) string {
+	for _, language := range parseAcceptLanguage(acceptLanguage) {
+		if _, ok := et.templates[language]; ok {
+			return language
+		}
+
+		if base := baseLanguage(language); base != language {
+			if _, ok := et.templates[base]; ok {
+				return base
+			}
+		}
+	}
+
+	return et.defaultLocale
+}
+
+type weightedLanguage struct {
+	language string
+	weight   float64
+}
+
+// parseAcceptLanguage returns the lowercase languages from an Accept-Language header ordered by their quality value.
+// Languages with a quality value of 0 and the wildcard are skipped.
+func parseAcceptLanguage(acceptLanguage string) []string {
+	weighted := make([]weightedLanguage, 0)
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		params := strings.Split(strings.TrimSpace(part), ";")
+
+		language := strings.ToLower(strings.TrimSpace(params[0]))
+		if language == "" || language == "*" {
+			continue
+		}
+
+		weight := 1.0
+
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err == nil {
+					weight = q
+				}
+			}
+		}
+
+		if weight > 0 {
+			weighted = append(weighted, weightedLanguage{language: language, weight: weight})
+		}
+	}
+
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return weighted[i].weight > weighted[j].weight
+	})
+
+	languages := make([]string, len(weighted))
+	for i, wl := range weighted {
+		languages[i] = wl.language
+	}
+
+	return languages
+}
+
+// baseLanguage returns the primary language subtag of a language tag, e.g. en for en-us
+func baseLanguage(locale string) string {
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		return locale[:i]
+	}
+
+	return locale
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/db/stores"
+	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+)
+
+// memoryOutbox is an EmailOutboxStore that keeps the emails in memory
+type memoryOutbox struct {
+	mu     sync.Mutex
+	emails []models.OutboxEmail
+	sent   map[string]bool
+}
+
+func (mo *memoryOutbox) Insert(email *models.OutboxEmail) error {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+
+	email.Id = strconv.Itoa(len(mo.emails))
+	mo.emails = append(mo.emails, *email)
+	return nil
+}
+
+func (mo *memoryOutbox) ClaimDue(limit uint64, _ time.Time) ([]models.OutboxEmail, error) {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+
+	due := make([]models.OutboxEmail, 0)
+	for _, email := range mo.emails {
+		if !mo.sent[email.Id] && uint64(len(due)) < limit {
+			due = append(due, email)
+		}
+	}
+
+	return due, nil
+}
+
+func (mo *memoryOutbox) MarkSent(id string) error {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+
+	mo.sent[id] = true
+	return nil
+}
+
+func (mo *memoryOutbox) MarkFailed(id, lastError string, nextAttemptAt time.Time, dead bool) error {
+	return nil
+}
+
+// outboxManager is a db.Manager that supports only the email outbox
+type outboxManager struct {
+	db.Manager
+	outbox *memoryOutbox
+}
+
+func (om *outboxManager) EmailOutbox() stores.EmailOutboxStore {
+	return om.outbox
+}
+
+// TestConfirmationEmail sends a confirmation email through the outbox and the worker and checks what reaches the sender
+func TestConfirmationEmail(t *testing.T) {
+	logger, err := log.NewLogrus(&log.Config{Level: "error", Format: "text", Output: "stdout"})
+	if err != nil {
+		t.Fatalf("could not create logger: %v", err)
+	}
+
+	templates, err := LoadEmailTemplates("../templates/email", "en")
+	if err != nil {
+		t.Fatalf("LoadEmailTemplates() error = %v", err)
+	}
+
+	manager := &outboxManager{outbox: &memoryOutbox{sent: make(map[string]bool)}}
+	sender := NewMemorySender()
+	emailsService := NewEmailsService(manager, templates, "reviews@example.com", "https://example.com/confirm", "https://example.com/reset", "https://example.com/restaurants", "token", "email", 30)
+	worker := NewEmailsWorker(manager, sender, time.Minute, 10, 3, time.Second, time.Minute, logger).(*emailsWorker)
+
+	token, err := emailsService.GenerateRandomEmailToken()
+	if err != nil {
+		t.Fatalf("GenerateRandomEmailToken() error = %v", err)
+	}
+
+	if err = emailsService.SendConfirmationEmail("user@example.com", "en", token); err != nil {
+		t.Fatalf("SendConfirmationEmail() error = %v", err)
+	}
+
+	worker.sendDue()
+
+	emails := sender.Emails()
+	if len(emails) != 1 {
+		t.Fatalf("got %d sent emails, want 1", len(emails))
+	}
+
+	if emails[0].To != "user@example.com" {
+		t.Errorf("recipient = %q, want %q", emails[0].To, "user@example.com")
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(emails[0].Message)))
+	if err != nil {
+		t.Fatalf("could not parse message: %v", err)
+	}
+
+	if to := msg.Header.Get("To"); to != "user@example.com" {
+		t.Errorf("To = %q, want %q", to, "user@example.com")
+	}
+
+	if _, err = msg.Header.Date(); err != nil {
+		t.Errorf("invalid Date header: %v", err)
+	}
+
+	if id := msg.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q, want <...@example.com>", id)
+	}
+
+	if version := msg.Header.Get("MIME-Version"); version != "1.0" {
+		t.Errorf("MIME-Version = %q, want 1.0", version)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []string{"text/plain", "text/html"} {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("could not read %s part: %v", want, err)
+		}
+
+		if contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); contentType != want {
+			t.Errorf("part Content-Type = %q, want %q", contentType, want)
+		}
+
+		// The reader decodes the quoted-printable parts
+		body, err := ioutil.ReadAll(part)
+		if err != nil {
+			t.Fatalf("could not read %s part: %v", want, err)
+		}
+
+		if !strings.Contains(string(body), "token="+url.QueryEscape(token)) {
+			t.Errorf("%s part does not contain the confirmation token", want)
+		}
+	}
+
+	if _, err = parts.NextPart(); err == nil {
+		t.Error("message has more than two parts")
+	}
+}
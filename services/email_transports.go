@@ -0,0 +1,245 @@
+package services
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// SMTPSecurity defines how the connection to the SMTP server is secured
+type SMTPSecurity string
+
+const (
+	// SMTPStartTLS connects in plain text and upgrades the connection with the STARTTLS command, which is mandatory
+	SMTPStartTLS SMTPSecurity = "starttls"
+	// SMTPImplicitTLS connects using TLS from the start (usually on port 465)
+	SMTPImplicitTLS SMTPSecurity = "tls"
+	// SMTPNoTLS doesn't encrypt the connection. It should be used only with local mail servers.
+	SMTPNoTLS SMTPSecurity = "none"
+)
+
+const smtpDialTimeout = 30 * time.Second
+
+type smtpSender struct {
+	host     string
+	addr     string
+	from     string
+	security SMTPSecurity
+	auth     smtp.Auth
+}
+
+// NewSMTPSender returns an EmailSender that sends emails through an SMTP server. PLAIN auth is used if a username is provided.
+func NewSMTPSender(host, port, from, username, password string, security SMTPSecurity) (EmailSender, error) {
+	if security != SMTPStartTLS && security != SMTPImplicitTLS && security != SMTPNoTLS {
+		return nil, errors.Errorf("unsupported smtp security %q", security)
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &smtpSender{
+		host:     host,
+		addr:     net.JoinHostPort(host, port),
+		from:     from,
+		security: security,
+		auth:     auth,
+	}, nil
+}
+
+func (ss *smtpSender) Send(to string, msg []byte) error {
+	client, err := ss.dial()
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	if ss.auth != nil {
+		if err = client.Auth(ss.auth); err != nil {
+			return errors.Wrap(err, "could not authenticate")
+		}
+	}
+
+	if err = client.Mail(ss.from); err != nil {
+		return errors.Wrap(err, "could not set sender")
+	}
+
+	if err = client.Rcpt(to); err != nil {
+		return errors.Wrap(err, "could not set recipient")
+	}
+
+	data, err := client.Data()
+	if err != nil {
+		return errors.Wrap(err, "could not start data command")
+	}
+
+	if _, err = data.Write(msg); err != nil {
+		return errors.Wrap(err, "could not write message")
+	}
+
+	if err = data.Close(); err != nil {
+		return errors.Wrap(err, "could not send message")
+	}
+
+	return errors.Wrap(client.Quit(), "could not quit smtp session")
+}
+
+// dial connects to the SMTP server and secures the connection according to the configured security
+func (ss *smtpSender) dial() (*smtp.Client, error) {
+	tlsConfig := &tls.Config{
+		ServerName: ss.host,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+
+	if ss.security == SMTPImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", ss.addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", ss.addr)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to smtp server")
+	}
+
+	client, err := smtp.NewClient(conn, ss.host)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "could not create smtp client")
+	}
+
+	if ss.security == SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+
+		if err = client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, errors.Wrap(err, "could not start tls")
+		}
+	}
+
+	return client, nil
+}
+
+type maildirSender struct {
+	dir      string
+	hostname string
+}
+
+// NewMaildirSender returns an EmailSender that delivers the emails to a Maildir instead of sending them, which is useful for
+// local development. Every email is stored as a separate .eml file in the new subdirectory and can be opened by most mail clients.
+func NewMaildirSender(dir string) (EmailSender, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0750); err != nil {
+			return nil, errors.Wrapf(err, "could not create maildir subdirectory %s", sub)
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	return &maildirSender{
+		dir:      dir,
+		hostname: hostname,
+	}, nil
+}
+
+// Send writes the email in the tmp subdirectory and then moves it to the new subdirectory,
+// so that readers of the Maildir never see partially written emails.
+func (ms *maildirSender) Send(to string, msg []byte) error {
+	fileName := fmt.Sprintf("%d.%s.%s.eml", time.Now().UnixNano(), uuid.NewV4().String(), ms.hostname)
+	tmpPath := filepath.Join(ms.dir, "tmp", fileName)
+
+	if err := ioutil.WriteFile(tmpPath, msg, 0640); err != nil {
+		return errors.Wrap(err, "could not write email file")
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(ms.dir, "new", fileName)); err != nil {
+		return errors.Wrap(err, "could not move email file to new")
+	}
+
+	return nil
+}
+
+// SentEmail is an email captured by the MemorySender
+type SentEmail struct {
+	To      string
+	Message []byte
+	SentAt  time.Time
+}
+
+// MemorySender is an EmailSender that keeps the emails in memory instead of sending them,
+// so that tests can inspect what would have been sent. Nothing removes the emails, so it must not be used outside of tests.
+type MemorySender struct {
+	mu     sync.Mutex
+	emails []SentEmail
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (ms *MemorySender) Send(to string, msg []byte) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.emails = append(ms.emails, SentEmail{
+		To:      to,
+		Message: append([]byte(nil), msg...),
+		SentAt:  time.Now(),
+	})
+
+	return nil
+}
+
+// Emails returns a copy of all captured emails in the order they have been sent
+func (ms *MemorySender) Emails() []SentEmail {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	return append([]SentEmail(nil), ms.emails...)
+}
+
+// EmailsTo returns the captured emails that have been sent to a particular recipient
+func (ms *MemorySender) EmailsTo(to string) []SentEmail {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	emails := make([]SentEmail, 0)
+	for _, email := range ms.emails {
+		if email.To == to {
+			emails = append(emails, email)
+		}
+	}
+
+	return emails
+}
+
+// Reset removes all captured emails
+func (ms *MemorySender) Reset() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.emails = nil
+}
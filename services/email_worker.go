@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+)
+
+// EmailsWorker sends the emails from the outbox in the background, retrying the failed ones with exponential backoff
+type EmailsWorker interface {
+	Run()
+	Shutdown(ctx context.Context) error
+}
+
+// The time for which a claimed email is not picked up again, in case the worker dies while sending it
+const emailLease = 5 * time.Minute
+
+type emailsWorker struct {
+	db           db.Manager
+	sender       EmailSender
+	pollInterval time.Duration
+	batchSize    uint64
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	logger       log.Logger
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+func NewEmailsWorker(db db.Manager, sender EmailSender, pollInterval time.Duration, batchSize uint64, maxAttempts int, backoff, maxBackoff time.Duration, logger log.Logger) EmailsWorker {
+	return &emailsWorker{
+		db:           db,
+		sender:       sender,
+		pollInterval: pollInterval,
+		batchSize:    batchSize,
+		maxAttempts:  maxAttempts,
+		backoff:      backoff,
+		maxBackoff:   maxBackoff,
+		logger:       logger,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Run polls the outbox and sends the due emails until Shutdown is called. It blocks, so it should be started in a goroutine.
+func (ew *emailsWorker) Run() {
+	defer close(ew.done)
+
+	ticker := time.NewTicker(ew.pollInterval)
+	defer ticker.Stop()
+
+	ew.logger.Infoln("Emails worker started")
+
+	for {
+		ew.sendDue()
+
+		select {
+		case <-ew.stop:
+			// Drain the emails that became due while the last batch was being sent
+			ew.sendDue()
+			ew.logger.Infoln("Emails worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown stops the worker after it has sent all emails that are currently due. Emails that are not sent before the
+// context is done stay in the outbox and are sent after the next start.
+func (ew *emailsWorker) Shutdown(ctx context.Context) error {
+	close(ew.stop)
+
+	select {
+	case <-ew.done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "emails worker did not stop in time")
+	}
+}
+
+// sendDue sends due emails batch by batch until there are no more due emails or the worker is stopped
+func (ew *emailsWorker) sendDue() {
+	for {
+		emails, err := ew.db.EmailOutbox().ClaimDue(ew.batchSize, time.Now().UTC().Add(emailLease))
+		if err != nil {
+			ew.logger.WithError(err).Warnln("Could not claim due emails")
+			return
+		}
+
+		for i := range emails {
+			ew.send(&emails[i])
+		}
+
+		if uint64(len(emails)) < ew.batchSize {
+			return
+		}
+	}
+}
+
+func (ew *emailsWorker) send(email *models.OutboxEmail) {
+	sendErr := ew.sender.Send(email.Recipient, []byte(email.Message))
+	if sendErr == nil {
+		if err := ew.db.EmailOutbox().MarkSent(email.Id); err != nil {
+			ew.logger.WithError(err).WithField("emailId", email.Id).Warnln("Could not mark email as sent")
+		}
+
+		return
+	}
+
+	attempts := email.Attempts + 1
+	dead := attempts >= ew.maxAttempts
+	logger := ew.logger.WithError(sendErr).WithField("emailId", email.Id).WithField("attempts", attempts)
+
+	if dead {
+		logger.Errorln("Could not send email, giving up")
+	} else {
+		logger.Warnln("Could not send email, will retry")
+	}
+
+	if err := ew.db.EmailOutbox().MarkFailed(email.Id, sendErr.Error(), time.Now().UTC().Add(ew.backoffFor(attempts)), dead); err != nil {
+		ew.logger.WithError(err).WithField("emailId", email.Id).Warnln("Could not mark email as failed")
+	}
+}
+
+// backoffFor returns the delay before the next attempt, doubling it after every failed attempt up to maxBackoff
+func (ew *emailsWorker) backoffFor(attempts int) time.Duration {
+	delay := ew.backoff
+	for i := 1; i < attempts && delay < ew.maxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > ew.maxBackoff {
+		return ew.maxBackoff
+	}
+
+	return delay
+}
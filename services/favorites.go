@@ -0,0 +1,87 @@
+package services
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+)
+
+type FavoritesService interface {
+	Add(userId, restaurantId string) error
+	Remove(userId, restaurantId string) error
+	List(userId string, top, skip uint64) ([]models.FavoriteRestaurant, error)
+	Count(userId string) (int64, error)
+	FavoriteIds(userId string, restaurantIds []string) (map[string]bool, error)
+}
+
+type favoritesService struct {
+	db db.Manager
+}
+
+func NewFavorites(db db.Manager) FavoritesService {
+	return &favoritesService{
+		db: db,
+	}
+}
+
+// Add saves a restaurant as a favorite of a user. Adding a favorite again is not an error.
+func (fs *favoritesService) Add(userId, restaurantId string) error {
+	exists, err := fs.db.Restaurants().Exists(restaurantId)
+	if err != nil {
+		return errors.Wrap(err, "could not check if restaurant exists")
+	}
+
+	if !exists {
+		return ErrRestaurantNotFound
+	}
+
+	err = fs.db.Favorites().Insert(&models.Favorite{
+		UserId:       userId,
+		RestaurantId: restaurantId,
+		CreatedAt:    time.Now().UTC(),
+	})
+
+	return errors.Wrap(err, "could not insert favorite")
+}
+
+// Remove removes a restaurant from the favorites of a user. Removing a restaurant that is not a favorite is not an error.
+func (fs *favoritesService) Remove(userId, restaurantId string) error {
+	err := fs.db.Favorites().Delete(userId, restaurantId)
+	return errors.Wrap(err, "could not delete favorite")
+}
+
+func (fs *favoritesService) List(userId string, top, skip uint64) ([]models.FavoriteRestaurant, error) {
+	favorites, err := fs.db.Favorites().ListForUser(userId, top, skip)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get favorite restaurants")
+	}
+
+	return favorites, nil
+}
+
+func (fs *favoritesService) Count(userId string) (int64, error) {
+	count, err := fs.db.Favorites().CountForUser(userId)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not count favorite restaurants")
+	}
+
+	return count, nil
+}
+
+// FavoriteIds returns which of the given restaurants are favorites of a user
+func (fs *favoritesService) FavoriteIds(userId string, restaurantIds []string) (map[string]bool, error) {
+	ids, err := fs.db.Favorites().ListFavoriteIds(userId, restaurantIds)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get favorite restaurant ids")
+	}
+
+	favorites := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		favorites[id] = true
+	}
+
+	return favorites, nil
+}
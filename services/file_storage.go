@@ -0,0 +1,161 @@
+package services
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+)
+
+var (
+	ErrFileNotFound = errors.New("file not found")
+	ErrInvalidKey   = errors.New("invalid file key")
+)
+
+// FileStorage stores uploaded files by keys, which are slash separated paths such as restaurants/{id}/{file}
+type FileStorage interface {
+	Put(key string, data []byte) error
+	// Open returns the file with the given key. The caller must close it.
+	Open(key string) (*StoredFile, error)
+	Delete(key string) error
+}
+
+// StoredFile is the content of a file in a FileStorage together with its metadata. The content also implements io.Seeker
+// if the storage supports it, e.g. for files on the local filesystem.
+type StoredFile struct {
+	io.ReadCloser
+	Size    int64
+	ModTime time.Time
+}
+
+// storeImage puts a processed image and its thumbnail in the storage under the given directory and returns their keys.
+// Nothing is left in the storage when it fails.
+func storeImage(storage FileStorage, dir, id string, image *ProcessedImage) (string, string, error) {
+	storageKey := path.Join(dir, id+image.Extension)
+	thumbnailKey := path.Join(dir, id+"_thumb"+image.Extension)
+
+	if err := storage.Put(storageKey, image.Data); err != nil {
+		return "", "", errors.Wrap(err, "could not store image")
+	}
+
+	if err := storage.Put(thumbnailKey, image.Thumbnail); err != nil {
+		_ = storage.Delete(storageKey)
+		return "", "", errors.Wrap(err, "could not store thumbnail")
+	}
+
+	return storageKey, thumbnailKey, nil
+}
+
+// deleteFiles deletes the files with the given keys. Failures are only logged, as the files are deleted after their records
+// and cannot be reached anymore.
+func deleteFiles(storage FileStorage, logger log.Logger, keys ...string) {
+	for _, key := range keys {
+		if err := storage.Delete(key); err != nil {
+			logger.WithError(err).WithField("key", key).Warnln("could not delete file")
+		}
+	}
+}
+
+type filesystemStorage struct {
+	root string
+}
+
+// NewFilesystemStorage returns a FileStorage that keeps the files in a directory of the local filesystem
+func NewFilesystemStorage(root string) (FileStorage, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get absolute storage path")
+	}
+
+	if err = os.MkdirAll(root, 0755); err != nil {
+		return nil, errors.Wrap(err, "could not create storage directory")
+	}
+
+	return &filesystemStorage{
+		root: root,
+	}, nil
+}
+
+// Put writes the file to a temporary file first and then renames it, so that readers never see partially written files
+func (fs *filesystemStorage) Put(key string, data []byte) error {
+	path, err := fs.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "could not create file directory")
+	}
+
+	tmpPath := filepath.Join(filepath.Dir(path), "."+uuid.NewV4().String()+".tmp")
+	if err = ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.Wrap(err, "could not write file")
+	}
+
+	if err = os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return errors.Wrap(err, "could not move file")
+	}
+
+	return nil
+}
+
+func (fs *filesystemStorage) Open(key string) (*StoredFile, error) {
+	path, err := fs.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrFileNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not open file")
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, errors.Wrap(err, "could not get file info")
+	}
+
+	return &StoredFile{
+		ReadCloser: file,
+		Size:       info.Size(),
+		ModTime:    info.ModTime(),
+	}, nil
+}
+
+// Delete removes the file. Deleting a file that doesn't exist is not an error.
+func (fs *filesystemStorage) Delete(key string) error {
+	path, err := fs.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "could not delete file")
+	}
+
+	return nil
+}
+
+// path returns the filesystem path of the key making sure that it stays inside the root directory
+func (fs *filesystemStorage) path(key string) (string, error) {
+	path := filepath.Join(fs.root, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, fs.root+string(filepath.Separator)) {
+		return "", ErrInvalidKey
+	}
+
+	return path, nil
+}
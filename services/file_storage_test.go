@@ -0,0 +1,109 @@
+package services
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestFilesystemStorage returns a storage rooted in a subdirectory of a new temporary directory, which the caller must remove
+func newTestFilesystemStorage(t *testing.T) (*filesystemStorage, string) {
+	dir, err := ioutil.TempDir("", "storage")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+
+	storage, err := NewFilesystemStorage(filepath.Join(dir, "root"))
+	if err != nil {
+		t.Fatalf("NewFilesystemStorage() error = %v", err)
+	}
+
+	return storage.(*filesystemStorage), dir
+}
+
+func TestFilesystemStoragePath(t *testing.T) {
+	storage, dir := newTestFilesystemStorage(t)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{key: "restaurants/1/image.jpg"},
+		{key: "/restaurants/1/image.jpg"},
+		{key: "restaurants/../reviews/1/image.jpg"},
+		{key: "", wantErr: true},
+		{key: ".", wantErr: true},
+		{key: "..", wantErr: true},
+		{key: "../secret", wantErr: true},
+		{key: "restaurants/../../secret", wantErr: true},
+		{key: "../root-sibling/image.jpg", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			path, err := storage.path(tt.key)
+			if tt.wantErr {
+				if err != ErrInvalidKey {
+					t.Errorf("path() = %q, %v, want ErrInvalidKey", path, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("path() error = %v", err)
+			}
+
+			if !strings.HasPrefix(path, storage.root+string(filepath.Separator)) {
+				t.Errorf("path() = %q is outside of %q", path, storage.root)
+			}
+		})
+	}
+}
+
+func TestFilesystemStorage(t *testing.T) {
+	storage, dir := newTestFilesystemStorage(t)
+	defer os.RemoveAll(dir)
+
+	if err := storage.Put("restaurants/1/image.jpg", []byte("image")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	file, err := storage.Open("restaurants/1/image.jpg")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	data, err := ioutil.ReadAll(file)
+	_ = file.Close()
+	if err != nil || string(data) != "image" || file.Size != int64(len("image")) {
+		t.Errorf("Open() read %q (size %d), %v, want %q", data, file.Size, err, "image")
+	}
+
+	if err = storage.Put("../outside.jpg", []byte("image")); err != ErrInvalidKey {
+		t.Errorf("Put() outside of the root error = %v, want ErrInvalidKey", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "outside.jpg")); !os.IsNotExist(err) {
+		t.Error("Put() wrote a file outside of the root")
+	}
+
+	if _, err = storage.Open("../../etc/passwd"); err != ErrInvalidKey {
+		t.Errorf("Open() outside of the root error = %v, want ErrInvalidKey", err)
+	}
+
+	if err = storage.Delete("restaurants/1/image.jpg"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if _, err = storage.Open("restaurants/1/image.jpg"); err != ErrFileNotFound {
+		t.Errorf("Open() after Delete() error = %v, want ErrFileNotFound", err)
+	}
+
+	if err = storage.Delete("restaurants/1/image.jpg"); err != nil {
+		t.Errorf("Delete() of a missing file error = %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	// Registers the GIF decoder used by image.Decode
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	jpegQuality = 85
+	// MaxImageMegapixels limits the dimensions of the uploaded images, so that small files cannot be decoded into huge bitmaps
+	MaxImageMegapixels = 40
+	maxImagePixels     = MaxImageMegapixels * 1000 * 1000
+)
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image format")
+	ErrImageTooLarge    = errors.New("image dimensions are too large")
+)
+
+// ImageContentType returns the content type of an image stored by its key, which ends with the extension of a ProcessedImage
+func ImageContentType(key string) (string, error) {
+	switch path.Ext(key) {
+	case ".jpg":
+		return "image/jpeg", nil
+	case ".png":
+		return "image/png", nil
+	default:
+		return "", ErrUnsupportedImage
+	}
+}
+
+// ProcessedImage is an uploaded image that has been re-encoded without its metadata, together with its thumbnail
+type ProcessedImage struct {
+	ContentType string
+	Extension   string
+	Data        []byte
+	Width       int
+	Height      int
+	Thumbnail   []byte
+}
+
+// ProcessImage checks that the data is a JPEG, PNG, or GIF image by sniffing its content and re-encodes it, which drops all metadata
+// (EXIF, including the GPS location, comments, etc.). The EXIF orientation of JPEG images is applied to the pixels before it is dropped.
+// A thumbnail that fits in a thumbnailSize x thumbnailSize square is generated as well. PNG and GIF images are stored as PNG
+// to keep their transparency, while JPEG images stay JPEG.
+func ProcessImage(data []byte, thumbnailSize int) (*ProcessedImage, error) {
+	contentType := http.DetectContentType(data)
+	if contentType != "image/jpeg" && contentType != "image/png" && contentType != "image/gif" {
+		return nil, ErrUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	if config.Width*config.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	if contentType == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	processed := &ProcessedImage{
+		ContentType: "image/png",
+		Extension:   ".png",
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+	}
+
+	if contentType == "image/jpeg" {
+		processed.ContentType = "image/jpeg"
+		processed.Extension = ".jpg"
+	}
+
+	if processed.Data, err = encodeImage(img, processed.ContentType); err != nil {
+		return nil, errors.Wrap(err, "could not encode image")
+	}
+
+	if processed.Thumbnail, err = encodeImage(fit(img, thumbnailSize), processed.ContentType); err != nil {
+		return nil, errors.Wrap(err, "could not encode thumbnail")
+	}
+
+	return processed, nil
+}
+
+func encodeImage(img image.Image, contentType string) ([]byte, error) {
+	buf := bytes.Buffer{}
+
+	var err error
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+
+	return buf.Bytes(), err
+}
+
+// fit scales the image down, keeping its aspect ratio, so that it fits in a size x size square. Each pixel of the result
+// is the average of the source pixels it covers, which gives smooth thumbnails without an external imaging library.
+func fit(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width <= size && height <= size {
+		return src
+	}
+
+	dstWidth, dstHeight := size, height*size/width
+	if height > width {
+		dstWidth, dstHeight = width*size/height, size
+	}
+
+	if dstWidth < 1 {
+		dstWidth = 1
+	}
+
+	if dstHeight < 1 {
+		dstHeight = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < dstHeight; y++ {
+		y0, y1 := bounds.Min.Y+y*height/dstHeight, bounds.Min.Y+(y+1)*height/dstHeight
+		for x := 0; x < dstWidth; x++ {
+			x0, x1 := bounds.Min.X+x*width/dstWidth, bounds.Min.X+(x+1)*width/dstWidth
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+
+	return dst
+}
+
+// applyOrientation rotates and flips the image according to its EXIF orientation (1-8), so that it is displayed correctly without the tag
+func applyOrientation(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// Orientations 5-8 swap the width and the height
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dx, dy := x, y
+
+			switch orientation {
+			case 2:
+				dx = width - 1 - x
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dy = height - 1 - y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = height-1-y, x
+			case 7:
+				dx, dy = height-1-y, width-1-x
+			case 8:
+				dx, dy = y, width-1-x
+			}
+
+			dst.Set(dx, dy, src.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+
+	return dst
+}
+
+// jpegOrientation returns the orientation tag of the EXIF data of a JPEG image or 1 (normal) if there is no such tag
+func jpegOrientation(data []byte) int {
+	const orientationTag = 0x0112
+
+	// Walk the JPEG segments until the APP1 segment holding the EXIF data. Like image/jpeg, fill bytes before markers
+	// and markers without segments are skipped. The walk stops at the first malformed segment.
+	for i := 2; i+2 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+
+		if marker == 0xFF {
+			i++
+			continue
+		}
+
+		if marker == 0x00 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			i += 2
+			continue
+		}
+
+		if marker == 0xDA || marker == 0xD9 || i+4 > len(data) {
+			break
+		}
+
+		// The length includes its own two bytes
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			break
+		}
+
+		segment := data[i+4 : i+2+length]
+		i += 2 + length
+
+		if marker != 0xE1 || len(segment) < 14 || string(segment[:6]) != "Exif\x00\x00" {
+			continue
+		}
+
+		tiff := segment[6:]
+
+		var order binary.ByteOrder = binary.BigEndian
+		if string(tiff[:2]) == "II" {
+			order = binary.LittleEndian
+		}
+
+		ifd := int(order.Uint32(tiff[4:]))
+		if ifd < 0 || ifd+2 > len(tiff) {
+			return 1
+		}
+
+		entries := int(order.Uint16(tiff[ifd:]))
+		for e := 0; e < entries; e++ {
+			entry := ifd + 2 + e*12
+			if entry+12 > len(tiff) {
+				return 1
+			}
+
+			if order.Uint16(tiff[entry:]) == orientationTag {
+				return int(order.Uint16(tiff[entry+8:]))
+			}
+		}
+
+		return 1
+	}
+
+	return 1
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// exifSegment returns an APP1 segment with a little-endian EXIF block that has a single orientation entry
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte{'I', 'I', 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00, 0x01, 0x00}
+	entry := make([]byte, 12)
+	binary.LittleEndian.PutUint16(entry, 0x0112)
+	binary.LittleEndian.PutUint16(entry[2:], 3)
+	binary.LittleEndian.PutUint32(entry[4:], 1)
+	binary.LittleEndian.PutUint16(entry[8:], orientation)
+
+	payload := append(append([]byte("Exif\x00\x00"), tiff...), entry...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+
+	return append(segment, payload...)
+}
+
+func TestJpegOrientation(t *testing.T) {
+	soi := []byte{0xFF, 0xD8}
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{name: "no segments", data: soi, want: 1},
+		{name: "exif orientation", data: append(append([]byte{}, soi...), exifSegment(6)...), want: 6},
+		{name: "fill bytes before the marker", data: append(append([]byte{}, soi...), append([]byte{0xFF, 0xFF}, exifSegment(8)...)...), want: 8},
+		{name: "zero length after a stray marker", data: append(append([]byte{}, soi...), 0xFF, 0x00, 0x00, 0x00), want: 1},
+		{name: "zero length segment", data: append(append([]byte{}, soi...), 0xFF, 0xE1, 0x00, 0x00, 0xFF, 0xD9), want: 1},
+		{name: "length of one", data: append(append([]byte{}, soi...), 0xFF, 0xE0, 0x00, 0x01, 0x00), want: 1},
+		{name: "truncated app1 segment", data: append(append([]byte{}, soi...), exifSegment(6)[:12]...), want: 1},
+		{name: "truncated length", data: append(append([]byte{}, soi...), 0xFF, 0xE1, 0x00), want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// encodeTestImage returns a width x height image in the given format (jpeg, png, or gif)
+func encodeTestImage(t *testing.T, format string, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	buf := bytes.Buffer{}
+
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "png":
+		err = png.Encode(&buf, img)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+
+	if err != nil {
+		t.Fatalf("could not encode %s image: %v", format, err)
+	}
+
+	return buf.Bytes()
+}
+
+// withPNGSize returns a copy of the PNG with the dimensions in its header replaced. Only the header is valid afterwards.
+func withPNGSize(data []byte, width, height uint32) []byte {
+	data = append([]byte{}, data...)
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	return data
+}
+
+// withExif returns a copy of the JPEG with an EXIF segment that has the given orientation inserted after the SOI marker
+func withExif(data []byte, orientation uint16) []byte {
+	return append(append(append([]byte{}, data[:2]...), exifSegment(orientation)...), data[2:]...)
+}
+
+func TestProcessImage(t *testing.T) {
+	pngImage := encodeTestImage(t, "png", 400, 200)
+
+	tests := []struct {
+		name                            string
+		data                            []byte
+		wantErr                         error
+		wantContentType                 string
+		wantWidth, wantHeight           int
+		wantThumbWidth, wantThumbHeight int
+	}{
+		{name: "png", data: pngImage, wantContentType: "image/png", wantWidth: 400, wantHeight: 200, wantThumbWidth: 100, wantThumbHeight: 50},
+		{name: "gif is stored as png", data: encodeTestImage(t, "gif", 60, 300), wantContentType: "image/png", wantWidth: 60, wantHeight: 300, wantThumbWidth: 20, wantThumbHeight: 100},
+		{name: "small image is not scaled up", data: encodeTestImage(t, "jpeg", 40, 30), wantContentType: "image/jpeg", wantWidth: 40, wantHeight: 30, wantThumbWidth: 40, wantThumbHeight: 30},
+		{name: "exif orientation is applied", data: withExif(encodeTestImage(t, "jpeg", 200, 100), 6), wantContentType: "image/jpeg", wantWidth: 100, wantHeight: 200, wantThumbWidth: 50, wantThumbHeight: 100},
+		{name: "not an image", data: []byte("<html><body>not an image</body></html>"), wantErr: ErrUnsupportedImage},
+		{name: "unsupported image format", data: []byte("BM\x00\x00\x00\x00\x00\x00\x00\x00\x36\x00\x00\x00"), wantErr: ErrUnsupportedImage},
+		{name: "truncated image", data: pngImage[:len(pngImage)/2], wantErr: ErrUnsupportedImage},
+		{name: "too many pixels", data: withPNGSize(pngImage, 10000, 10000), wantErr: ErrImageTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessImage(tt.data, 100)
+			if err != tt.wantErr {
+				t.Fatalf("ProcessImage() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if got.ContentType != tt.wantContentType || got.Width != tt.wantWidth || got.Height != tt.wantHeight {
+				t.Errorf("ProcessImage() = %s %dx%d, want %s %dx%d", got.ContentType, got.Width, got.Height, tt.wantContentType, tt.wantWidth, tt.wantHeight)
+			}
+
+			if bytes.Contains(got.Data, []byte("Exif")) {
+				t.Error("ProcessImage() kept the EXIF metadata")
+			}
+
+			for name, data := range map[string][]byte{"image": got.Data, "thumbnail": got.Thumbnail} {
+				config, format, err := image.DecodeConfig(bytes.NewReader(data))
+				if err != nil {
+					t.Fatalf("could not decode %s: %v", name, err)
+				}
+
+				if "image/"+format != tt.wantContentType {
+					t.Errorf("%s format = %s, want %s", name, format, tt.wantContentType)
+				}
+
+				if name == "thumbnail" && (config.Width != tt.wantThumbWidth || config.Height != tt.wantThumbHeight) {
+					t.Errorf("thumbnail size = %dx%d, want %dx%d", config.Width, config.Height, tt.wantThumbWidth, tt.wantThumbHeight)
+				}
+			}
+		})
+	}
+}
+
+func TestImageContentType(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "restaurants/1/image.jpg", want: "image/jpeg"},
+		{key: "restaurants/1/image_thumb.png", want: "image/png"},
+		{key: "restaurants/1/page.html", wantErr: true},
+		{key: "restaurants/1/image", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := ImageContentType(tt.key)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ImageContentType() = %q, %v, want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
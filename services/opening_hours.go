@@ -0,0 +1,106 @@
+package services
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+)
+
+type OpeningHoursService interface {
+	List(restaurantId string) ([]models.OpeningHours, error)
+	Set(restaurant *models.Restaurant, timeZone string, hours []models.OpeningHours) error
+	AddException(exception *models.ScheduleException) error
+	GetException(id string) (*models.ScheduleException, error)
+	ListExceptions(restaurantId string) ([]models.ScheduleException, error)
+	DeleteException(id string) error
+}
+
+var (
+	ErrInvalidTimeZone           = errors.New("invalid time zone")
+	ErrScheduleExceptionNotFound = errors.New("schedule exception not found")
+)
+
+type openingHoursService struct {
+	db db.Manager
+}
+
+func NewOpeningHours(db db.Manager) OpeningHoursService {
+	return &openingHoursService{
+		db: db,
+	}
+}
+
+func (ohs *openingHoursService) List(restaurantId string) ([]models.OpeningHours, error) {
+	hours, err := ohs.db.OpeningHours().ListForRestaurant(restaurantId)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get opening hours")
+	}
+
+	return hours, nil
+}
+
+// Set replaces the weekly schedule of the restaurant and changes its time zone. The time zone has to be known by the database,
+// which evaluates the opening hours, and it is checked there instead of in the time zone database of the server.
+func (ohs *openingHoursService) Set(restaurant *models.Restaurant, timeZone string, hours []models.OpeningHours) error {
+	exists, err := ohs.db.OpeningHours().TimeZoneExists(timeZone)
+	if err != nil {
+		return errors.Wrap(err, "could not check time zone")
+	}
+
+	if !exists {
+		return ErrInvalidTimeZone
+	}
+
+	for i := range hours {
+		hours[i].RestaurantId = restaurant.Id
+	}
+
+	err = ohs.db.OpeningHours().Replace(restaurant.Id, timeZone, hours)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return ErrRestaurantNotFound
+		}
+
+		return errors.Wrap(err, "could not replace opening hours")
+	}
+
+	restaurant.TimeZone = timeZone
+	return nil
+}
+
+func (ohs *openingHoursService) AddException(exception *models.ScheduleException) error {
+	err := ohs.db.OpeningHours().InsertException(exception)
+	return errors.Wrap(err, "could not insert schedule exception")
+}
+
+func (ohs *openingHoursService) GetException(id string) (*models.ScheduleException, error) {
+	exception, err := ohs.db.OpeningHours().GetException(id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return nil, ErrScheduleExceptionNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not get schedule exception")
+	}
+
+	return exception, nil
+}
+
+func (ohs *openingHoursService) ListExceptions(restaurantId string) ([]models.ScheduleException, error) {
+	exceptions, err := ohs.db.OpeningHours().ListExceptions(restaurantId)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get schedule exceptions")
+	}
+
+	return exceptions, nil
+}
+
+func (ohs *openingHoursService) DeleteException(id string) error {
+	err := ohs.db.OpeningHours().DeleteException(id)
+	if err == db.ErrNotFound {
+		return ErrScheduleExceptionNotFound
+	}
+
+	return errors.Wrap(err, "could not delete schedule exception")
+}
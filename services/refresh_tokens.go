@@ -0,0 +1,69 @@
+package services
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+)
+
+type RefreshTokensService interface {
+	Create(token *models.RefreshToken) error
+	GetByHash(hashedToken string) (*models.RefreshToken, error)
+	Rotate(oldId string, newToken *models.RefreshToken) error
+	Revoke(id string) error
+	RevokeAllForUser(userId string) error
+}
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenRevoked  = errors.New("refresh token has already been revoked")
+)
+
+type refreshTokensService struct {
+	db db.Manager
+}
+
+func NewRefreshTokens(db db.Manager) RefreshTokensService {
+	return &refreshTokensService{
+		db: db,
+	}
+}
+
+func (rts *refreshTokensService) Create(token *models.RefreshToken) error {
+	err := rts.db.RefreshTokens().Insert(token)
+	return errors.Wrap(err, "could not insert refresh token")
+}
+
+func (rts *refreshTokensService) GetByHash(hashedToken string) (*models.RefreshToken, error) {
+	token, err := rts.db.RefreshTokens().GetByHash(hashedToken)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return nil, ErrRefreshTokenNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not get refresh token")
+	}
+
+	return token, nil
+}
+
+func (rts *refreshTokensService) Rotate(oldId string, newToken *models.RefreshToken) error {
+	err := rts.db.RefreshTokens().Rotate(oldId, newToken)
+	if err == db.ErrNotFound {
+		return ErrRefreshTokenRevoked
+	}
+
+	return errors.Wrap(err, "could not rotate refresh token")
+}
+
+func (rts *refreshTokensService) Revoke(id string) error {
+	err := rts.db.RefreshTokens().Revoke(id)
+	return errors.Wrap(err, "could not revoke refresh token")
+}
+
+// RevokeAllForUser revokes all refresh tokens of the user and invalidates all access tokens that have been issued to them
+func (rts *refreshTokensService) RevokeAllForUser(userId string) error {
+	err := rts.db.RefreshTokens().RevokeAllForUser(userId)
+	return errors.Wrap(err, "could not revoke all refresh tokens for user")
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+)
+
+type RestaurantImagesService interface {
+	Upload(restaurantId string, data []byte, cover bool) (*models.RestaurantImage, error)
+	GetById(id string) (*models.RestaurantImage, error)
+	List(restaurantId string) ([]models.RestaurantImage, error)
+	SetCover(image *models.RestaurantImage) error
+	Delete(image *models.RestaurantImage) error
+	DeleteFiles(images []models.RestaurantImage)
+	OpenFile(key string) (*StoredFile, error)
+	URL(key string) string
+}
+
+var (
+	ErrImageNotFound = errors.New("image not found")
+	ErrTooManyImages = errors.New("restaurant has too many images")
+)
+
+type restaurantImagesService struct {
+	db               db.Manager
+	storage          FileStorage
+	publicURL        string
+	maxPerRestaurant int
+	thumbnailSize    int
+	logger           log.Logger
+}
+
+// NewRestaurantImages returns a RestaurantImagesService that keeps the images in the storage. The images are served under the publicURL.
+func NewRestaurantImages(db db.Manager, storage FileStorage, publicURL string, maxPerRestaurant, thumbnailSize int, logger log.Logger) RestaurantImagesService {
+	return &restaurantImagesService{
+		db:               db,
+		storage:          storage,
+		publicURL:        publicURL,
+		maxPerRestaurant: maxPerRestaurant,
+		thumbnailSize:    thumbnailSize,
+		logger:           logger,
+	}
+}
+
+// Upload processes the image (see ProcessImage), stores it with its thumbnail and adds it to the restaurant.
+// The first image of a restaurant always becomes its cover image.
+func (ris *restaurantImagesService) Upload(restaurantId string, data []byte, cover bool) (*models.RestaurantImage, error) {
+	count, err := ris.db.RestaurantImages().CountForRestaurant(restaurantId)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not count restaurant images")
+	}
+
+	if count >= ris.maxPerRestaurant {
+		return nil, ErrTooManyImages
+	}
+
+	processed, err := ProcessImage(data, ris.thumbnailSize)
+	if err != nil {
+		return nil, err
+	}
+
+	image := &models.RestaurantImage{
+		Id:           uuid.NewV4().String(),
+		RestaurantId: restaurantId,
+		ContentType:  processed.ContentType,
+		Width:        processed.Width,
+		Height:       processed.Height,
+		Size:         len(processed.Data),
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	image.StorageKey, image.ThumbnailKey, err = storeImage(ris.storage, path.Join("restaurants", restaurantId), image.Id, processed)
+	if err != nil {
+		return nil, err
+	}
+
+	// The count above only avoids processing images that cannot be added, the limit is enforced by the insert
+	count, err = ris.db.RestaurantImages().Insert(image, ris.maxPerRestaurant)
+	if err != nil {
+		ris.DeleteFiles([]models.RestaurantImage{*image})
+		if err == db.ErrLimitReached {
+			return nil, ErrTooManyImages
+		}
+
+		return nil, errors.Wrap(err, "could not insert restaurant image")
+	}
+
+	if cover || count == 0 {
+		if err = ris.SetCover(image); err != nil {
+			return nil, err
+		}
+	}
+
+	return image, nil
+}
+
+func (ris *restaurantImagesService) GetById(id string) (*models.RestaurantImage, error) {
+	image, err := ris.db.RestaurantImages().GetById(id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return nil, ErrImageNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not get restaurant image")
+	}
+
+	return image, nil
+}
+
+func (ris *restaurantImagesService) List(restaurantId string) ([]models.RestaurantImage, error) {
+	images, err := ris.db.RestaurantImages().ListForRestaurant(restaurantId)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list restaurant images")
+	}
+
+	return images, nil
+}
+
+// SetCover makes the image the cover image of its restaurant and uses its URL as the img of the restaurant
+func (ris *restaurantImagesService) SetCover(image *models.RestaurantImage) error {
+	if err := ris.db.RestaurantImages().SetCover(image, ris.URL(image.StorageKey)); err != nil {
+		return errors.Wrap(err, "could not set cover image")
+	}
+
+	image.IsCover = true
+	return nil
+}
+
+// Delete removes the image from its restaurant and deletes its files
+func (ris *restaurantImagesService) Delete(image *models.RestaurantImage) error {
+	if err := ris.db.RestaurantImages().Delete(image); err != nil {
+		if err == db.ErrNotFound {
+			return ErrImageNotFound
+		}
+
+		return errors.Wrap(err, "could not delete restaurant image")
+	}
+
+	ris.DeleteFiles([]models.RestaurantImage{*image})
+	return nil
+}
+
+// DeleteFiles deletes the files of images whose records are already deleted, e.g. together with their restaurant
+func (ris *restaurantImagesService) DeleteFiles(images []models.RestaurantImage) {
+	for _, image := range images {
+		deleteFiles(ris.storage, ris.logger, image.StorageKey, image.ThumbnailKey)
+	}
+}
+
+func (ris *restaurantImagesService) OpenFile(key string) (*StoredFile, error) {
+	return ris.storage.Open(key)
+}
+
+// URL returns the public URL of the file with the given key
+func (ris *restaurantImagesService) URL(key string) string {
+	return ris.publicURL + "/" + key
+}
@@ -5,14 +5,21 @@ import (
 
 	"github.com/hrist0stoichev/ReviewsSystem/db"
 	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/db/stores"
 )
 
 type RestaurantsService interface {
 	Create(restaurant *models.Restaurant) error
-	ListByRating(top, skip int, userId string, userRole models.Role, minrRating, maxRating float32) ([]models.Restaurant, error)
+	Update(restaurant *models.Restaurant) error
+	List(filter stores.RestaurantsFilter, userId string, userRole models.Role) ([]models.Restaurant, error)
+	Count(filter stores.RestaurantsFilter, userId string, userRole models.Role) (int64, error)
+	Nearby(filter stores.RestaurantsFilter, userId string, userRole models.Role) ([]models.NearbyRestaurant, error)
+	Facets(filter stores.RestaurantsFilter, userId string, userRole models.Role) (*models.RestaurantFacets, error)
+	Search(query, city string, top, skip uint64, userId string, userRole models.Role) ([]models.RestaurantSearchResult, error)
 	GetSingle(id string) (*models.Restaurant, error)
 	Exists(id string) (bool, error)
-	Delete(restId string) error
+	Delete(restId string) (int64, *models.DeletedUploads, error)
+	SoftDelete(restId string) error
 }
 
 var (
@@ -34,14 +41,22 @@ func (rs *restaurantsService) Create(restaurant *models.Restaurant) error {
 	return errors.Wrap(err, "could not insert restaurant")
 }
 
-func (rs *restaurantsService) ListByRating(top, skip int, userId string, userRole models.Role, minRating, maxRating float32) ([]models.Restaurant, error) {
-	var ownerId *string = nil
+func (rs *restaurantsService) Update(restaurant *models.Restaurant) error {
+	err := rs.db.Restaurants().Update(restaurant)
+	if err == db.ErrNotFound {
+		return ErrRestaurantNotFound
+	}
+
+	return errors.Wrap(err, "could not update restaurant")
+}
 
+// List returns the restaurants matching the filter. Owners can list only their own restaurants.
+func (rs *restaurantsService) List(filter stores.RestaurantsFilter, userId string, userRole models.Role) ([]models.Restaurant, error) {
 	if userRole == models.Owner {
-		ownerId = &userId
+		filter.OwnerId = &userId
 	}
 
-	restaurants, err := rs.db.Restaurants().GetByRating(top, skip, ownerId, minRating, maxRating)
+	restaurants, err := rs.db.Restaurants().List(filter)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not get restaurants")
 	}
@@ -49,6 +64,64 @@ func (rs *restaurantsService) ListByRating(top, skip int, userId string, userRol
 	return restaurants, nil
 }
 
+// Count returns the number of restaurants matching the filter. Owners can count only their own restaurants.
+func (rs *restaurantsService) Count(filter stores.RestaurantsFilter, userId string, userRole models.Role) (int64, error) {
+	if userRole == models.Owner {
+		filter.OwnerId = &userId
+	}
+
+	count, err := rs.db.Restaurants().Count(filter)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not count restaurants")
+	}
+
+	return count, nil
+}
+
+// Nearby returns the restaurants within filter.Near ordered by distance. Owners can list only their own restaurants.
+func (rs *restaurantsService) Nearby(filter stores.RestaurantsFilter, userId string, userRole models.Role) ([]models.NearbyRestaurant, error) {
+	if userRole == models.Owner {
+		filter.OwnerId = &userId
+	}
+
+	restaurants, err := rs.db.Restaurants().ListNearby(filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get nearby restaurants")
+	}
+
+	return restaurants, nil
+}
+
+// Facets returns the numbers of restaurants matching the filter per tag and per city. Owners see only their own restaurants.
+func (rs *restaurantsService) Facets(filter stores.RestaurantsFilter, userId string, userRole models.Role) (*models.RestaurantFacets, error) {
+	if userRole == models.Owner {
+		filter.OwnerId = &userId
+	}
+
+	facets, err := rs.db.Restaurants().Facets(filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get restaurant facets")
+	}
+
+	return facets, nil
+}
+
+// Search returns the restaurants matching a full-text query ordered by relevance. Owners can find only their own restaurants.
+func (rs *restaurantsService) Search(query, city string, top, skip uint64, userId string, userRole models.Role) ([]models.RestaurantSearchResult, error) {
+	var ownerId *string = nil
+
+	if userRole == models.Owner {
+		ownerId = &userId
+	}
+
+	results, err := rs.db.Restaurants().Search(query, city, ownerId, top, skip)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not search restaurants")
+	}
+
+	return results, nil
+}
+
 func (rs *restaurantsService) GetSingle(id string) (*models.Restaurant, error) {
 	restaurant, err := rs.db.Restaurants().GetSingle(id)
 	if err != nil {
@@ -71,7 +144,26 @@ func (rs *restaurantsService) Exists(id string) (bool, error) {
 	return exists, nil
 }
 
-func (rs *restaurantsService) Delete(id string) error {
-	err := rs.db.Restaurants().Delete(id)
-	return errors.Wrap(err, "cannot delete restaurant")
+// Delete removes the restaurant together with its reviews and returns the number of deleted reviews.
+// The files of the returned images and media are not deleted.
+func (rs *restaurantsService) Delete(id string) (int64, *models.DeletedUploads, error) {
+	deletedReviews, uploads, err := rs.db.Restaurants().Delete(id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return 0, nil, ErrRestaurantNotFound
+		}
+
+		return 0, nil, errors.Wrap(err, "cannot delete restaurant")
+	}
+
+	return deletedReviews, uploads, nil
+}
+
+func (rs *restaurantsService) SoftDelete(id string) error {
+	err := rs.db.Restaurants().SoftDelete(id)
+	if err == db.ErrNotFound {
+		return ErrRestaurantNotFound
+	}
+
+	return errors.Wrap(err, "cannot soft delete restaurant")
 }
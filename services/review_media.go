@@ -0,0 +1,146 @@
+package services
+
+import (
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+)
+
+type ReviewMediaService interface {
+	Upload(reviewId string, data []byte) (*models.ReviewMedia, error)
+	GetById(id string) (*models.ReviewMedia, error)
+	ListForReviews(reviewIds []string) (map[string][]models.ReviewMedia, error)
+	Delete(media *models.ReviewMedia) error
+	DeleteFiles(media []models.ReviewMedia)
+	URL(key string) string
+}
+
+var (
+	ErrReviewMediaNotFound = errors.New("review media not found")
+	ErrTooManyReviewMedia  = errors.New("review has too many media")
+)
+
+type reviewMediaService struct {
+	db            db.Manager
+	storage       FileStorage
+	publicURL     string
+	maxPerReview  int
+	thumbnailSize int
+	logger        log.Logger
+}
+
+// NewReviewMedia returns a ReviewMediaService that keeps the photos in the storage. The photos are served under the publicURL.
+func NewReviewMedia(db db.Manager, storage FileStorage, publicURL string, maxPerReview, thumbnailSize int, logger log.Logger) ReviewMediaService {
+	return &reviewMediaService{
+		db:            db,
+		storage:       storage,
+		publicURL:     publicURL,
+		maxPerReview:  maxPerReview,
+		thumbnailSize: thumbnailSize,
+		logger:        logger,
+	}
+}
+
+// Upload processes the photo (see ProcessImage), stores it with its thumbnail and attaches it to the review
+func (rms *reviewMediaService) Upload(reviewId string, data []byte) (*models.ReviewMedia, error) {
+	count, err := rms.db.ReviewMedia().CountForReview(reviewId)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not count review media")
+	}
+
+	if count >= rms.maxPerReview {
+		return nil, ErrTooManyReviewMedia
+	}
+
+	processed, err := ProcessImage(data, rms.thumbnailSize)
+	if err != nil {
+		return nil, err
+	}
+
+	media := &models.ReviewMedia{
+		Id:          uuid.NewV4().String(),
+		ReviewId:    reviewId,
+		ContentType: processed.ContentType,
+		Width:       processed.Width,
+		Height:      processed.Height,
+		Size:        len(processed.Data),
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	media.StorageKey, media.ThumbnailKey, err = storeImage(rms.storage, path.Join("reviews", reviewId), media.Id, processed)
+	if err != nil {
+		return nil, err
+	}
+
+	// The count above only avoids processing photos that cannot be added, the limit is enforced by the insert
+	if err = rms.db.ReviewMedia().Insert(media, rms.maxPerReview); err != nil {
+		rms.DeleteFiles([]models.ReviewMedia{*media})
+		if err == db.ErrLimitReached {
+			return nil, ErrTooManyReviewMedia
+		}
+
+		return nil, errors.Wrap(err, "could not insert review media")
+	}
+
+	return media, nil
+}
+
+func (rms *reviewMediaService) GetById(id string) (*models.ReviewMedia, error) {
+	media, err := rms.db.ReviewMedia().GetById(id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return nil, ErrReviewMediaNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not get review media")
+	}
+
+	return media, nil
+}
+
+// ListForReviews returns the media of the reviews grouped by review id
+func (rms *reviewMediaService) ListForReviews(reviewIds []string) (map[string][]models.ReviewMedia, error) {
+	media, err := rms.db.ReviewMedia().ListForReviews(reviewIds)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list review media")
+	}
+
+	mediaByReview := make(map[string][]models.ReviewMedia, len(reviewIds))
+	for _, m := range media {
+		mediaByReview[m.ReviewId] = append(mediaByReview[m.ReviewId], m)
+	}
+
+	return mediaByReview, nil
+}
+
+// Delete removes the photo from its review and deletes its files
+func (rms *reviewMediaService) Delete(media *models.ReviewMedia) error {
+	if err := rms.db.ReviewMedia().Delete(media.Id); err != nil {
+		if err == db.ErrNotFound {
+			return ErrReviewMediaNotFound
+		}
+
+		return errors.Wrap(err, "could not delete review media")
+	}
+
+	rms.DeleteFiles([]models.ReviewMedia{*media})
+	return nil
+}
+
+// DeleteFiles deletes the files of media whose records are already deleted, e.g. together with their review
+func (rms *reviewMediaService) DeleteFiles(media []models.ReviewMedia) {
+	for _, m := range media {
+		deleteFiles(rms.storage, rms.logger, m.StorageKey, m.ThumbnailKey)
+	}
+}
+
+// URL returns the public URL of the file with the given key
+func (rms *reviewMediaService) URL(key string) string {
+	return rms.publicURL + "/" + key
+}
@@ -5,14 +5,18 @@ import (
 
 	"github.com/hrist0stoichev/ReviewsSystem/db"
 	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/db/stores"
 )
 
 type ReviewsService interface {
 	Create(review *models.Review) error
 	HasUserReviewed(userId, restaurantId string) (bool, error)
-	ListForRestaurant(restaurantId string, unanswered bool, top, skip uint64, orderBy string, isAsc bool) ([]models.Review, error)
+	ListForRestaurant(filter stores.ReviewsFilter) ([]models.Review, error)
+	CountForRestaurant(filter stores.ReviewsFilter) (int64, error)
+	Search(restaurantId, query string, top, skip uint64) ([]models.ReviewSearchResult, error)
 	GetById(id string) (*models.Review, error)
 	Update(review *models.Review) error
+	Delete(id string) error
 }
 
 var (
@@ -44,9 +48,22 @@ func (rs *reviewsService) GetById(id string) (*models.Review, error) {
 
 func (rs *reviewsService) Update(review *models.Review) error {
 	err := rs.db.Reviews().Update(review)
+	if err == db.ErrNotFound {
+		return ErrReviewNotFound
+	}
+
 	return errors.Wrap(err, "could not update review")
 }
 
+func (rs *reviewsService) Delete(id string) error {
+	err := rs.db.Reviews().Delete(id)
+	if err == db.ErrNotFound {
+		return ErrReviewNotFound
+	}
+
+	return errors.Wrap(err, "could not delete review")
+}
+
 func (rs *reviewsService) Create(review *models.Review) error {
 	err := rs.db.Reviews().Insert(review)
 	return errors.Wrap(err, "could not insert review")
@@ -57,11 +74,29 @@ func (rs *reviewsService) HasUserReviewed(userId, restaurantId string) (bool, er
 	return exists, errors.Wrap(err, "could not determine whether review exists")
 }
 
-func (rs *reviewsService) ListForRestaurant(restaurantId string, unanswered bool, top, skip uint64, orderBy string, isAsc bool) ([]models.Review, error) {
-	reviews, err := rs.db.Reviews().ListForRestaurant(restaurantId, unanswered, top, skip, orderBy, isAsc)
+func (rs *reviewsService) ListForRestaurant(filter stores.ReviewsFilter) ([]models.Review, error) {
+	reviews, err := rs.db.Reviews().ListForRestaurant(filter)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot get reviews for restaurant")
 	}
 
 	return reviews, nil
 }
+
+func (rs *reviewsService) CountForRestaurant(filter stores.ReviewsFilter) (int64, error) {
+	count, err := rs.db.Reviews().CountForRestaurant(filter)
+	if err != nil {
+		return 0, errors.Wrap(err, "cannot count reviews for restaurant")
+	}
+
+	return count, nil
+}
+
+func (rs *reviewsService) Search(restaurantId, query string, top, skip uint64) ([]models.ReviewSearchResult, error) {
+	results, err := rs.db.Reviews().Search(restaurantId, query, top, skip)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot search reviews for restaurant")
+	}
+
+	return results, nil
+}
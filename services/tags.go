@@ -0,0 +1,160 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db"
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+)
+
+type TagsService interface {
+	Create(tag *models.Tag) error
+	Update(tag *models.Tag) error
+	GetById(id string) (*models.Tag, error)
+	List() ([]models.Tag, error)
+	Resolve(slugs []string) ([]models.Tag, error)
+	ListForRestaurants(restaurantIds []string) (map[string][]models.Tag, error)
+	Delete(id string) error
+}
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("tag with this slug already exists")
+)
+
+// UnknownTagsError is returned when some of the slugs given to Resolve do not belong to any tag
+type UnknownTagsError struct {
+	Slugs []string
+}
+
+func (e *UnknownTagsError) Error() string {
+	return "unknown tags"
+}
+
+type tagsService struct {
+	db db.Manager
+}
+
+func NewTags(db db.Manager) TagsService {
+	return &tagsService{
+		db: db,
+	}
+}
+
+func (ts *tagsService) Create(tag *models.Tag) error {
+	if err := ts.checkSlugIsFree(tag); err != nil {
+		return err
+	}
+
+	tag.CreatedAt = time.Now().UTC()
+
+	err := ts.db.Tags().Insert(tag)
+	return errors.Wrap(err, "could not insert tag")
+}
+
+func (ts *tagsService) Update(tag *models.Tag) error {
+	if err := ts.checkSlugIsFree(tag); err != nil {
+		return err
+	}
+
+	err := ts.db.Tags().Update(tag)
+	if err == db.ErrNotFound {
+		return ErrTagNotFound
+	}
+
+	return errors.Wrap(err, "could not update tag")
+}
+
+// checkSlugIsFree returns ErrTagExists if another tag has the slug of the given one
+func (ts *tagsService) checkSlugIsFree(tag *models.Tag) error {
+	existing, err := ts.db.Tags().GetBySlug(tag.Slug)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return nil
+		}
+
+		return errors.Wrap(err, "could not get tag by slug")
+	}
+
+	if existing.Id != tag.Id {
+		return ErrTagExists
+	}
+
+	return nil
+}
+
+func (ts *tagsService) GetById(id string) (*models.Tag, error) {
+	tag, err := ts.db.Tags().GetById(id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return nil, ErrTagNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not get tag")
+	}
+
+	return tag, nil
+}
+
+func (ts *tagsService) List() ([]models.Tag, error) {
+	tags, err := ts.db.Tags().List()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get tags")
+	}
+
+	return tags, nil
+}
+
+// Resolve returns the tags with the given slugs. If some of the slugs are unknown, an *UnknownTagsError listing them is returned.
+func (ts *tagsService) Resolve(slugs []string) ([]models.Tag, error) {
+	tags, err := ts.db.Tags().ListBySlugs(slugs)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get tags by slugs")
+	}
+
+	found := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		found[t.Slug] = true
+	}
+
+	unknown := make([]string, 0)
+	for _, s := range slugs {
+		if !found[s] {
+			unknown = append(unknown, s)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, &UnknownTagsError{Slugs: unknown}
+	}
+
+	return tags, nil
+}
+
+// ListForRestaurants returns the tags of several restaurants keyed by the id of the restaurant
+func (ts *tagsService) ListForRestaurants(restaurantIds []string) (map[string][]models.Tag, error) {
+	restaurantTags, err := ts.db.Tags().ListForRestaurants(restaurantIds)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get tags of restaurants")
+	}
+
+	tags := make(map[string][]models.Tag, len(restaurantIds))
+	for _, rt := range restaurantTags {
+		tags[rt.RestaurantId] = append(tags[rt.RestaurantId], rt.Tag)
+	}
+
+	return tags, nil
+}
+
+func (ts *tagsService) Delete(id string) error {
+	err := ts.db.Tags().Delete(id)
+	if err == db.ErrNotFound {
+		return ErrTagNotFound
+	}
+
+	return errors.Wrap(err, "could not delete tag")
+}
@@ -1,21 +1,30 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"time"
 
 	"github.com/dgrijalva/jwt-go/v4"
 	"github.com/pkg/errors"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
 )
 
 type TokensService interface {
 	GenerateSignedToken(req *UserClaims) (string, *Claims, error)
 	ParseSignedToken(tokenStr string) (*UserClaims, error)
+	GenerateRefreshToken(userId string) (string, *models.RefreshToken, error)
+	HashRefreshToken(token string) string
 }
 
 type UserClaims struct {
-	Id   string
-	Role string
+	Id      string
+	Role    string
+	Version int
 }
 
 var (
@@ -23,26 +32,33 @@ var (
 	ErrExpiredToken = errors.New("token has expired")
 )
 
+// The number of random bytes in a refresh token
+const refreshTokenLength = 32
+
 type tokensService struct {
-	validFor   time.Duration
-	signingKey []byte
+	validFor        time.Duration
+	refreshValidFor time.Duration
+	signingKey      []byte
 }
 
-func NewTokensService(validFor time.Duration, signingKey []byte) TokensService {
+func NewTokensService(validFor, refreshValidFor time.Duration, signingKey []byte) TokensService {
 	return &tokensService{
-		validFor:   validFor,
-		signingKey: signingKey,
+		validFor:        validFor,
+		refreshValidFor: refreshValidFor,
+		signingKey:      signingKey,
 	}
 }
 
 type Claims struct {
 	jwt.StandardClaims
-	Role string `json:"role"`
+	Role    string `json:"role"`
+	Version int    `json:"ver"`
 }
 
 func (us *tokensService) GenerateSignedToken(req *UserClaims) (string, *Claims, error) {
 	claims := &Claims{
-		Role: req.Role,
+		Role:    req.Role,
+		Version: req.Version,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: jwt.NewTime(float64(time.Now().Add(us.validFor).Unix())),
 			Subject:   req.Id,
@@ -79,10 +95,37 @@ func (us *tokensService) ParseSignedToken(tokenStr string) (*UserClaims, error)
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
 		return &UserClaims{
-			Id:   claims.Subject,
-			Role: claims.Role,
+			Id:      claims.Subject,
+			Role:    claims.Role,
+			Version: claims.Version,
 		}, nil
 	}
 
 	return nil, ErrInvalidToken
 }
+
+// GenerateRefreshToken returns a new random refresh token for the user together with the record that should be stored in the DB.
+// The record contains only a hash of the token, so the token itself has to be given to the client right away.
+func (us *tokensService) GenerateRefreshToken(userId string) (string, *models.RefreshToken, error) {
+	b := make([]byte, refreshTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, errors.Wrap(err, "could not generate random bytes")
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	now := time.Now().UTC()
+
+	return token, &models.RefreshToken{
+		UserId:      userId,
+		HashedToken: us.HashRefreshToken(token),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(us.refreshValidFor),
+	}, nil
+}
+
+// HashRefreshToken returns the hash under which a refresh token is stored in the DB.
+// Refresh tokens have enough entropy, so a fast hash is sufficient unlike passwords.
+func (us *tokensService) HashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 
 	"github.com/hrist0stoichev/ReviewsSystem/db"
@@ -8,13 +10,22 @@ import (
 )
 
 var (
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound              = errors.New("user not found")
+	ErrInvalidPasswordResetToken = errors.New("invalid password reset token")
+	ErrEmailSentTooRecently      = errors.New("an email was sent to the user too recently")
 )
 
 type UsersService interface {
 	CreateUser(user *models.User) error
 	GetByEmail(email string) (*models.User, error)
+	GetById(id string) (*models.User, error)
+	List(top, skip uint64, emailSearch string) ([]models.User, error)
 	ConfirmEmail(id string) error
+	SetEmailConfirmationToken(id, token string, createdAt time.Time, minInterval time.Duration) error
+	SetPasswordResetToken(id, token string, expiresAt time.Time, minInterval time.Duration) error
+	ResetPassword(id, token, hashedPassword string) error
+	Update(user *models.User) error
+	Delete(id string) (*models.DeletedUploads, error)
 }
 
 type usersService struct {
@@ -49,3 +60,91 @@ func (us *usersService) ConfirmEmail(id string) error {
 	err := us.db.Users().ConfirmEmail(id)
 	return errors.Wrap(err, "could not confirm email")
 }
+
+func (us *usersService) GetById(id string) (*models.User, error) {
+	user, err := us.db.Users().GetById(id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return nil, ErrUserNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not get user by id")
+	}
+
+	return user, nil
+}
+
+func (us *usersService) List(top, skip uint64, emailSearch string) ([]models.User, error) {
+	users, err := us.db.Users().List(top, skip, emailSearch)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list users")
+	}
+
+	return users, nil
+}
+
+func (us *usersService) Update(user *models.User) error {
+	err := us.db.Users().Update(user)
+	if err == db.ErrNotFound {
+		return ErrUserNotFound
+	}
+
+	return errors.Wrap(err, "could not update user")
+}
+
+// Delete removes the user together with the restaurants they own and the reviews they have written.
+// The files of the returned images and media are not deleted.
+func (us *usersService) Delete(id string) (*models.DeletedUploads, error) {
+	uploads, err := us.db.Users().Delete(id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return nil, ErrUserNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not delete user")
+	}
+
+	return uploads, nil
+}
+
+// SetEmailConfirmationToken replaces the email confirmation token of the user unless the previous one was created less than
+// minInterval ago, in which case ErrEmailSentTooRecently is returned
+func (us *usersService) SetEmailConfirmationToken(id, token string, createdAt time.Time, minInterval time.Duration) error {
+	set, err := us.db.Users().SetEmailConfirmationToken(id, token, createdAt, createdAt.Add(-minInterval))
+	if err != nil {
+		return errors.Wrap(err, "could not set email confirmation token")
+	}
+
+	if !set {
+		return ErrEmailSentTooRecently
+	}
+
+	return nil
+}
+
+// SetPasswordResetToken replaces the password reset token of the user unless the previous one was issued less than minInterval ago,
+// in which case ErrEmailSentTooRecently is returned. All tokens are valid for the same time, so the previous token was issued
+// early enough if it expires at least minInterval before the new one.
+func (us *usersService) SetPasswordResetToken(id, token string, expiresAt time.Time, minInterval time.Duration) error {
+	set, err := us.db.Users().SetPasswordResetToken(id, token, expiresAt, expiresAt.Add(-minInterval))
+	if err != nil {
+		return errors.Wrap(err, "could not set password reset token")
+	}
+
+	if !set {
+		return ErrEmailSentTooRecently
+	}
+
+	return nil
+}
+
+// ResetPassword changes the password of the user and revokes all of their sessions. ErrInvalidPasswordResetToken is returned
+// if the token is not the valid password reset token of the user, e.g. because it has just been used.
+func (us *usersService) ResetPassword(id, token, hashedPassword string) error {
+	err := us.db.Users().ResetPassword(id, token, hashedPassword)
+	if err == db.ErrNotFound {
+		return ErrInvalidPasswordResetToken
+	}
+
+	return errors.Wrap(err, "could not reset password")
+}
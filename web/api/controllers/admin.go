@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+	"github.com/hrist0stoichev/ReviewsSystem/services"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/middlewares"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/problems"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/transfermodels"
+)
+
+const (
+	CannotChangeOwnAccount = "You cannot change or delete your own account"
+)
+
+// Admin contains the endpoints that are used by admins to manage the users of the system
+type Admin struct {
+	usersService            services.UsersService
+	restaurantImagesService services.RestaurantImagesService
+	reviewMediaService      services.ReviewMediaService
+	baseController
+}
+
+func NewAdmin(usersService services.UsersService, restaurantImagesService services.RestaurantImagesService, reviewMediaService services.ReviewMediaService, logger log.Logger, validator Validator) *Admin {
+	return &Admin{
+		usersService:            usersService,
+		restaurantImagesService: restaurantImagesService,
+		reviewMediaService:      reviewMediaService,
+		baseController: baseController{
+			logger:    logger,
+			validator: validator,
+		},
+	}
+}
+
+func (ac *Admin) ListUsers(res http.ResponseWriter, req *http.Request) {
+	top := ac.parseFloatParam(req, "top", DefaultTop, MinTop, MaxTop)
+	skip := ac.parseFloatParam(req, "skip", DefaultSkip, MinSkip, MaxSkip)
+	email := req.URL.Query().Get("email")
+
+	users, err := ac.usersService.List(uint64(top), uint64(skip), email)
+	if err != nil {
+		ac.logger.WithError(err).Warnln("Cannot list users")
+		ac.internalError(res)
+		return
+	}
+
+	usersResponse := make([]transfermodels.UserResponse, len(users))
+	for i := range users {
+		usersResponse[i] = newUserResponse(&users[i])
+	}
+
+	ac.returnJsonResponse(res, usersResponse)
+}
+
+func (ac *Admin) GetUser(res http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+
+	user, err := ac.usersService.GetById(id)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			ac.notFound(res)
+			return
+		}
+
+		ac.logger.WithError(err).Warnln("Cannot get user by id")
+		ac.internalError(res)
+		return
+	}
+
+	ac.returnJsonResponse(res, newUserResponse(user))
+}
+
+func (ac *Admin) UpdateUser(res http.ResponseWriter, req *http.Request) {
+	updateRequest := transfermodels.UpdateUserRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&updateRequest); err != nil {
+		ac.decodeError(res)
+		return
+	}
+
+	if err := ac.validator.Struct(updateRequest); err != nil {
+		ac.validationError(res, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	id := mux.Vars(req)["id"]
+
+	if !ac.isOtherUser(res, req, id) {
+		return
+	}
+
+	user, err := ac.usersService.GetById(id)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			ac.notFound(res)
+			return
+		}
+
+		ac.logger.WithError(err).Warnln("Cannot get user by id")
+		ac.internalError(res)
+		return
+	}
+
+	if updateRequest.Role != nil {
+		role, roleErr := models.ParseRole(*updateRequest.Role)
+		if roleErr != nil {
+			ac.problem(res, http.StatusUnprocessableEntity, problems.CodeValidationFailed, roleErr.Error())
+			return
+		}
+
+		user.Role = role
+	}
+
+	if updateRequest.Disabled != nil {
+		user.Disabled = *updateRequest.Disabled
+	}
+
+	if err = ac.usersService.Update(user); err != nil {
+		if err == services.ErrUserNotFound {
+			ac.notFound(res)
+			return
+		}
+
+		ac.logger.WithError(err).Warnln("Cannot update user")
+		ac.internalError(res)
+		return
+	}
+
+	ac.returnJsonResponse(res, newUserResponse(user))
+}
+
+// DeleteUser removes the user together with the restaurants they own (including all reviews for them)
+// and the reviews they have written.
+func (ac *Admin) DeleteUser(res http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+
+	if !ac.isOtherUser(res, req, id) {
+		return
+	}
+
+	uploads, err := ac.usersService.Delete(id)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			ac.notFound(res)
+			return
+		}
+
+		ac.logger.WithError(err).Warnln("Cannot delete user")
+		ac.internalError(res)
+		return
+	}
+
+	ac.restaurantImagesService.DeleteFiles(uploads.Images)
+	ac.reviewMediaService.DeleteFiles(uploads.Media)
+	ac.returnJsonResponse(res, transfermodels.UserDeleteResponse{OK: true})
+}
+
+// isOtherUser makes sure that admins cannot lock themselves out by changing or deleting their own account.
+// It writes an error response and returns false if the id belongs to the calling admin.
+func (ac *Admin) isOtherUser(res http.ResponseWriter, req *http.Request, id string) bool {
+	userId, err := middlewares.UserIDFromRequest(req)
+	if err != nil {
+		ac.logger.WithError(err).Warnln("Cannot get user id from request")
+		ac.internalError(res)
+		return false
+	}
+
+	if *userId == id {
+		ac.problem(res, http.StatusConflict, problems.CodeCannotChangeOwnAccount, CannotChangeOwnAccount)
+		return false
+	}
+
+	return true
+}
+
+func newUserResponse(user *models.User) transfermodels.UserResponse {
+	return transfermodels.UserResponse{
+		Id:             user.Id,
+		Email:          user.Email,
+		EmailConfirmed: user.EmailConfirmed,
+		Role:           user.Role.String(),
+		Disabled:       user.Disabled,
+	}
+}
@@ -2,16 +2,33 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
+	"html"
 	"math"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
 	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+	"github.com/hrist0stoichev/ReviewsSystem/services"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/middlewares"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/problems"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/transfermodels"
 )
 
+// ListEnvelopeMediaType is the media type that clients accept in order to get list responses wrapped in transfermodels.ListResponse
+const ListEnvelopeMediaType = "application/vnd.reviewssystem.list.v2+json"
+
 const (
 	ModelDecodeError    = "Could not decode request body"
+	InvalidCursorError  = "Invalid cursor"
 	InternalServerError = "Something went wrong"
+	NotFoundError       = "The requested resource was not found"
+	ValidationFailed    = "The request body is not valid"
 	DefaultTop          = 20
 	MinTop              = 1
 	MaxTop              = 50
@@ -22,6 +39,9 @@ const (
 	MaxRating           = 5
 )
 
+// snippetHighlighter replaces the highlight markers returned by the database with <mark> tags
+var snippetHighlighter = strings.NewReplacer(models.HighlightStart, "<mark>", models.HighlightStop, "</mark>")
+
 type Validator interface {
 	Struct(s interface{}) error
 }
@@ -34,10 +54,57 @@ type baseController struct {
 func (bc *baseController) returnJsonResponse(w http.ResponseWriter, res interface{}) {
 	if err := json.NewEncoder(w).Encode(res); err != nil {
 		bc.logger.WithError(err).Warnln("Could not encode response")
-		http.Error(w, InternalServerError, http.StatusInternalServerError)
+		bc.internalError(w)
 	}
 }
 
+// problem returns an RFC 7807 problem with a machine-readable code and a human-readable detail
+func (bc *baseController) problem(w http.ResponseWriter, status int, code, detail string) {
+	problems.Error(w, status, code, detail)
+}
+
+func (bc *baseController) internalError(w http.ResponseWriter) {
+	problems.Error(w, http.StatusInternalServerError, problems.CodeInternal, InternalServerError)
+}
+
+func (bc *baseController) notFound(w http.ResponseWriter) {
+	problems.Error(w, http.StatusNotFound, problems.CodeNotFound, NotFoundError)
+}
+
+func (bc *baseController) decodeError(w http.ResponseWriter) {
+	problems.Error(w, http.StatusBadRequest, problems.CodeInvalidBody, ModelDecodeError)
+}
+
+// fieldError returns a validation problem for a single field of the request body that could only be checked after the validation,
+// e.g. against the database
+func (bc *baseController) fieldError(w http.ResponseWriter, status int, field, code, message string) {
+	problem := problems.New(status, problems.CodeValidationFailed, ValidationFailed)
+	problem.Errors = []problems.FieldError{{Field: field, Code: code, Message: message}}
+	problems.Write(w, problem)
+}
+
+// validationError returns a problem with the given status listing all fields of the request body that failed validation
+func (bc *baseController) validationError(w http.ResponseWriter, status int, err error) {
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		bc.logger.WithError(err).Warnln("Could not validate request body")
+		bc.internalError(w)
+		return
+	}
+
+	problem := problems.New(status, problems.CodeValidationFailed, ValidationFailed)
+	for _, fe := range validationErrors {
+		problem.Errors = append(problem.Errors, problems.FieldError{
+			Field:   fieldPath(fe),
+			Code:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: validationMessage(fe),
+		})
+	}
+
+	problems.Write(w, problem)
+}
+
 // parseIntParam parses a float parameter from the URI putting it inside the boundary [min, max].
 // If the param cannot be parsed to float, a default value is returned.
 func (bc *baseController) parseFloatParam(req *http.Request, param string, def, min, max float64) float64 {
@@ -56,3 +123,148 @@ func (bc *baseController) parseFloatParam(req *http.Request, param string, def,
 
 	return floatParam
 }
+
+// getRestaurant returns the restaurant from the URI if the user can see it. Owners can see only their own restaurants
+// and only owners and admins can change them.
+func (bc *baseController) getRestaurant(res http.ResponseWriter, req *http.Request, restaurantsService services.RestaurantsService, change bool) (*models.Restaurant, bool) {
+	restaurant, err := restaurantsService.GetSingle(mux.Vars(req)["id"])
+	if err != nil {
+		if err == services.ErrRestaurantNotFound {
+			bc.notFound(res)
+			return nil, false
+		}
+
+		bc.logger.WithError(err).Warnln("Cannot get restaurant")
+		bc.internalError(res)
+		return nil, false
+	}
+
+	userId, err := middlewares.UserIDFromRequest(req)
+	if err != nil {
+		bc.logger.WithError(err).Warnln("Cannot get user id from request")
+		bc.internalError(res)
+		return nil, false
+	}
+
+	userRole, err := middlewares.UserRoleFromRequest(req)
+	if err != nil {
+		bc.logger.WithError(err).Warnln("Cannot get user role from request")
+		bc.internalError(res)
+		return nil, false
+	}
+
+	if (*userRole == models.Owner || (change && *userRole != models.Admin)) && restaurant.OwnerId != *userId {
+		bc.notFound(res)
+		return nil, false
+	}
+
+	return restaurant, true
+}
+
+// loadTags sets the tags of several restaurants using a single query
+func (bc *baseController) loadTags(res http.ResponseWriter, tagsService services.TagsService, restaurants []*models.Restaurant) bool {
+	ids := make([]string, len(restaurants))
+	for i, r := range restaurants {
+		ids[i] = r.Id
+	}
+
+	tags, err := tagsService.ListForRestaurants(ids)
+	if err != nil {
+		bc.logger.WithError(err).Warnln("Cannot get tags of restaurants")
+		bc.internalError(res)
+		return false
+	}
+
+	for _, r := range restaurants {
+		r.Tags = tags[r.Id]
+	}
+
+	return true
+}
+
+// loadFavorites sets whether the restaurants are favorites of the user using a single query
+func (bc *baseController) loadFavorites(res http.ResponseWriter, favoritesService services.FavoritesService, userId string, restaurants []*models.Restaurant) bool {
+	ids := make([]string, len(restaurants))
+	for i, r := range restaurants {
+		ids[i] = r.Id
+	}
+
+	favorites, err := favoritesService.FavoriteIds(userId, ids)
+	if err != nil {
+		bc.logger.WithError(err).Warnln("Cannot get favorites of user")
+		bc.internalError(res)
+		return false
+	}
+
+	for _, r := range restaurants {
+		r.IsFavorite = favorites[r.Id]
+	}
+
+	return true
+}
+
+// highlightSnippet escapes a search snippet, so that it can be safely rendered as HTML, and marks the matched words with <mark> tags
+func highlightSnippet(snippet string) string {
+	return snippetHighlighter.Replace(html.EscapeString(snippet))
+}
+
+// cursorFromRequest returns the cursor query parameter and whether the client paginates with cursors.
+// An empty cursor requests the first page.
+func cursorFromRequest(req *http.Request) (string, bool) {
+	values, ok := req.URL.Query()["cursor"]
+	if !ok {
+		return "", false
+	}
+
+	return values[0], true
+}
+
+// wantsListEnvelope checks whether the client opted in for list responses wrapped in an envelope
+func wantsListEnvelope(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Accept"), ListEnvelopeMediaType)
+}
+
+// returnListResponse wraps the items of a page in a list envelope and adds Link headers (RFC 8288) to the previous and the next pages.
+// The facets are optional and omitted when nil.
+func (bc *baseController) returnListResponse(w http.ResponseWriter, req *http.Request, items interface{}, total int64, top, skip uint64, facets interface{}) {
+	if skip > 0 {
+		prevSkip := uint64(0)
+		if skip > top {
+			prevSkip = skip - top
+		}
+
+		addLinkHeader(w, "prev", pageLink(req, map[string]string{"skip": strconv.FormatUint(prevSkip, 10)}))
+	}
+
+	if skip+top < uint64(total) {
+		addLinkHeader(w, "next", pageLink(req, map[string]string{"skip": strconv.FormatUint(skip+top, 10)}))
+	}
+
+	w.Header().Set("Content-Type", ListEnvelopeMediaType)
+	w.Header().Add("Vary", "Accept")
+
+	bc.returnJsonResponse(w, transfermodels.ListResponse{
+		Items:  items,
+		Total:  total,
+		Top:    top,
+		Skip:   skip,
+		Facets: facets,
+	})
+}
+
+// pageLink returns the URI of the request with some of its query parameters replaced
+func pageLink(req *http.Request, params map[string]string) string {
+	link := *req.URL
+
+	query := link.Query()
+	for k, v := range params {
+		query.Set(k, v)
+	}
+
+	link.RawQuery = query.Encode()
+	return link.RequestURI()
+}
+
+func addLinkHeader(w http.ResponseWriter, rel, link string) {
+	w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="%s"`, link, rel))
+}
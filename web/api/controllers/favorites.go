@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+	"github.com/hrist0stoichev/ReviewsSystem/services"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/middlewares"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/transfermodels"
+)
+
+// Favorites contains the endpoints for managing the restaurants that the calling user has saved, e.g. to try them later
+type Favorites struct {
+	favoritesService services.FavoritesService
+	tagsService      services.TagsService
+	baseController
+}
+
+func NewFavorites(favoritesService services.FavoritesService, tagsService services.TagsService, logger log.Logger, validator Validator) *Favorites {
+	return &Favorites{
+		favoritesService: favoritesService,
+		tagsService:      tagsService,
+		baseController: baseController{
+			logger:    logger,
+			validator: validator,
+		},
+	}
+}
+
+// Add saves a restaurant as a favorite of the user. Adding a favorite again succeeds as well.
+func (fc *Favorites) Add(res http.ResponseWriter, req *http.Request) {
+	userId, err := middlewares.UserIDFromRequest(req)
+	if err != nil {
+		fc.logger.WithError(err).Warnln("Cannot get user id from request")
+		fc.internalError(res)
+		return
+	}
+
+	restaurantId := mux.Vars(req)["restaurantId"]
+
+	if err = fc.favoritesService.Add(*userId, restaurantId); err != nil {
+		if err == services.ErrRestaurantNotFound {
+			fc.notFound(res)
+			return
+		}
+
+		fc.logger.WithError(err).Warnln("Cannot add favorite")
+		fc.internalError(res)
+		return
+	}
+
+	fc.returnJsonResponse(res, transfermodels.FavoriteResponse{RestaurantId: restaurantId, IsFavorite: true})
+}
+
+// Remove removes a restaurant from the favorites of the user. Removing a restaurant that is not a favorite succeeds as well.
+func (fc *Favorites) Remove(res http.ResponseWriter, req *http.Request) {
+	userId, err := middlewares.UserIDFromRequest(req)
+	if err != nil {
+		fc.logger.WithError(err).Warnln("Cannot get user id from request")
+		fc.internalError(res)
+		return
+	}
+
+	restaurantId := mux.Vars(req)["restaurantId"]
+
+	if err = fc.favoritesService.Remove(*userId, restaurantId); err != nil {
+		fc.logger.WithError(err).Warnln("Cannot remove favorite")
+		fc.internalError(res)
+		return
+	}
+
+	fc.returnJsonResponse(res, transfermodels.FavoriteResponse{RestaurantId: restaurantId, IsFavorite: false})
+}
+
+// List returns the favorite restaurants of the user, the most recently saved first
+func (fc *Favorites) List(res http.ResponseWriter, req *http.Request) {
+	top := fc.parseFloatParam(req, "top", DefaultTop, MinTop, MaxTop)
+	skip := fc.parseFloatParam(req, "skip", DefaultSkip, MinSkip, MaxSkip)
+
+	userId, err := middlewares.UserIDFromRequest(req)
+	if err != nil {
+		fc.logger.WithError(err).Warnln("Cannot get user id from request")
+		fc.internalError(res)
+		return
+	}
+
+	favorites, err := fc.favoritesService.List(*userId, uint64(top), uint64(skip))
+	if err != nil {
+		fc.logger.WithError(err).Warnln("Cannot list favorites")
+		fc.internalError(res)
+		return
+	}
+
+	restaurantPtrs := make([]*models.Restaurant, len(favorites))
+	for i := range favorites {
+		favorites[i].Restaurant.IsFavorite = true
+		restaurantPtrs[i] = &favorites[i].Restaurant
+	}
+
+	if !fc.loadTags(res, fc.tagsService, restaurantPtrs) {
+		return
+	}
+
+	favoritesResponse := make([]transfermodels.RestaurantFavoriteResponse, len(favorites))
+	for i := range favorites {
+		favoritesResponse[i] = transfermodels.RestaurantFavoriteResponse{
+			RestaurantSimpleResponse: newRestaurantSimpleResponse(&favorites[i].Restaurant),
+			FavoritedAt:              favorites[i].FavoritedAt,
+		}
+	}
+
+	if wantsListEnvelope(req) {
+		total, err := fc.favoritesService.Count(*userId)
+		if err != nil {
+			fc.logger.WithError(err).Warnln("Cannot count favorites")
+			fc.internalError(res)
+			return
+		}
+
+		fc.returnListResponse(res, req, favoritesResponse, total, uint64(top), uint64(skip), nil)
+		return
+	}
+
+	fc.returnJsonResponse(res, favoritesResponse)
+}
@@ -0,0 +1,259 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+	"github.com/hrist0stoichev/ReviewsSystem/services"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/transfermodels"
+)
+
+const (
+	TimeOfDayLayout    = "15:04"
+	DateLayout         = "2006-01-02"
+	InvalidTimeZoneMsg = "must be a valid IANA time zone, e.g. Europe/Sofia"
+)
+
+// weekdays maps the names of the days used by the API to their weekdays
+var weekdays = newWeekdays()
+
+func newWeekdays() map[string]time.Weekday {
+	days := make(map[string]time.Weekday, 7)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		days[strings.ToLower(d.String())] = d
+	}
+
+	return days
+}
+
+// OpeningHours contains the endpoints for managing the weekly opening hours and the schedule exceptions of restaurants
+type OpeningHours struct {
+	openingHoursService services.OpeningHoursService
+	restaurantsService  services.RestaurantsService
+	baseController
+}
+
+func NewOpeningHours(openingHoursService services.OpeningHoursService, restaurantsService services.RestaurantsService, logger log.Logger, validator Validator) *OpeningHours {
+	return &OpeningHours{
+		openingHoursService: openingHoursService,
+		restaurantsService:  restaurantsService,
+		baseController: baseController{
+			logger:    logger,
+			validator: validator,
+		},
+	}
+}
+
+// Get returns the time zone, the weekly opening hours and the schedule exceptions of a restaurant
+func (ohc *OpeningHours) Get(res http.ResponseWriter, req *http.Request) {
+	restaurant, ok := ohc.getRestaurant(res, req, ohc.restaurantsService, false)
+	if !ok {
+		return
+	}
+
+	ohc.returnOpeningHours(res, restaurant)
+}
+
+// Set replaces the weekly opening hours of a restaurant and sets its time zone. The schedule exceptions are not changed.
+func (ohc *OpeningHours) Set(res http.ResponseWriter, req *http.Request) {
+	hoursRequest := transfermodels.SetOpeningHoursRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&hoursRequest); err != nil {
+		ohc.decodeError(res)
+		return
+	}
+
+	if err := ohc.validator.Struct(hoursRequest); err != nil {
+		ohc.validationError(res, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	restaurant, ok := ohc.getRestaurant(res, req, ohc.restaurantsService, true)
+	if !ok {
+		return
+	}
+
+	hours := make([]models.OpeningHours, len(hoursRequest.Hours))
+	for i, h := range hoursRequest.Hours {
+		hours[i] = models.OpeningHours{
+			Weekday:      weekdays[h.Day],
+			OpensMinute:  parseTimeOfDay(h.Opens),
+			ClosesMinute: parseTimeOfDay(h.Closes),
+		}
+	}
+
+	if err := ohc.openingHoursService.Set(restaurant, hoursRequest.TimeZone, hours); err != nil {
+		if err == services.ErrInvalidTimeZone {
+			ohc.fieldError(res, http.StatusUnprocessableEntity, "time_zone", "timezone", InvalidTimeZoneMsg)
+			return
+		}
+
+		if err == services.ErrRestaurantNotFound {
+			ohc.notFound(res)
+			return
+		}
+
+		ohc.logger.WithError(err).Warnln("Cannot set opening hours")
+		ohc.internalError(res)
+		return
+	}
+
+	ohc.returnOpeningHours(res, restaurant)
+}
+
+// AddException adds a schedule exception to a restaurant, which replaces its weekly opening hours on the dates of the exception
+func (ohc *OpeningHours) AddException(res http.ResponseWriter, req *http.Request) {
+	exceptionRequest := transfermodels.CreateScheduleExceptionRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&exceptionRequest); err != nil {
+		ohc.decodeError(res)
+		return
+	}
+
+	if err := ohc.validator.Struct(exceptionRequest); err != nil {
+		ohc.validationError(res, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	restaurant, ok := ohc.getRestaurant(res, req, ohc.restaurantsService, true)
+	if !ok {
+		return
+	}
+
+	if exceptionRequest.EndDate == "" {
+		exceptionRequest.EndDate = exceptionRequest.StartDate
+	}
+
+	exception := models.ScheduleException{
+		RestaurantId: restaurant.Id,
+		StartDate:    parseDate(exceptionRequest.StartDate),
+		EndDate:      parseDate(exceptionRequest.EndDate),
+		Reason:       exceptionRequest.Reason,
+	}
+
+	if exceptionRequest.Opens != "" {
+		opens, closes := parseTimeOfDay(exceptionRequest.Opens), parseTimeOfDay(exceptionRequest.Closes)
+		exception.OpensMinute = &opens
+		exception.ClosesMinute = &closes
+	}
+
+	if err := ohc.openingHoursService.AddException(&exception); err != nil {
+		ohc.logger.WithError(err).Warnln("Cannot add schedule exception")
+		ohc.internalError(res)
+		return
+	}
+
+	res.WriteHeader(http.StatusCreated)
+	ohc.returnJsonResponse(res, newScheduleExceptionResponse(&exception))
+}
+
+func (ohc *OpeningHours) DeleteException(res http.ResponseWriter, req *http.Request) {
+	restaurant, ok := ohc.getRestaurant(res, req, ohc.restaurantsService, true)
+	if !ok {
+		return
+	}
+
+	exception, err := ohc.openingHoursService.GetException(mux.Vars(req)["exceptionId"])
+	if err != nil {
+		if err == services.ErrScheduleExceptionNotFound {
+			ohc.notFound(res)
+			return
+		}
+
+		ohc.logger.WithError(err).Warnln("Cannot get schedule exception")
+		ohc.internalError(res)
+		return
+	}
+
+	if exception.RestaurantId != restaurant.Id {
+		ohc.notFound(res)
+		return
+	}
+
+	if err = ohc.openingHoursService.DeleteException(exception.Id); err != nil {
+		if err == services.ErrScheduleExceptionNotFound {
+			ohc.notFound(res)
+			return
+		}
+
+		ohc.logger.WithError(err).Warnln("Cannot delete schedule exception")
+		ohc.internalError(res)
+		return
+	}
+
+	ohc.returnJsonResponse(res, transfermodels.ScheduleExceptionDeleteResponse{OK: true})
+}
+
+func (ohc *OpeningHours) returnOpeningHours(res http.ResponseWriter, restaurant *models.Restaurant) {
+	hours, err := ohc.openingHoursService.List(restaurant.Id)
+	if err != nil {
+		ohc.logger.WithError(err).Warnln("Cannot get opening hours")
+		ohc.internalError(res)
+		return
+	}
+
+	exceptions, err := ohc.openingHoursService.ListExceptions(restaurant.Id)
+	if err != nil {
+		ohc.logger.WithError(err).Warnln("Cannot get schedule exceptions")
+		ohc.internalError(res)
+		return
+	}
+
+	hoursResponse := transfermodels.OpeningHoursResponse{
+		TimeZone:   restaurant.TimeZone,
+		Hours:      make([]transfermodels.OpeningHoursInterval, len(hours)),
+		Exceptions: make([]transfermodels.ScheduleExceptionResponse, len(exceptions)),
+	}
+
+	for i, h := range hours {
+		hoursResponse.Hours[i] = transfermodels.OpeningHoursInterval{
+			Day:    strings.ToLower(h.Weekday.String()),
+			Opens:  formatTimeOfDay(h.OpensMinute),
+			Closes: formatTimeOfDay(h.ClosesMinute),
+		}
+	}
+
+	for i := range exceptions {
+		hoursResponse.Exceptions[i] = newScheduleExceptionResponse(&exceptions[i])
+	}
+
+	ohc.returnJsonResponse(res, hoursResponse)
+}
+
+func newScheduleExceptionResponse(exception *models.ScheduleException) transfermodels.ScheduleExceptionResponse {
+	exceptionResponse := transfermodels.ScheduleExceptionResponse{
+		Id:        exception.Id,
+		StartDate: exception.StartDate.Format(DateLayout),
+		EndDate:   exception.EndDate.Format(DateLayout),
+		Reason:    exception.Reason,
+	}
+
+	if exception.OpensMinute != nil && exception.ClosesMinute != nil {
+		opens, closes := formatTimeOfDay(*exception.OpensMinute), formatTimeOfDay(*exception.ClosesMinute)
+		exceptionResponse.Opens = &opens
+		exceptionResponse.Closes = &closes
+	}
+
+	return exceptionResponse
+}
+
+// parseTimeOfDay returns the minutes from midnight of a time in the HH:MM format. The time has to be validated beforehand.
+func parseTimeOfDay(value string) int {
+	t, _ := time.Parse(TimeOfDayLayout, value)
+	return t.Hour()*60 + t.Minute()
+}
+
+func formatTimeOfDay(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// parseDate returns a date in the YYYY-MM-DD format. The date has to be validated beforehand.
+func parseDate(value string) time.Time {
+	date, _ := time.Parse(DateLayout, value)
+	return date
+}
@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+	"github.com/hrist0stoichev/ReviewsSystem/services"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/problems"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/transfermodels"
+)
+
+const (
+	CoverFormField     = "cover"
+	TooManyImagesError = "The restaurant cannot have more images"
+	ImageCacheMaxAge   = 365 * 24 * 60 * 60
+)
+
+// RestaurantImages contains the endpoints for uploading and managing the photos of restaurants
+type RestaurantImages struct {
+	restaurantImagesService services.RestaurantImagesService
+	restaurantsService      services.RestaurantsService
+	maxSize                 int64
+	baseController
+}
+
+func NewRestaurantImages(restaurantImagesService services.RestaurantImagesService, restaurantsService services.RestaurantsService, maxSize int64, logger log.Logger, validator Validator) *RestaurantImages {
+	return &RestaurantImages{
+		restaurantImagesService: restaurantImagesService,
+		restaurantsService:      restaurantsService,
+		maxSize:                 maxSize,
+		baseController: baseController{
+			logger:    logger,
+			validator: validator,
+		},
+	}
+}
+
+// Upload adds an image to a restaurant. The image is sent in the image field of a multipart form
+// and becomes the cover image of the restaurant if the cover field is true.
+func (ric *RestaurantImages) Upload(res http.ResponseWriter, req *http.Request) {
+	restaurant, ok := ric.getRestaurant(res, req, ric.restaurantsService, true)
+	if !ok {
+		return
+	}
+
+	data, ok := ric.readImageUpload(res, req, ric.maxSize)
+	if !ok {
+		return
+	}
+
+	cover, _ := strconv.ParseBool(req.FormValue(CoverFormField))
+
+	image, err := ric.restaurantImagesService.Upload(restaurant.Id, data, cover)
+	if err != nil {
+		if ric.imageError(res, err, ric.maxSize) {
+			return
+		}
+
+		if err == services.ErrTooManyImages {
+			ric.problem(res, http.StatusConflict, problems.CodeTooManyImages, TooManyImagesError)
+			return
+		}
+
+		ric.logger.WithError(err).Warnln("Cannot upload restaurant image")
+		ric.internalError(res)
+		return
+	}
+
+	res.WriteHeader(http.StatusCreated)
+	ric.returnJsonResponse(res, newRestaurantImageResponse(image, ric.restaurantImagesService))
+}
+
+func (ric *RestaurantImages) List(res http.ResponseWriter, req *http.Request) {
+	restaurant, ok := ric.getRestaurant(res, req, ric.restaurantsService, false)
+	if !ok {
+		return
+	}
+
+	images, err := ric.restaurantImagesService.List(restaurant.Id)
+	if err != nil {
+		ric.logger.WithError(err).Warnln("Cannot list restaurant images")
+		ric.internalError(res)
+		return
+	}
+
+	ric.returnJsonResponse(res, newRestaurantImageResponses(images, ric.restaurantImagesService))
+}
+
+// SetCover makes the image the cover image of the restaurant, which is also used as the img of the restaurant
+func (ric *RestaurantImages) SetCover(res http.ResponseWriter, req *http.Request) {
+	image, ok := ric.getImage(res, req)
+	if !ok {
+		return
+	}
+
+	if err := ric.restaurantImagesService.SetCover(image); err != nil {
+		ric.logger.WithError(err).Warnln("Cannot set cover image")
+		ric.internalError(res)
+		return
+	}
+
+	ric.returnJsonResponse(res, newRestaurantImageResponse(image, ric.restaurantImagesService))
+}
+
+func (ric *RestaurantImages) Delete(res http.ResponseWriter, req *http.Request) {
+	image, ok := ric.getImage(res, req)
+	if !ok {
+		return
+	}
+
+	if err := ric.restaurantImagesService.Delete(image); err != nil {
+		if err == services.ErrImageNotFound {
+			ric.notFound(res)
+			return
+		}
+
+		ric.logger.WithError(err).Warnln("Cannot delete restaurant image")
+		ric.internalError(res)
+		return
+	}
+
+	ric.returnJsonResponse(res, transfermodels.RestaurantImageDeleteResponse{OK: true})
+}
+
+// Serve returns an image file. The files are never changed, because every upload gets a new key, so they can be cached forever.
+func (ric *RestaurantImages) Serve(res http.ResponseWriter, req *http.Request) {
+	key := mux.Vars(req)["key"]
+
+	file, err := ric.restaurantImagesService.OpenFile(key)
+	if err != nil {
+		if err == services.ErrFileNotFound || err == services.ErrInvalidKey {
+			ric.notFound(res)
+			return
+		}
+
+		ric.logger.WithError(err).Warnln("Cannot open image file")
+		ric.internalError(res)
+		return
+	}
+	defer file.Close()
+
+	contentType, err := services.ImageContentType(key)
+	if err != nil {
+		ric.notFound(res)
+		return
+	}
+
+	res.Header().Set("Content-Type", contentType)
+	res.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", ImageCacheMaxAge))
+	res.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// Range and conditional requests are only supported by storages whose files can be seeked
+	if content, ok := file.ReadCloser.(io.ReadSeeker); ok {
+		http.ServeContent(res, req, path.Base(key), file.ModTime, content)
+		return
+	}
+
+	res.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+	res.Header().Set("Last-Modified", file.ModTime.UTC().Format(http.TimeFormat))
+	if req.Method == http.MethodHead {
+		return
+	}
+
+	if _, err = io.Copy(res, file); err != nil {
+		ric.logger.WithError(err).Warnln("Cannot write image file")
+	}
+}
+
+// getImage returns the image from the URI if it belongs to the restaurant from the URI and the user can change it
+func (ric *RestaurantImages) getImage(res http.ResponseWriter, req *http.Request) (*models.RestaurantImage, bool) {
+	restaurant, ok := ric.getRestaurant(res, req, ric.restaurantsService, true)
+	if !ok {
+		return nil, false
+	}
+
+	image, err := ric.restaurantImagesService.GetById(mux.Vars(req)["imageId"])
+	if err != nil {
+		if err == services.ErrImageNotFound {
+			ric.notFound(res)
+			return nil, false
+		}
+
+		ric.logger.WithError(err).Warnln("Cannot get restaurant image")
+		ric.internalError(res)
+		return nil, false
+	}
+
+	if image.RestaurantId != restaurant.Id {
+		ric.notFound(res)
+		return nil, false
+	}
+
+	return image, true
+}
+
+func newRestaurantImageResponse(image *models.RestaurantImage, imagesService services.RestaurantImagesService) transfermodels.RestaurantImageResponse {
+	return transfermodels.RestaurantImageResponse{
+		Id:           image.Id,
+		URL:          imagesService.URL(image.StorageKey),
+		ThumbnailURL: imagesService.URL(image.ThumbnailKey),
+		Width:        image.Width,
+		Height:       image.Height,
+		IsCover:      image.IsCover,
+	}
+}
+
+func newRestaurantImageResponses(images []models.RestaurantImage, imagesService services.RestaurantImagesService) []transfermodels.RestaurantImageResponse {
+	responses := make([]transfermodels.RestaurantImageResponse, len(images))
+	for i := range images {
+		responses[i] = newRestaurantImageResponse(&images[i], imagesService)
+	}
+
+	return responses
+}
@@ -3,25 +3,71 @@ package controllers
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/db/stores"
 	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
 	"github.com/hrist0stoichev/ReviewsSystem/services"
 	"github.com/hrist0stoichev/ReviewsSystem/web/api/middlewares"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/problems"
 	"github.com/hrist0stoichev/ReviewsSystem/web/api/transfermodels"
 )
 
+const (
+	DefaultRadiusKm = 5
+	MinRadiusKm     = 0.1
+	MaxRadiusKm     = 100
+)
+
 type Restaurants struct {
-	restaurantsService services.RestaurantsService
+	restaurantsService      services.RestaurantsService
+	restaurantImagesService services.RestaurantImagesService
+	reviewMediaService      services.ReviewMediaService
+	tagsService             services.TagsService
+	favoritesService        services.FavoritesService
+	cursorsService          services.CursorsService
 	baseController
 }
 
-func NewRestaurant(restaurantsService services.RestaurantsService, logger log.Logger, validator Validator) *Restaurants {
+// restaurantsSortOptions are the keys that restaurants can be ordered by. Restaurants are ordered by rating by default.
+var restaurantsSortOptions = newRestaurantsSortOptions()
+
+func newRestaurantsSortOptions() []sortOption {
+	options := make([]sortOption, len(stores.RestaurantsSortKeys))
+	for i, k := range stores.RestaurantsSortKeys {
+		options[i] = sortOption{
+			key:        string(k),
+			defaultAsc: k.DefaultAscending(),
+		}
+	}
+
+	return options
+}
+
+// restaurantsCursor is the position encoded in the cursors of the restaurants listing.
+// The ordering is part of it, so that all pages are ordered in the same way.
+type restaurantsCursor struct {
+	SortBy  stores.RestaurantsSortKey `json:"sortBy"`
+	SortAsc bool                      `json:"sortAsc"`
+	Value   string                    `json:"value"`
+	Id      string                    `json:"id"`
+}
+
+func NewRestaurant(restaurantsService services.RestaurantsService, restaurantImagesService services.RestaurantImagesService, reviewMediaService services.ReviewMediaService, tagsService services.TagsService, favoritesService services.FavoritesService, cursorsService services.CursorsService, logger log.Logger, validator Validator) *Restaurants {
 	return &Restaurants{
-		restaurantsService: restaurantsService,
+		restaurantsService:      restaurantsService,
+		restaurantImagesService: restaurantImagesService,
+		reviewMediaService:      reviewMediaService,
+		tagsService:             tagsService,
+		favoritesService:        favoritesService,
+		cursorsService:          cursorsService,
 		baseController: baseController{
 			logger:    logger,
 			validator: validator,
@@ -33,6 +79,140 @@ func (rs *Restaurants) ListByRating(res http.ResponseWriter, req *http.Request)
 	top := rs.parseFloatParam(req, "top", DefaultTop, MinTop, MaxTop)
 	skip := rs.parseFloatParam(req, "skip", DefaultSkip, MinSkip, MaxSkip)
 
+	sort, err := parseSortSpec(req, "sortBy", "sortAsc", restaurantsSortOptions)
+	if err != nil {
+		rs.problem(res, http.StatusBadRequest, problems.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	userId, idErr := middlewares.UserIDFromRequest(req)
+	userRole, roleErr := middlewares.UserRoleFromRequest(req)
+
+	if idErr != nil || roleErr != nil {
+		rs.logger.WithError(idErr).WithError(roleErr).Warnln("Cannot get user id or role from the request")
+		rs.internalError(res)
+		return
+	}
+
+	filter, err := rs.parseRestaurantsFilter(req, *userRole)
+	if err != nil {
+		rs.problem(res, http.StatusBadRequest, problems.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	filter.Top = uint64(top)
+	filter.Skip = uint64(skip)
+	filter.SortBy = stores.RestaurantsSortKey(sort.key)
+	filter.SortAsc = sort.asc
+
+	cursor, useCursor := cursorFromRequest(req)
+	if cursor != "" {
+		position := restaurantsCursor{}
+		if err := rs.cursorsService.Decode(cursor, &position); err != nil {
+			rs.problem(res, http.StatusBadRequest, problems.CodeInvalidCursor, InvalidCursorError)
+			return
+		}
+
+		filter.SortBy = position.SortBy
+		filter.SortAsc = position.SortAsc
+		filter.After = &stores.RestaurantsKeyset{
+			SortBy: position.SortBy,
+			Value:  position.Value,
+			Id:     position.Id,
+		}
+	}
+
+	// Get one more restaurant to find out if there is a next page
+	if useCursor {
+		filter.Top++
+	}
+
+	restaurants, err := rs.restaurantsService.List(filter, *userId, *userRole)
+	if err != nil {
+		rs.logger.WithError(err).Warnln("could not list restaurants")
+		rs.internalError(res)
+		return
+	}
+
+	var nextCursor *string
+	if useCursor && len(restaurants) > int(top) {
+		restaurants = restaurants[:int(top)]
+		keyset := stores.NewRestaurantsKeyset(filter.SortBy, &restaurants[len(restaurants)-1])
+
+		encoded, err := rs.cursorsService.Encode(restaurantsCursor{
+			SortBy:  filter.SortBy,
+			SortAsc: filter.SortAsc,
+			Value:   keyset.Value,
+			Id:      keyset.Id,
+		})
+		if err != nil {
+			rs.logger.WithError(err).Warnln("could not encode restaurants cursor")
+			rs.internalError(res)
+			return
+		}
+
+		nextCursor = &encoded
+	}
+
+	restaurantPtrs := make([]*models.Restaurant, len(restaurants))
+	for i := range restaurants {
+		restaurantPtrs[i] = &restaurants[i]
+	}
+
+	if !rs.loadListDetails(res, *userId, restaurantPtrs) {
+		return
+	}
+
+	restaurantsResponse := make([]transfermodels.RestaurantSimpleResponse, len(restaurants))
+	for i := range restaurants {
+		restaurantsResponse[i] = newRestaurantSimpleResponse(&restaurants[i])
+	}
+
+	// Facets take two more queries, so they are returned only when asked for. The plain array has no place for them,
+	// so asking for facets also returns the ListResponse envelope.
+	var facetsResponse interface{}
+	if req.URL.Query().Get("facets") == "true" {
+		facets, err := rs.restaurantsService.Facets(filter, *userId, *userRole)
+		if err != nil {
+			rs.logger.WithError(err).Warnln("Cannot get restaurant facets")
+			rs.internalError(res)
+			return
+		}
+
+		facetsResponse = newRestaurantFacetsResponse(facets)
+	}
+
+	if useCursor {
+		if nextCursor != nil {
+			addLinkHeader(res, "next", pageLink(req, map[string]string{"cursor": *nextCursor}))
+		}
+
+		rs.returnJsonResponse(res, transfermodels.CursorPageResponse{
+			Items:      restaurantsResponse,
+			NextCursor: nextCursor,
+			Facets:     facetsResponse,
+		})
+		return
+	}
+
+	if wantsListEnvelope(req) || facetsResponse != nil {
+		total, err := rs.restaurantsService.Count(filter, *userId, *userRole)
+		if err != nil {
+			rs.logger.WithError(err).Warnln("Cannot count restaurants")
+			rs.internalError(res)
+			return
+		}
+
+		rs.returnListResponse(res, req, restaurantsResponse, total, filter.Top, filter.Skip, facetsResponse)
+		return
+	}
+
+	rs.returnJsonResponse(res, restaurantsResponse)
+}
+
+// parseRestaurantsFilter parses the conditions of the restaurants filter that are shared by the listings of restaurants,
+// i.e. everything except the pagination and the ordering. An error is returned if a parameter cannot be ignored when it is not valid.
+func (rs *Restaurants) parseRestaurantsFilter(req *http.Request, userRole models.Role) (stores.RestaurantsFilter, error) {
 	minRating := rs.parseFloatParam(req, "minRating", 0, MinRating, MaxRating)
 	maxRating := rs.parseFloatParam(req, "maxRating", 5, MinRating, MaxRating)
 
@@ -40,36 +220,291 @@ func (rs *Restaurants) ListByRating(res http.ResponseWriter, req *http.Request)
 		minRating = maxRating
 	}
 
+	filter := stores.RestaurantsFilter{
+		MinRating:  float32(minRating),
+		MaxRating:  float32(maxRating),
+		City:       req.URL.Query().Get("city"),
+		CityPrefix: req.URL.Query().Get("cityPrefix"),
+		MinReviews: int(rs.parseFloatParam(req, "minReviews", 0, 0, math.MaxInt32)),
+	}
+
+	for _, dimension := range models.RatingDimensions {
+		minParam, maxParam := SubRatingParams(dimension)
+		if req.URL.Query().Get(minParam) == "" && req.URL.Query().Get(maxParam) == "" {
+			continue
+		}
+
+		subRatingRange := stores.SubRatingRange{
+			Dimension: dimension,
+			Min:       float32(rs.parseFloatParam(req, minParam, 0, MinRating, MaxRating)),
+			Max:       float32(rs.parseFloatParam(req, maxParam, 5, MinRating, MaxRating)),
+		}
+
+		if subRatingRange.Min > subRatingRange.Max {
+			subRatingRange.Min = subRatingRange.Max
+		}
+
+		filter.SubRatings = append(filter.SubRatings, subRatingRange)
+	}
+
+	// Only owners and admins answer reviews, so the filter makes no sense for regular users
+	if userRole != models.Regular {
+		filter.HasUnansweredReviews = req.URL.Query().Get("unanswered") == "true"
+	}
+
+	if priceLevels := req.URL.Query().Get("priceLevel"); priceLevels != "" {
+		for _, p := range strings.Split(priceLevels, ",") {
+			level := parsePriceLevel(strings.TrimSpace(p))
+			if level == nil {
+				return filter, fmt.Errorf("priceLevel must be a comma-separated list of $, $$, $$$ and $$$$")
+			}
+
+			filter.PriceLevels = append(filter.PriceLevels, *level)
+		}
+	}
+
+	if amenities := req.URL.Query().Get("amenities"); amenities != "" {
+		for _, a := range strings.Split(amenities, ",") {
+			amenity := models.Amenity(strings.TrimSpace(a))
+			if !isAmenity(amenity) {
+				return filter, fmt.Errorf("amenities must be a comma-separated list of %s", amenityNames())
+			}
+
+			filter.Amenities = append(filter.Amenities, amenity)
+		}
+	}
+
+	if tags := req.URL.Query().Get("tags"); tags != "" {
+		filter.Tags = parseTagSlugs(tags)
+
+		switch req.URL.Query().Get("tagsMatch") {
+		case "", "any":
+		case "all":
+			filter.MatchAllTags = true
+		default:
+			return filter, fmt.Errorf("tagsMatch must be one of any, all")
+		}
+	}
+
+	if openAt := req.URL.Query().Get("openAt"); openAt != "" {
+		at, err := time.Parse(time.RFC3339, openAt)
+		if err != nil {
+			return filter, fmt.Errorf("openAt must be a time in RFC 3339 format, e.g. 2020-06-01T19:30:00+03:00")
+		}
+
+		filter.OpenAt = &at
+	} else if req.URL.Query().Get("openNow") == "true" {
+		now := time.Now()
+		filter.OpenAt = &now
+	}
+
+	return filter, nil
+}
+
+// parseTagSlugs returns the unique slugs from a comma-separated list. The slugs are lowercase, so the case is ignored.
+func parseTagSlugs(list string) []string {
+	seen := make(map[string]bool)
+	slugs := make([]string, 0)
+
+	for _, s := range strings.Split(list, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s != "" && !seen[s] {
+			seen[s] = true
+			slugs = append(slugs, s)
+		}
+	}
+
+	return slugs
+}
+
+// parsePriceLevel returns the price level of one to four dollar signs, e.g. $$ is 2, or nil if the value is not a price level
+func parsePriceLevel(value string) *int {
+	if value == "" || len(value) > models.MaxPriceLevel || strings.Trim(value, "$") != "" {
+		return nil
+	}
+
+	level := len(value)
+	return &level
+}
+
+// formatPriceLevel returns the price level as dollar signs or nil if the restaurant has no price level
+func formatPriceLevel(level *int) *string {
+	if level == nil {
+		return nil
+	}
+
+	value := strings.Repeat("$", *level)
+	return &value
+}
+
+func isAmenity(amenity models.Amenity) bool {
+	for _, a := range models.AllAmenities {
+		if a == amenity {
+			return true
+		}
+	}
+
+	return false
+}
+
+// amenityNames returns the names of all amenities separated by commas
+func amenityNames() string {
+	names := make([]string, len(models.AllAmenities))
+	for i, a := range models.AllAmenities {
+		names[i] = string(a)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// SubRatingParams returns the names of the query parameters with the range of a dimension, e.g. minFoodRating and maxFoodRating
+func SubRatingParams(dimension models.RatingDimension) (string, string) {
+	name := strings.Title(string(dimension)) + "Rating"
+	return "min" + name, "max" + name
+}
+
+// Nearby returns the restaurants within radiusKm kilometers from the location given by lat and lng, the closest first.
+// It supports the same filters as the listing of restaurants, but restaurants without a location are never returned.
+func (rs *Restaurants) Nearby(res http.ResponseWriter, req *http.Request) {
+	lat, ok := rs.parseCoordinateParam(res, req, "lat", 90)
+	if !ok {
+		return
+	}
+
+	lng, ok := rs.parseCoordinateParam(res, req, "lng", 180)
+	if !ok {
+		return
+	}
+
+	top := rs.parseFloatParam(req, "top", DefaultTop, MinTop, MaxTop)
+	skip := rs.parseFloatParam(req, "skip", DefaultSkip, MinSkip, MaxSkip)
+
 	userId, idErr := middlewares.UserIDFromRequest(req)
 	userRole, roleErr := middlewares.UserRoleFromRequest(req)
 
 	if idErr != nil || roleErr != nil {
 		rs.logger.WithError(idErr).WithError(roleErr).Warnln("Cannot get user id or role from the request")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
 		return
 	}
 
-	restaurants, err := rs.restaurantsService.ListByRating(int(top), int(skip), *userId, *userRole, float32(minRating), float32(maxRating))
+	filter, err := rs.parseRestaurantsFilter(req, *userRole)
 	if err != nil {
-		rs.logger.WithError(err).Warnln("could not list restaurants")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.problem(res, http.StatusBadRequest, problems.CodeInvalidParameter, err.Error())
 		return
 	}
 
-	restaurantsResponse := make([]transfermodels.RestaurantSimpleResponse, len(restaurants))
+	filter.Top = uint64(top)
+	filter.Skip = uint64(skip)
+	filter.Near = &stores.GeoCircle{
+		Latitude:  lat,
+		Longitude: lng,
+		RadiusKm:  rs.parseFloatParam(req, "radiusKm", DefaultRadiusKm, MinRadiusKm, MaxRadiusKm),
+	}
+
+	restaurants, err := rs.restaurantsService.Nearby(filter, *userId, *userRole)
+	if err != nil {
+		rs.logger.WithError(err).Warnln("could not list nearby restaurants")
+		rs.internalError(res)
+		return
+	}
+
+	restaurantPtrs := make([]*models.Restaurant, len(restaurants))
+	for i := range restaurants {
+		restaurantPtrs[i] = &restaurants[i].Restaurant
+	}
+
+	if !rs.loadListDetails(res, *userId, restaurantPtrs) {
+		return
+	}
+
+	nearbyResponse := make([]transfermodels.RestaurantNearbyResponse, len(restaurants))
 	for i, r := range restaurants {
-		restaurantsResponse[i] = transfermodels.RestaurantSimpleResponse{
-			Id:            r.Id,
-			Name:          r.Name,
-			City:          r.City,
-			Address:       r.Address,
-			Img:           r.Img,
-			Description:   r.Description,
-			AverageRating: r.AverageRating,
+		nearbyResponse[i] = transfermodels.RestaurantNearbyResponse{
+			RestaurantSimpleResponse: newRestaurantSimpleResponse(&restaurants[i].Restaurant),
+			DistanceKm:               r.DistanceKm,
 		}
 	}
 
-	rs.returnJsonResponse(res, restaurantsResponse)
+	if wantsListEnvelope(req) {
+		total, err := rs.restaurantsService.Count(filter, *userId, *userRole)
+		if err != nil {
+			rs.logger.WithError(err).Warnln("Cannot count nearby restaurants")
+			rs.internalError(res)
+			return
+		}
+
+		rs.returnListResponse(res, req, nearbyResponse, total, filter.Top, filter.Skip, nil)
+		return
+	}
+
+	rs.returnJsonResponse(res, nearbyResponse)
+}
+
+// parseCoordinateParam parses a required latitude or longitude from the URI. Unlike the other numeric parameters,
+// coordinates are not clamped, because a wrong location would silently return unrelated restaurants.
+func (rs *Restaurants) parseCoordinateParam(res http.ResponseWriter, req *http.Request, param string, limit float64) (float64, bool) {
+	value := req.URL.Query().Get(param)
+	if value == "" {
+		rs.problem(res, http.StatusBadRequest, problems.CodeMissingParameter, fmt.Sprintf("You need to specify %s", param))
+		return 0, false
+	}
+
+	coordinate, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(coordinate) || math.Abs(coordinate) > limit {
+		rs.problem(res, http.StatusBadRequest, problems.CodeInvalidParameter, fmt.Sprintf("%s must be a number between -%v and %v", param, limit, limit))
+		return 0, false
+	}
+
+	return coordinate, true
+}
+
+func (rs *Restaurants) Search(res http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query().Get("q")
+	if query == "" {
+		rs.problem(res, http.StatusBadRequest, problems.CodeMissingParameter, "You need to specify a search query")
+		return
+	}
+
+	city := req.URL.Query().Get("city")
+	top := rs.parseFloatParam(req, "top", DefaultTop, MinTop, MaxTop)
+	skip := rs.parseFloatParam(req, "skip", DefaultSkip, MinSkip, MaxSkip)
+
+	userId, idErr := middlewares.UserIDFromRequest(req)
+	userRole, roleErr := middlewares.UserRoleFromRequest(req)
+
+	if idErr != nil || roleErr != nil {
+		rs.logger.WithError(idErr).WithError(roleErr).Warnln("Cannot get user id or role from the request")
+		rs.internalError(res)
+		return
+	}
+
+	results, err := rs.restaurantsService.Search(query, city, uint64(top), uint64(skip), *userId, *userRole)
+	if err != nil {
+		rs.logger.WithError(err).Warnln("could not search restaurants")
+		rs.internalError(res)
+		return
+	}
+
+	restaurantPtrs := make([]*models.Restaurant, len(results))
+	for i := range results {
+		restaurantPtrs[i] = &results[i].Restaurant
+	}
+
+	if !rs.loadListDetails(res, *userId, restaurantPtrs) {
+		return
+	}
+
+	searchResponse := make([]transfermodels.RestaurantSearchResponse, len(results))
+	for i, r := range results {
+		searchResponse[i] = transfermodels.RestaurantSearchResponse{
+			RestaurantSimpleResponse: newRestaurantSimpleResponse(&results[i].Restaurant),
+			Rank:                     r.Rank,
+			Snippet:                  highlightSnippet(r.Snippet),
+		}
+	}
+
+	rs.returnJsonResponse(res, searchResponse)
 }
 
 func (rs *Restaurants) GetSingle(res http.ResponseWriter, req *http.Request) {
@@ -78,85 +513,58 @@ func (rs *Restaurants) GetSingle(res http.ResponseWriter, req *http.Request) {
 	restaurant, err := rs.restaurantsService.GetSingle(id)
 	if err != nil {
 		if err == services.ErrRestaurantNotFound {
-			http.NotFound(res, req)
+			rs.notFound(res)
 			return
 		}
 
 		rs.logger.WithError(err).Warnln("Cannot get restaurant")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
 		return
 	}
 
 	userId, err := middlewares.UserIDFromRequest(req)
 	if err != nil {
 		rs.logger.WithError(err).Warnln("Cannot get user id from request")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
 		return
 	}
 
 	userRole, err := middlewares.UserRoleFromRequest(req)
 	if err != nil {
 		rs.logger.WithError(err).Warnln("Cannot get user role from request")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
 		return
 	}
 
 	if *userRole == models.Owner && restaurant.OwnerId != *userId {
-		http.NotFound(res, req)
+		rs.notFound(res)
 		return
 	}
 
-	restaurantResponse := transfermodels.RestaurantDetailedResponse{
-		Id:            restaurant.Id,
-		Name:          restaurant.Name,
-		City:          restaurant.City,
-		Address:       restaurant.Address,
-		Img:           restaurant.Img,
-		Description:   restaurant.Description,
-		AverageRating: restaurant.AverageRating,
-	}
-
-	if restaurant.MinReview != nil {
-		restaurantResponse.MinReview = &transfermodels.ReviewSimpleResponse{
-			Id:        restaurant.MinReview.Id,
-			Reviewer:  restaurant.MinReview.Reviewer.Email,
-			Rating:    restaurant.MinReview.Rating,
-			Timestamp: restaurant.MinReview.Timestamp,
-			Comment:   restaurant.MinReview.Comment,
-			Answer:    restaurant.MinReview.Answer,
-		}
-	}
-
-	if restaurant.MaxReview != nil {
-		restaurantResponse.MaxReview = &transfermodels.ReviewSimpleResponse{
-			Id:        restaurant.MaxReview.Id,
-			Reviewer:  restaurant.MaxReview.Reviewer.Email,
-			Rating:    restaurant.MaxReview.Rating,
-			Timestamp: restaurant.MaxReview.Timestamp,
-			Comment:   restaurant.MaxReview.Comment,
-			Answer:    restaurant.MaxReview.Answer,
-		}
-	}
-
-	rs.returnJsonResponse(res, restaurantResponse)
+	rs.returnDetailedResponse(res, *userId, restaurant)
 }
 
 func (rs *Restaurants) Create(res http.ResponseWriter, req *http.Request) {
 	restaurantRequest := transfermodels.CreateRestaurantRequest{}
 	if err := json.NewDecoder(req.Body).Decode(&restaurantRequest); err != nil {
-		http.Error(res, ModelDecodeError, http.StatusBadRequest)
+		rs.decodeError(res)
 		return
 	}
 
 	if err := rs.validator.Struct(restaurantRequest); err != nil {
-		http.Error(res, err.Error(), http.StatusUnprocessableEntity)
+		rs.validationError(res, http.StatusUnprocessableEntity, err)
 		return
 	}
 
 	userId, err := middlewares.UserIDFromRequest(req)
 	if err != nil {
 		rs.logger.WithError(err).Warnln("Cannot get user id from request")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
+		return
+	}
+
+	tags, ok := rs.resolveTags(res, restaurantRequest.Tags)
+	if !ok {
 		return
 	}
 
@@ -167,23 +575,22 @@ func (rs *Restaurants) Create(res http.ResponseWriter, req *http.Request) {
 		Address:     restaurantRequest.Address,
 		Img:         restaurantRequest.Img,
 		Description: restaurantRequest.Description,
+		Latitude:    restaurantRequest.Latitude,
+		Longitude:   restaurantRequest.Longitude,
+		PriceLevel:  parsePriceLevel(restaurantRequest.PriceLevel),
+		Amenities:   models.Amenities(restaurantRequest.Amenities),
+		Tags:        tags,
 	}
 
+	setContactDetails(&restaurant.ContactDetails, &restaurantRequest.ContactDetails)
+
 	if err := rs.restaurantsService.Create(&restaurant); err != nil {
 		rs.logger.WithError(err).Warnln("Could not create restaurant")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
 		return
 	}
 
-	restaurantResponse := transfermodels.RestaurantSimpleResponse{
-		Id:            restaurant.Id,
-		Name:          restaurant.Name,
-		City:          restaurant.City,
-		Address:       restaurant.Address,
-		Img:           restaurant.Img,
-		Description:   restaurant.Description,
-		AverageRating: 0,
-	}
+	restaurantResponse := newRestaurantSimpleResponse(&restaurant)
 
 	res.Header().Add("Location", fmt.Sprintf("%s%s%s/%s", req.URL.Scheme, req.Host, req.URL.Path, restaurant.Id))
 	res.WriteHeader(http.StatusCreated)
@@ -191,26 +598,302 @@ func (rs *Restaurants) Create(res http.ResponseWriter, req *http.Request) {
 	rs.returnJsonResponse(res, restaurantResponse)
 }
 
+// Delete removes the restaurant together with all of its reviews. If the soft query parameter is set to true,
+// the restaurant is only hidden and its reviews are kept.
 func (rs *Restaurants) Delete(res http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	soft := req.URL.Query().Get("soft") == "true"
+
+	if soft {
+		if err := rs.restaurantsService.SoftDelete(id); err != nil {
+			if err == services.ErrRestaurantNotFound {
+				rs.notFound(res)
+				return
+			}
+
+			rs.logger.WithError(err).Warnln("Cannot soft delete restaurant")
+			rs.internalError(res)
+			return
+		}
+
+		rs.returnJsonResponse(res, transfermodels.RestaurantDeleteResponse{OK: true, Soft: true})
+		return
+	}
+
+	deletedReviews, uploads, err := rs.restaurantsService.Delete(id)
+	if err != nil {
+		if err == services.ErrRestaurantNotFound {
+			rs.notFound(res)
+			return
+		}
+
+		rs.logger.WithError(err).Warnln("Cannot delete restaurant")
+		rs.internalError(res)
+		return
+	}
+
+	rs.restaurantImagesService.DeleteFiles(uploads.Images)
+	rs.reviewMediaService.DeleteFiles(uploads.Media)
+	rs.returnJsonResponse(res, transfermodels.RestaurantDeleteResponse{OK: true, DeletedReviews: deletedReviews})
+}
+
+func (rs *Restaurants) Update(res http.ResponseWriter, req *http.Request) {
+	updateRequest := transfermodels.UpdateRestaurantRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&updateRequest); err != nil {
+		rs.decodeError(res)
+		return
+	}
+
+	if err := rs.validator.Struct(updateRequest); err != nil {
+		rs.validationError(res, http.StatusUnprocessableEntity, err)
+		return
+	}
+
 	id := mux.Vars(req)["id"]
 
-	_, err := rs.restaurantsService.GetSingle(id)
+	restaurant, err := rs.restaurantsService.GetSingle(id)
 	if err != nil {
 		if err == services.ErrRestaurantNotFound {
-			http.NotFound(res, req)
+			rs.notFound(res)
 			return
 		}
 
 		rs.logger.WithError(err).Warnln("Cannot get restaurant")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
 		return
 	}
 
-	if err = rs.restaurantsService.Delete(id); err != nil {
-		rs.logger.WithError(err).Warnln("Cannot delete restaurant")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+	userId, err := middlewares.UserIDFromRequest(req)
+	if err != nil {
+		rs.logger.WithError(err).Warnln("Cannot get user id from request")
+		rs.internalError(res)
+		return
+	}
+
+	userRole, err := middlewares.UserRoleFromRequest(req)
+	if err != nil {
+		rs.logger.WithError(err).Warnln("Cannot get user role from request")
+		rs.internalError(res)
+		return
+	}
+
+	if *userRole != models.Admin && restaurant.OwnerId != *userId {
+		rs.notFound(res)
+		return
+	}
+
+	if updateRequest.Name != nil {
+		restaurant.Name = *updateRequest.Name
+	}
+
+	if updateRequest.City != nil {
+		restaurant.City = *updateRequest.City
+	}
+
+	if updateRequest.Address != nil {
+		restaurant.Address = *updateRequest.Address
+	}
+
+	if updateRequest.Img != nil {
+		restaurant.Img = *updateRequest.Img
+	}
+
+	if updateRequest.Description != nil {
+		restaurant.Description = *updateRequest.Description
+	}
+
+	if updateRequest.Latitude != nil {
+		restaurant.Latitude = updateRequest.Latitude
+		restaurant.Longitude = updateRequest.Longitude
+	}
+
+	if updateRequest.Tags != nil {
+		tags, ok := rs.resolveTags(res, *updateRequest.Tags)
+		if !ok {
+			return
+		}
+
+		restaurant.Tags = tags
+	}
+
+	if updateRequest.PriceLevel != nil {
+		restaurant.PriceLevel = parsePriceLevel(*updateRequest.PriceLevel)
+	}
+
+	if updateRequest.Amenities != nil {
+		restaurant.Amenities = models.Amenities(*updateRequest.Amenities)
+	}
+
+	if updateRequest.Phone != nil {
+		restaurant.Phone = *updateRequest.Phone
+	}
+
+	if updateRequest.Website != nil {
+		restaurant.Website = *updateRequest.Website
+	}
+
+	if updateRequest.SocialLinks != nil {
+		setSocialLinks(&restaurant.ContactDetails, updateRequest.SocialLinks)
+	}
+
+	if err = rs.restaurantsService.Update(restaurant); err != nil {
+		if err == services.ErrRestaurantNotFound {
+			rs.notFound(res)
+			return
+		}
+
+		rs.logger.WithError(err).Warnln("Cannot update restaurant")
+		rs.internalError(res)
+		return
+	}
+
+	rs.returnDetailedResponse(res, *userId, restaurant)
+}
+
+// setContactDetails copies the validated contact details of a request to a restaurant
+func setContactDetails(contactDetails *models.ContactDetails, request *transfermodels.ContactDetails) {
+	contactDetails.Phone = request.Phone
+	contactDetails.Website = request.Website
+	setSocialLinks(contactDetails, &request.SocialLinks)
+}
+
+func setSocialLinks(contactDetails *models.ContactDetails, links *transfermodels.SocialLinks) {
+	contactDetails.FacebookUrl = links.Facebook
+	contactDetails.InstagramUrl = links.Instagram
+	contactDetails.TwitterUrl = links.Twitter
+}
+
+// resolveTags returns the tags with the given slugs. If some of them are unknown, a validation problem is returned.
+func (rs *Restaurants) resolveTags(res http.ResponseWriter, slugs []string) ([]models.Tag, bool) {
+	tags, err := rs.tagsService.Resolve(slugs)
+	if err != nil {
+		if unknown, ok := err.(*services.UnknownTagsError); ok {
+			rs.fieldError(res, http.StatusUnprocessableEntity, "tags", "exists", "contains unknown tags: "+strings.Join(unknown.Slugs, ", "))
+			return nil, false
+		}
+
+		rs.logger.WithError(err).Warnln("Cannot resolve tags")
+		rs.internalError(res)
+		return nil, false
+	}
+
+	return tags, true
+}
+
+// loadListDetails sets the tags of the listed restaurants and whether they are favorites of the user
+func (rs *Restaurants) loadListDetails(res http.ResponseWriter, userId string, restaurants []*models.Restaurant) bool {
+	return rs.loadTags(res, rs.tagsService, restaurants) && rs.loadFavorites(res, rs.favoritesService, userId, restaurants)
+}
+
+// returnDetailedResponse returns the restaurant together with its images and the media of its min and max reviews
+func (rs *Restaurants) returnDetailedResponse(res http.ResponseWriter, userId string, restaurant *models.Restaurant) {
+	if !rs.loadFavorites(res, rs.favoritesService, userId, []*models.Restaurant{restaurant}) {
+		return
+	}
+
+	images, err := rs.restaurantImagesService.List(restaurant.Id)
+	if err != nil {
+		rs.logger.WithError(err).Warnln("Cannot list restaurant images")
+		rs.internalError(res)
 		return
 	}
 
-	rs.returnJsonResponse(res, transfermodels.RestaurantDeleteResponse{OK: true})
+	restaurantResponse := newRestaurantDetailedResponse(restaurant)
+	restaurantResponse.Images = newRestaurantImageResponses(images, rs.restaurantImagesService)
+
+	var reviewIds []string
+	for _, review := range []*transfermodels.ReviewSimpleResponse{restaurantResponse.MinReview, restaurantResponse.MaxReview} {
+		if review != nil {
+			reviewIds = append(reviewIds, review.Id)
+		}
+	}
+
+	media, err := rs.reviewMediaService.ListForReviews(reviewIds)
+	if err != nil {
+		rs.logger.WithError(err).Warnln("Cannot get media of reviews")
+		rs.internalError(res)
+		return
+	}
+
+	for _, review := range []*transfermodels.ReviewSimpleResponse{restaurantResponse.MinReview, restaurantResponse.MaxReview} {
+		if review != nil {
+			review.Media = newReviewMediaResponses(media[review.Id], rs.reviewMediaService)
+		}
+	}
+
+	rs.returnJsonResponse(res, restaurantResponse)
+}
+
+func newRestaurantSimpleResponse(restaurant *models.Restaurant) transfermodels.RestaurantSimpleResponse {
+	return transfermodels.RestaurantSimpleResponse{
+		Id:                restaurant.Id,
+		Name:              restaurant.Name,
+		City:              restaurant.City,
+		Address:           restaurant.Address,
+		Img:               restaurant.Img,
+		Description:       restaurant.Description,
+		Latitude:          restaurant.Latitude,
+		Longitude:         restaurant.Longitude,
+		PriceLevel:        formatPriceLevel(restaurant.PriceLevel),
+		AverageRating:     restaurant.AverageRating,
+		SubRatingAverages: transfermodels.SubRatingAverages(restaurant.SubRatingAverages),
+		Tags:              newTagResponses(restaurant.Tags),
+		IsFavorite:        restaurant.IsFavorite,
+	}
+}
+
+// newRestaurantDetailedResponse maps a restaurant, together with its min and max reviews (if any), to a detailed response
+func newRestaurantDetailedResponse(restaurant *models.Restaurant) transfermodels.RestaurantDetailedResponse {
+	restaurantResponse := transfermodels.RestaurantDetailedResponse{
+		Id:          restaurant.Id,
+		Name:        restaurant.Name,
+		City:        restaurant.City,
+		Address:     restaurant.Address,
+		Img:         restaurant.Img,
+		Description: restaurant.Description,
+		Latitude:    restaurant.Latitude,
+		Longitude:   restaurant.Longitude,
+		TimeZone:    restaurant.TimeZone,
+		PriceLevel:  formatPriceLevel(restaurant.PriceLevel),
+		Amenities:   transfermodels.Amenities(restaurant.Amenities),
+		ContactDetails: transfermodels.ContactDetails{
+			Phone:   restaurant.Phone,
+			Website: restaurant.Website,
+			SocialLinks: transfermodels.SocialLinks{
+				Facebook:  restaurant.FacebookUrl,
+				Instagram: restaurant.InstagramUrl,
+				Twitter:   restaurant.TwitterUrl,
+			},
+		},
+		AverageRating:     restaurant.AverageRating,
+		SubRatingAverages: transfermodels.SubRatingAverages(restaurant.SubRatingAverages),
+		Tags:              newTagResponses(restaurant.Tags),
+		IsFavorite:        restaurant.IsFavorite,
+	}
+
+	if restaurant.MinReview != nil {
+		restaurantResponse.MinReview = &transfermodels.ReviewSimpleResponse{
+			Id:         restaurant.MinReview.Id,
+			Reviewer:   restaurant.MinReview.Reviewer.Email,
+			Rating:     restaurant.MinReview.Rating,
+			SubRatings: transfermodels.SubRatings(restaurant.MinReview.SubRatings),
+			Timestamp:  restaurant.MinReview.Timestamp,
+			Comment:    restaurant.MinReview.Comment,
+			Answer:     restaurant.MinReview.Answer,
+		}
+	}
+
+	if restaurant.MaxReview != nil {
+		restaurantResponse.MaxReview = &transfermodels.ReviewSimpleResponse{
+			Id:         restaurant.MaxReview.Id,
+			Reviewer:   restaurant.MaxReview.Reviewer.Email,
+			Rating:     restaurant.MaxReview.Rating,
+			SubRatings: transfermodels.SubRatings(restaurant.MaxReview.SubRatings),
+			Timestamp:  restaurant.MaxReview.Timestamp,
+			Comment:    restaurant.MaxReview.Comment,
+			Answer:     restaurant.MaxReview.Answer,
+		}
+	}
+
+	return restaurantResponse
 }
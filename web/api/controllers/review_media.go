@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+	"github.com/hrist0stoichev/ReviewsSystem/services"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/middlewares"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/problems"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/transfermodels"
+)
+
+const (
+	TooManyReviewMediaError = "The review cannot have more photos"
+)
+
+// ReviewMedia contains the endpoints for attaching photos to reviews
+type ReviewMedia struct {
+	reviewMediaService services.ReviewMediaService
+	reviewsService     services.ReviewsService
+	maxSize            int64
+	baseController
+}
+
+func NewReviewMedia(reviewMediaService services.ReviewMediaService, reviewsService services.ReviewsService, maxSize int64, logger log.Logger, validator Validator) *ReviewMedia {
+	return &ReviewMedia{
+		reviewMediaService: reviewMediaService,
+		reviewsService:     reviewsService,
+		maxSize:            maxSize,
+		baseController: baseController{
+			logger:    logger,
+			validator: validator,
+		},
+	}
+}
+
+// Upload attaches a photo to a review of the user. The photo is sent in the image field of a multipart form.
+func (rmc *ReviewMedia) Upload(res http.ResponseWriter, req *http.Request) {
+	review, ok := rmc.getReview(res, req)
+	if !ok {
+		return
+	}
+
+	data, ok := rmc.readImageUpload(res, req, rmc.maxSize)
+	if !ok {
+		return
+	}
+
+	media, err := rmc.reviewMediaService.Upload(review.Id, data)
+	if err != nil {
+		if rmc.imageError(res, err, rmc.maxSize) {
+			return
+		}
+
+		if err == services.ErrTooManyReviewMedia {
+			rmc.problem(res, http.StatusConflict, problems.CodeTooManyImages, TooManyReviewMediaError)
+			return
+		}
+
+		rmc.logger.WithError(err).Warnln("Cannot upload review media")
+		rmc.internalError(res)
+		return
+	}
+
+	res.WriteHeader(http.StatusCreated)
+	rmc.returnJsonResponse(res, newReviewMediaResponse(media, rmc.reviewMediaService))
+}
+
+// Delete removes a photo from a review. Regular users can remove photos only from their own reviews, while admins can remove any photo.
+func (rmc *ReviewMedia) Delete(res http.ResponseWriter, req *http.Request) {
+	review, ok := rmc.getReview(res, req)
+	if !ok {
+		return
+	}
+
+	media, err := rmc.reviewMediaService.GetById(mux.Vars(req)["mediaId"])
+	if err != nil {
+		if err == services.ErrReviewMediaNotFound {
+			rmc.notFound(res)
+			return
+		}
+
+		rmc.logger.WithError(err).Warnln("Cannot get review media")
+		rmc.internalError(res)
+		return
+	}
+
+	if media.ReviewId != review.Id {
+		rmc.notFound(res)
+		return
+	}
+
+	if err = rmc.reviewMediaService.Delete(media); err != nil {
+		if err == services.ErrReviewMediaNotFound {
+			rmc.notFound(res)
+			return
+		}
+
+		rmc.logger.WithError(err).Warnln("Cannot delete review media")
+		rmc.internalError(res)
+		return
+	}
+
+	rmc.returnJsonResponse(res, transfermodels.ReviewMediaDeleteResponse{OK: true})
+}
+
+// getReview returns the review from the URI if it was written by the user or the user is an admin
+func (rmc *ReviewMedia) getReview(res http.ResponseWriter, req *http.Request) (*models.Review, bool) {
+	review, err := rmc.reviewsService.GetById(mux.Vars(req)["id"])
+	if err != nil {
+		if err == services.ErrReviewNotFound {
+			rmc.notFound(res)
+			return nil, false
+		}
+
+		rmc.logger.WithError(err).Warnln("could not get review by id")
+		rmc.internalError(res)
+		return nil, false
+	}
+
+	userId, err := middlewares.UserIDFromRequest(req)
+	if err != nil {
+		rmc.logger.WithError(err).Warnln("Cannot get user id from request")
+		rmc.internalError(res)
+		return nil, false
+	}
+
+	userRole, err := middlewares.UserRoleFromRequest(req)
+	if err != nil {
+		rmc.logger.WithError(err).Warnln("Cannot get user role from request")
+		rmc.internalError(res)
+		return nil, false
+	}
+
+	if *userRole != models.Admin && review.ReviewerId != *userId {
+		rmc.notFound(res)
+		return nil, false
+	}
+
+	return review, true
+}
+
+func newReviewMediaResponse(media *models.ReviewMedia, mediaService services.ReviewMediaService) transfermodels.ReviewMediaResponse {
+	return transfermodels.ReviewMediaResponse{
+		Id:           media.Id,
+		URL:          mediaService.URL(media.StorageKey),
+		ThumbnailURL: mediaService.URL(media.ThumbnailKey),
+		Width:        media.Width,
+		Height:       media.Height,
+	}
+}
+
+func newReviewMediaResponses(media []models.ReviewMedia, mediaService services.ReviewMediaService) []transfermodels.ReviewMediaResponse {
+	responses := make([]transfermodels.ReviewMediaResponse, len(media))
+	for i := range media {
+		responses[i] = newReviewMediaResponse(&media[i], mediaService)
+	}
+
+	return responses
+}
@@ -9,22 +9,63 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/db/stores"
 	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
 	"github.com/hrist0stoichev/ReviewsSystem/services"
 	"github.com/hrist0stoichev/ReviewsSystem/web/api/middlewares"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/problems"
 	"github.com/hrist0stoichev/ReviewsSystem/web/api/transfermodels"
 )
 
 type Reviews struct {
 	reviewsService     services.ReviewsService
+	reviewMediaService services.ReviewMediaService
 	restaurantsService services.RestaurantsService
+	usersService       services.UsersService
+	emailsService      services.EmailsService
+	cursorsService     services.CursorsService
 	baseController
 }
 
-func NewReviews(reviewsService services.ReviewsService, restaurantsService services.RestaurantsService, logger log.Logger, validator Validator) *Reviews {
+// reviewsSortOptions are the keys that reviews can be ordered by. The newest, best rated and answered reviews come first by default.
+var reviewsSortOptions = newReviewsSortOptions()
+
+func newReviewsSortOptions() []sortOption {
+	options := make([]sortOption, len(stores.ReviewsSortKeys))
+	for i, k := range stores.ReviewsSortKeys {
+		options[i] = sortOption{
+			key: string(k),
+		}
+	}
+
+	return options
+}
+
+// reviewsCursor is the position encoded in the cursors of the reviews listing
+type reviewsCursor struct {
+	RestaurantId string    `json:"restaurantId"`
+	SortAsc      bool      `json:"sortAsc"`
+	Timestamp    time.Time `json:"timestamp"`
+	Id           string    `json:"id"`
+}
+
+func NewReviews(
+	reviewsService services.ReviewsService,
+	reviewMediaService services.ReviewMediaService,
+	restaurantsService services.RestaurantsService,
+	usersService services.UsersService,
+	emailsService services.EmailsService,
+	cursorsService services.CursorsService,
+	logger log.Logger,
+	validator Validator,
+) *Reviews {
 	return &Reviews{
 		reviewsService:     reviewsService,
+		reviewMediaService: reviewMediaService,
 		restaurantsService: restaurantsService,
+		usersService:       usersService,
+		emailsService:      emailsService,
+		cursorsService:     cursorsService,
 		baseController: baseController{
 			logger:    logger,
 			validator: validator,
@@ -35,82 +76,217 @@ func NewReviews(reviewsService services.ReviewsService, restaurantsService servi
 func (rs *Reviews) ListForRestaurant(res http.ResponseWriter, req *http.Request) {
 	restaurantId := req.URL.Query().Get("restaurantId")
 	if restaurantId == "" {
-		http.Error(res, "You need to specify restaurant id", http.StatusBadRequest)
+		rs.problem(res, http.StatusBadRequest, problems.CodeMissingParameter, "You need to specify restaurant id")
 		return
 	}
 
-	orderBy := req.URL.Query().Get("orderBy")
-	if orderBy == "" {
-		orderBy = "timestamp"
+	sort, err := parseSortSpec(req, "orderBy", "orderByAsc", reviewsSortOptions)
+	if err != nil {
+		rs.problem(res, http.StatusBadRequest, problems.CodeInvalidParameter, err.Error())
+		return
 	}
 
 	top := rs.parseFloatParam(req, "top", DefaultTop, MinTop, MaxTop)
 	skip := rs.parseFloatParam(req, "skip", DefaultSkip, MinSkip, MaxSkip)
 	unanswered := req.URL.Query().Get("unanswered") == "true"
-	asc := req.URL.Query().Get("orderByAsc") == "true"
 
-	reviews, err := rs.reviewsService.ListForRestaurant(restaurantId, unanswered, uint64(top), uint64(skip), orderBy, asc)
+	filter := stores.ReviewsFilter{
+		RestaurantId: restaurantId,
+		Unanswered:   unanswered,
+		Top:          uint64(top),
+		Skip:         uint64(skip),
+		SortBy:       stores.ReviewsSortKey(sort.key),
+		SortAsc:      sort.asc,
+	}
+
+	cursor, useCursor := cursorFromRequest(req)
+	if useCursor && filter.SortBy != stores.SortReviewsByTimestamp {
+		rs.problem(res, http.StatusBadRequest, problems.CodeInvalidParameter, "Cursor pagination is supported only when ordering by timestamp")
+		return
+	}
+
+	if cursor != "" {
+		position := reviewsCursor{}
+		if err := rs.cursorsService.Decode(cursor, &position); err != nil || position.RestaurantId != restaurantId {
+			rs.problem(res, http.StatusBadRequest, problems.CodeInvalidCursor, InvalidCursorError)
+			return
+		}
+
+		filter.SortAsc = position.SortAsc
+		filter.After = &stores.ReviewsKeyset{
+			Timestamp: position.Timestamp,
+			Id:        position.Id,
+		}
+	}
+
+	// Get one more review to find out if there is a next page
+	if useCursor {
+		filter.Top++
+	}
+
+	reviews, err := rs.reviewsService.ListForRestaurant(filter)
 	if err != nil {
 		rs.logger.WithError(err).Warnln("Cannot get reviews")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
+		return
+	}
+
+	var nextCursor *string
+	if useCursor && len(reviews) > int(top) {
+		reviews = reviews[:int(top)]
+		last := reviews[len(reviews)-1]
+
+		encoded, err := rs.cursorsService.Encode(reviewsCursor{
+			RestaurantId: restaurantId,
+			SortAsc:      filter.SortAsc,
+			Timestamp:    last.Timestamp,
+			Id:           last.Id,
+		})
+		if err != nil {
+			rs.logger.WithError(err).Warnln("Cannot encode reviews cursor")
+			rs.internalError(res)
+			return
+		}
+
+		nextCursor = &encoded
+	}
+
+	reviewIds := make([]string, len(reviews))
+	for i, r := range reviews {
+		reviewIds[i] = r.Id
+	}
+
+	media, err := rs.reviewMediaService.ListForReviews(reviewIds)
+	if err != nil {
+		rs.logger.WithError(err).Warnln("Cannot get media of reviews")
+		rs.internalError(res)
 		return
 	}
 
 	responseReviews := make([]transfermodels.ReviewSimpleResponse, len(reviews))
 	for i, r := range reviews {
 		responseReviews[i] = transfermodels.ReviewSimpleResponse{
-			Id:        r.Id,
-			Reviewer:  r.Reviewer.Email,
-			Rating:    r.Rating,
-			Timestamp: r.Timestamp,
-			Comment:   r.Comment,
-			Answer:    r.Answer,
+			Id:         r.Id,
+			Reviewer:   r.Reviewer.Email,
+			Rating:     r.Rating,
+			SubRatings: transfermodels.SubRatings(r.SubRatings),
+			Timestamp:  r.Timestamp,
+			Comment:    r.Comment,
+			Answer:     r.Answer,
+			Media:      newReviewMediaResponses(media[r.Id], rs.reviewMediaService),
+		}
+	}
+
+	if useCursor {
+		if nextCursor != nil {
+			addLinkHeader(res, "next", pageLink(req, map[string]string{"cursor": *nextCursor}))
+		}
+
+		rs.returnJsonResponse(res, transfermodels.CursorPageResponse{
+			Items:      responseReviews,
+			NextCursor: nextCursor,
+		})
+		return
+	}
+
+	if wantsListEnvelope(req) {
+		total, err := rs.reviewsService.CountForRestaurant(filter)
+		if err != nil {
+			rs.logger.WithError(err).Warnln("Cannot count reviews")
+			rs.internalError(res)
+			return
 		}
+
+		rs.returnListResponse(res, req, responseReviews, total, filter.Top, filter.Skip, nil)
+		return
 	}
 
 	rs.returnJsonResponse(res, responseReviews)
 }
 
+func (rs *Reviews) Search(res http.ResponseWriter, req *http.Request) {
+	restaurantId := req.URL.Query().Get("restaurantId")
+	if restaurantId == "" {
+		rs.problem(res, http.StatusBadRequest, problems.CodeMissingParameter, "You need to specify restaurant id")
+		return
+	}
+
+	query := req.URL.Query().Get("q")
+	if query == "" {
+		rs.problem(res, http.StatusBadRequest, problems.CodeMissingParameter, "You need to specify a search query")
+		return
+	}
+
+	top := rs.parseFloatParam(req, "top", DefaultTop, MinTop, MaxTop)
+	skip := rs.parseFloatParam(req, "skip", DefaultSkip, MinSkip, MaxSkip)
+
+	results, err := rs.reviewsService.Search(restaurantId, query, uint64(top), uint64(skip))
+	if err != nil {
+		rs.logger.WithError(err).Warnln("Cannot search reviews")
+		rs.internalError(res)
+		return
+	}
+
+	searchResponse := make([]transfermodels.ReviewSearchResponse, len(results))
+	for i, r := range results {
+		searchResponse[i] = transfermodels.ReviewSearchResponse{
+			ReviewSimpleResponse: transfermodels.ReviewSimpleResponse{
+				Id:         r.Review.Id,
+				Reviewer:   r.Review.Reviewer.Email,
+				Rating:     r.Review.Rating,
+				SubRatings: transfermodels.SubRatings(r.Review.SubRatings),
+				Timestamp:  r.Review.Timestamp,
+				Comment:    r.Review.Comment,
+				Answer:     r.Review.Answer,
+			},
+			Rank:    r.Rank,
+			Snippet: highlightSnippet(r.Snippet),
+		}
+	}
+
+	rs.returnJsonResponse(res, searchResponse)
+}
+
 func (rs *Reviews) Create(res http.ResponseWriter, req *http.Request) {
 	reviewRequest := transfermodels.CreateReviewRequest{}
 	if err := json.NewDecoder(req.Body).Decode(&reviewRequest); err != nil {
-		http.Error(res, ModelDecodeError, http.StatusBadRequest)
+		rs.decodeError(res)
 		return
 	}
 
 	if err := rs.validator.Struct(reviewRequest); err != nil {
-		http.Error(res, err.Error(), http.StatusUnprocessableEntity)
+		rs.validationError(res, http.StatusUnprocessableEntity, err)
 		return
 	}
 
 	userId, err := middlewares.UserIDFromRequest(req)
 	if err != nil {
 		rs.logger.WithError(err).Warnln("Cannot get user id from request")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
 		return
 	}
 
 	restaurantExists, err := rs.restaurantsService.Exists(reviewRequest.RestaurantId)
 	if err != nil {
 		rs.logger.WithError(err).Warnln("Cannot determine whether restaurant exists")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
 		return
 	}
 
 	if !restaurantExists {
-		http.NotFound(res, req)
+		rs.notFound(res)
 		return
 	}
 
 	userHasRated, err := rs.reviewsService.HasUserReviewed(*userId, reviewRequest.RestaurantId)
 	if err != nil {
 		rs.logger.WithError(err).Warnln("Cannot determine whether user has rated")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
 		return
 	}
 
 	if userHasRated {
-		http.Error(res, "You have already rated this restaurant", http.StatusConflict)
+		rs.problem(res, http.StatusConflict, problems.CodeAlreadyReviewed, "You have already rated this restaurant")
 		return
 	}
 
@@ -118,21 +294,25 @@ func (rs *Reviews) Create(res http.ResponseWriter, req *http.Request) {
 		RestaurantId: reviewRequest.RestaurantId,
 		ReviewerId:   *userId,
 		Rating:       reviewRequest.Rating,
+		SubRatings:   models.SubRatings(reviewRequest.SubRatings),
 		Timestamp:    time.Now().UTC(),
 		Comment:      reviewRequest.Comment,
 	}
 
 	if err := rs.reviewsService.Create(&review); err != nil {
 		rs.logger.WithError(err).Warnln("Could not create review")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
 		return
 	}
 
+	rs.notifyOwner(&review)
+
 	reviewResponse := transfermodels.ReviewSimpleResponse{
-		Id:        review.Id,
-		Rating:    review.Rating,
-		Timestamp: review.Timestamp,
-		Comment:   review.Comment,
+		Id:         review.Id,
+		Rating:     review.Rating,
+		SubRatings: transfermodels.SubRatings(review.SubRatings),
+		Timestamp:  review.Timestamp,
+		Comment:    review.Comment,
 	}
 
 	res.Header().Add("Location", fmt.Sprintf("%s%s%s/%s", req.URL.Scheme, req.Host, req.URL.Path, review.Id))
@@ -144,12 +324,12 @@ func (rs *Reviews) Create(res http.ResponseWriter, req *http.Request) {
 func (rs *Reviews) Answer(res http.ResponseWriter, req *http.Request) {
 	answerRequest := transfermodels.AnswerReviewRequest{}
 	if err := json.NewDecoder(req.Body).Decode(&answerRequest); err != nil {
-		http.Error(res, ModelDecodeError, http.StatusBadRequest)
+		rs.decodeError(res)
 		return
 	}
 
 	if err := rs.validator.Struct(answerRequest); err != nil {
-		http.Error(res, err.Error(), http.StatusUnprocessableEntity)
+		rs.validationError(res, http.StatusUnprocessableEntity, err)
 		return
 	}
 
@@ -158,29 +338,29 @@ func (rs *Reviews) Answer(res http.ResponseWriter, req *http.Request) {
 	review, err := rs.reviewsService.GetById(id)
 	if err != nil {
 		if err == services.ErrReviewNotFound {
-			http.NotFound(res, req)
+			rs.notFound(res)
 			return
 		}
 
 		rs.logger.WithError(err).Warnln("could not get review by id")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
 		return
 	}
 
 	userId, err := middlewares.UserIDFromRequest(req)
 	if err != nil {
 		rs.logger.WithError(err).Warnln("Cannot get user id from request")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
 		return
 	}
 
 	if review.Restaurant.OwnerId != *userId {
-		http.NotFound(res, req)
+		rs.notFound(res)
 		return
 	}
 
 	if review.Answer != nil {
-		http.Error(res, "You have already answered this review!", http.StatusConflict)
+		rs.problem(res, http.StatusConflict, problems.CodeAlreadyAnswered, "You have already answered this review!")
 		return
 	}
 
@@ -189,17 +369,162 @@ func (rs *Reviews) Answer(res http.ResponseWriter, req *http.Request) {
 	err = rs.reviewsService.Update(review)
 	if err != nil {
 		rs.logger.WithError(err).Warnln("could not update review")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		rs.internalError(res)
+		return
+	}
+
+	reviewResponse := transfermodels.ReviewSimpleResponse{
+		Id:         review.Id,
+		Rating:     review.Rating,
+		SubRatings: transfermodels.SubRatings(review.SubRatings),
+		Timestamp:  review.Timestamp,
+		Comment:    review.Comment,
+		Answer:     review.Answer,
+	}
+
+	rs.returnJsonResponse(res, reviewResponse)
+}
+
+func (rs *Reviews) Edit(res http.ResponseWriter, req *http.Request) {
+	editRequest := transfermodels.EditReviewRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&editRequest); err != nil {
+		rs.decodeError(res)
+		return
+	}
+
+	if err := rs.validator.Struct(editRequest); err != nil {
+		rs.validationError(res, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	id := mux.Vars(req)["id"]
+
+	review, err := rs.reviewsService.GetById(id)
+	if err != nil {
+		if err == services.ErrReviewNotFound {
+			rs.notFound(res)
+			return
+		}
+
+		rs.logger.WithError(err).Warnln("could not get review by id")
+		rs.internalError(res)
+		return
+	}
+
+	userId, err := middlewares.UserIDFromRequest(req)
+	if err != nil {
+		rs.logger.WithError(err).Warnln("Cannot get user id from request")
+		rs.internalError(res)
+		return
+	}
+
+	if review.ReviewerId != *userId {
+		rs.notFound(res)
+		return
+	}
+
+	review.Rating = editRequest.Rating
+	review.SubRatings = models.SubRatings(editRequest.SubRatings)
+	review.Comment = editRequest.Comment
+
+	err = rs.reviewsService.Update(review)
+	if err != nil {
+		if err == services.ErrReviewNotFound {
+			rs.notFound(res)
+			return
+		}
+
+		rs.logger.WithError(err).Warnln("could not update review")
+		rs.internalError(res)
 		return
 	}
 
 	reviewResponse := transfermodels.ReviewSimpleResponse{
-		Id:        review.Id,
-		Rating:    review.Rating,
-		Timestamp: review.Timestamp,
-		Comment:   review.Comment,
-		Answer:    review.Answer,
+		Id:         review.Id,
+		Rating:     review.Rating,
+		SubRatings: transfermodels.SubRatings(review.SubRatings),
+		Timestamp:  review.Timestamp,
+		Comment:    review.Comment,
+		Answer:     review.Answer,
 	}
 
 	rs.returnJsonResponse(res, reviewResponse)
 }
+
+func (rs *Reviews) Delete(res http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+
+	review, err := rs.reviewsService.GetById(id)
+	if err != nil {
+		if err == services.ErrReviewNotFound {
+			rs.notFound(res)
+			return
+		}
+
+		rs.logger.WithError(err).Warnln("could not get review by id")
+		rs.internalError(res)
+		return
+	}
+
+	userId, err := middlewares.UserIDFromRequest(req)
+	if err != nil {
+		rs.logger.WithError(err).Warnln("Cannot get user id from request")
+		rs.internalError(res)
+		return
+	}
+
+	userRole, err := middlewares.UserRoleFromRequest(req)
+	if err != nil {
+		rs.logger.WithError(err).Warnln("Cannot get user role from request")
+		rs.internalError(res)
+		return
+	}
+
+	// Admins can delete any review, while regular users can delete only their own reviews
+	if *userRole != models.Admin && review.ReviewerId != *userId {
+		rs.notFound(res)
+		return
+	}
+
+	// The media are deleted together with the review, so their files have to be found beforehand
+	media, err := rs.reviewMediaService.ListForReviews([]string{id})
+	if err != nil {
+		rs.logger.WithError(err).Warnln("Cannot get media of review")
+		rs.internalError(res)
+		return
+	}
+
+	if err = rs.reviewsService.Delete(id); err != nil {
+		if err == services.ErrReviewNotFound {
+			rs.notFound(res)
+			return
+		}
+
+		rs.logger.WithError(err).Warnln("Cannot delete review")
+		rs.internalError(res)
+		return
+	}
+
+	rs.reviewMediaService.DeleteFiles(media[id])
+	rs.returnJsonResponse(res, transfermodels.ReviewDeleteResponse{OK: true})
+}
+
+// notifyOwner queues an email to the owner of the restaurant about a new review. Failures are only logged,
+// as the review has already been created.
+func (rs *Reviews) notifyOwner(review *models.Review) {
+	restaurant, err := rs.restaurantsService.GetSingle(review.RestaurantId)
+	if err != nil {
+		rs.logger.WithError(err).Warnln("Cannot get restaurant for review notification")
+		return
+	}
+
+	owner, err := rs.usersService.GetById(restaurant.OwnerId)
+	if err != nil {
+		rs.logger.WithError(err).Warnln("Cannot get restaurant owner for review notification")
+		return
+	}
+
+	if err = rs.emailsService.SendReviewNotificationEmail(owner.Email, owner.Locale, restaurant, review); err != nil {
+		rs.logger.WithError(err).Warnln("Cannot queue review notification email")
+	}
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// sortOption is a key that a list endpoint can be ordered by together with its natural direction
+type sortOption struct {
+	key        string
+	defaultAsc bool
+}
+
+// sortSpec is a validated ordering requested by the client
+type sortSpec struct {
+	key string
+	asc bool
+}
+
+// parseSortSpec reads the sort key from the keyParam query parameter and the direction from the ascParam query parameter.
+// Only the keys of the given options are accepted and the first option is used when the key is missing.
+// When the direction is missing, the natural direction of the key is used. The returned error explains what is wrong
+// with the parameters and can be shown to the client.
+func parseSortSpec(req *http.Request, keyParam, ascParam string, options []sortOption) (sortSpec, error) {
+	query := req.URL.Query()
+
+	key := query.Get(keyParam)
+	option, found := options[0], key == ""
+
+	for _, o := range options {
+		if o.key == key {
+			option, found = o, true
+			break
+		}
+	}
+
+	if !found {
+		keys := make([]string, len(options))
+		for i, o := range options {
+			keys[i] = o.key
+		}
+
+		return sortSpec{}, fmt.Errorf("%s must be one of %s", keyParam, strings.Join(keys, ", "))
+	}
+
+	spec := sortSpec{
+		key: option.key,
+		asc: option.defaultAsc,
+	}
+
+	switch query.Get(ascParam) {
+	case "":
+	case "true":
+		spec.asc = true
+	case "false":
+		spec.asc = false
+	default:
+		return sortSpec{}, fmt.Errorf("%s must be either true or false", ascParam)
+	}
+
+	return spec, nil
+}
@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+	"github.com/hrist0stoichev/ReviewsSystem/services"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/problems"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/transfermodels"
+)
+
+const TagExistsError = "A tag with this slug already exists"
+
+// Tags contains the endpoints for managing the taxonomy of cuisines and tags that restaurants are categorized with
+type Tags struct {
+	tagsService services.TagsService
+	baseController
+}
+
+func NewTags(tagsService services.TagsService, logger log.Logger, validator Validator) *Tags {
+	return &Tags{
+		tagsService: tagsService,
+		baseController: baseController{
+			logger:    logger,
+			validator: validator,
+		},
+	}
+}
+
+func (tc *Tags) List(res http.ResponseWriter, req *http.Request) {
+	tags, err := tc.tagsService.List()
+	if err != nil {
+		tc.logger.WithError(err).Warnln("Cannot list tags")
+		tc.internalError(res)
+		return
+	}
+
+	tc.returnJsonResponse(res, newTagResponses(tags))
+}
+
+func (tc *Tags) Create(res http.ResponseWriter, req *http.Request) {
+	tagRequest := transfermodels.CreateTagRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&tagRequest); err != nil {
+		tc.decodeError(res)
+		return
+	}
+
+	if err := tc.validator.Struct(tagRequest); err != nil {
+		tc.validationError(res, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	tag := models.Tag{
+		Slug: tagRequest.Slug,
+		Name: tagRequest.Name,
+		Kind: models.TagKind(tagRequest.Kind),
+	}
+
+	if err := tc.tagsService.Create(&tag); err != nil {
+		if err == services.ErrTagExists {
+			tc.problem(res, http.StatusConflict, problems.CodeTagExists, TagExistsError)
+			return
+		}
+
+		tc.logger.WithError(err).Warnln("Cannot create tag")
+		tc.internalError(res)
+		return
+	}
+
+	res.WriteHeader(http.StatusCreated)
+	tc.returnJsonResponse(res, newTagResponse(&tag))
+}
+
+// Update changes the fields of a tag that are present in the request. Changing the slug changes it for all restaurants with the tag.
+func (tc *Tags) Update(res http.ResponseWriter, req *http.Request) {
+	updateRequest := transfermodels.UpdateTagRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&updateRequest); err != nil {
+		tc.decodeError(res)
+		return
+	}
+
+	if err := tc.validator.Struct(updateRequest); err != nil {
+		tc.validationError(res, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	tag, err := tc.tagsService.GetById(mux.Vars(req)["id"])
+	if err != nil {
+		if err == services.ErrTagNotFound {
+			tc.notFound(res)
+			return
+		}
+
+		tc.logger.WithError(err).Warnln("Cannot get tag")
+		tc.internalError(res)
+		return
+	}
+
+	if updateRequest.Slug != nil {
+		tag.Slug = *updateRequest.Slug
+	}
+
+	if updateRequest.Name != nil {
+		tag.Name = *updateRequest.Name
+	}
+
+	if updateRequest.Kind != nil {
+		tag.Kind = models.TagKind(*updateRequest.Kind)
+	}
+
+	if err = tc.tagsService.Update(tag); err != nil {
+		if err == services.ErrTagExists {
+			tc.problem(res, http.StatusConflict, problems.CodeTagExists, TagExistsError)
+			return
+		}
+
+		if err == services.ErrTagNotFound {
+			tc.notFound(res)
+			return
+		}
+
+		tc.logger.WithError(err).Warnln("Cannot update tag")
+		tc.internalError(res)
+		return
+	}
+
+	tc.returnJsonResponse(res, newTagResponse(tag))
+}
+
+// Delete removes a tag from the taxonomy and from all restaurants
+func (tc *Tags) Delete(res http.ResponseWriter, req *http.Request) {
+	if err := tc.tagsService.Delete(mux.Vars(req)["id"]); err != nil {
+		if err == services.ErrTagNotFound {
+			tc.notFound(res)
+			return
+		}
+
+		tc.logger.WithError(err).Warnln("Cannot delete tag")
+		tc.internalError(res)
+		return
+	}
+
+	tc.returnJsonResponse(res, transfermodels.TagDeleteResponse{OK: true})
+}
+
+func newTagResponse(tag *models.Tag) transfermodels.TagResponse {
+	return transfermodels.TagResponse{
+		Id:   tag.Id,
+		Slug: tag.Slug,
+		Name: tag.Name,
+		Kind: string(tag.Kind),
+	}
+}
+
+func newTagResponses(tags []models.Tag) []transfermodels.TagResponse {
+	responses := make([]transfermodels.TagResponse, len(tags))
+	for i := range tags {
+		responses[i] = newTagResponse(&tags[i])
+	}
+
+	return responses
+}
+
+func newRestaurantFacetsResponse(facets *models.RestaurantFacets) transfermodels.RestaurantFacetsResponse {
+	facetsResponse := transfermodels.RestaurantFacetsResponse{
+		Tags:   make([]transfermodels.TagFacetResponse, len(facets.Tags)),
+		Cities: make([]transfermodels.CityFacetResponse, len(facets.Cities)),
+	}
+
+	for i, f := range facets.Tags {
+		facetsResponse.Tags[i] = transfermodels.TagFacetResponse{
+			TagResponse: newTagResponse(&facets.Tags[i].Tag),
+			Count:       f.Count,
+		}
+	}
+
+	for i, f := range facets.Cities {
+		facetsResponse.Cities[i] = transfermodels.CityFacetResponse{
+			City:  f.City,
+			Count: f.Count,
+		}
+	}
+
+	return facetsResponse
+}
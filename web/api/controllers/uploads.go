@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hrist0stoichev/ReviewsSystem/services"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/problems"
+)
+
+const (
+	ImageFormField    = "image"
+	MissingImageError = "The request must be a multipart form with an image file"
+	UnsupportedImage  = "Only JPEG, PNG and GIF images are supported"
+	// multipartFormMemory is the room left for the other parts of an upload form
+	multipartFormMemory = 1 << 20
+	// requestBodyTooLarge is the message of the error returned when reading more than allowed by http.MaxBytesReader
+	requestBodyTooLarge = "http: request body too large"
+)
+
+// readImageUpload reads the image file from the image field of a multipart form. The file can be at most maxSize bytes.
+func (bc *baseController) readImageUpload(res http.ResponseWriter, req *http.Request, maxSize int64) ([]byte, bool) {
+	if req.ContentLength > maxSize+multipartFormMemory {
+		bc.imageTooLarge(res, maxSize)
+		return nil, false
+	}
+
+	req.Body = http.MaxBytesReader(res, req.Body, maxSize+multipartFormMemory)
+
+	file, header, err := req.FormFile(ImageFormField)
+	if err != nil {
+		if err.Error() == requestBodyTooLarge {
+			bc.imageTooLarge(res, maxSize)
+			return nil, false
+		}
+
+		bc.problem(res, http.StatusBadRequest, problems.CodeMissingParameter, MissingImageError)
+		return nil, false
+	}
+	defer file.Close()
+
+	if header.Size > maxSize {
+		bc.imageTooLarge(res, maxSize)
+		return nil, false
+	}
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		bc.logger.WithError(err).Warnln("Cannot read uploaded image")
+		bc.internalError(res)
+		return nil, false
+	}
+
+	return data, true
+}
+
+// imageError returns a problem if the error is caused by an image that cannot be processed. It returns false for any other error.
+func (bc *baseController) imageError(res http.ResponseWriter, err error, maxSize int64) bool {
+	switch err {
+	case services.ErrUnsupportedImage:
+		bc.problem(res, http.StatusUnsupportedMediaType, problems.CodeUnsupportedMediaType, UnsupportedImage)
+	case services.ErrImageTooLarge:
+		bc.imageTooLarge(res, maxSize)
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (bc *baseController) imageTooLarge(res http.ResponseWriter, maxSize int64) {
+	bc.problem(res, http.StatusRequestEntityTooLarge, problems.CodeImageTooLarge, fmt.Sprintf("Images can be at most %d bytes and %d megapixels", maxSize, services.MaxImageMegapixels))
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+)
+
+// newUploadRequest returns a multipart form request with the file in the given field
+func newUploadRequest(t *testing.T, field string, file []byte) *http.Request {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile(field, "image.png")
+	if err != nil {
+		t.Fatalf("could not create form file: %v", err)
+	}
+
+	if _, err = part.Write(file); err != nil {
+		t.Fatalf("could not write form file: %v", err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatalf("could not close multipart writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/restaurants/1/images", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return req
+}
+
+func TestReadImageUpload(t *testing.T) {
+	logger, err := log.NewLogrus(&log.Config{Level: "error", Format: "text", Output: "stdout"})
+	if err != nil {
+		t.Fatalf("could not create logger: %v", err)
+	}
+
+	bc := &baseController{logger: logger}
+	const maxSize = 1024
+
+	// A body of unknown length is only limited while it is read
+	unknownLength := newUploadRequest(t, ImageFormField, make([]byte, maxSize+multipartFormMemory+1))
+	unknownLength.Body = ioutil.NopCloser(unknownLength.Body)
+	unknownLength.ContentLength = -1
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+	}{
+		{name: "file of the maximum size", req: newUploadRequest(t, ImageFormField, make([]byte, maxSize)), wantStatus: http.StatusOK},
+		{name: "file larger than the maximum size", req: newUploadRequest(t, ImageFormField, make([]byte, maxSize+1)), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "request larger than the maximum size", req: newUploadRequest(t, ImageFormField, make([]byte, maxSize+multipartFormMemory+1)), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "request of unknown length", req: unknownLength, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "file in another field", req: newUploadRequest(t, "file", make([]byte, maxSize)), wantStatus: http.StatusBadRequest},
+		{name: "not a multipart form", req: httptest.NewRequest(http.MethodPost, "/api/v1/restaurants/1/images", bytes.NewReader(make([]byte, maxSize))), wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+
+			data, ok := bc.readImageUpload(res, tt.req, maxSize)
+			if ok != (tt.wantStatus == http.StatusOK) || res.Code != tt.wantStatus {
+				t.Fatalf("readImageUpload() ok = %v, status = %d, want status %d", ok, res.Code, tt.wantStatus)
+			}
+
+			if ok && len(data) != maxSize {
+				t.Errorf("readImageUpload() read %d bytes, want %d", len(data), maxSize)
+			}
+		})
+	}
+}
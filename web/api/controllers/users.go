@@ -1,29 +1,43 @@
 package controllers
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/hrist0stoichev/ReviewsSystem/db/models"
 	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
 	"github.com/hrist0stoichev/ReviewsSystem/services"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/problems"
 	"github.com/hrist0stoichev/ReviewsSystem/web/api/transfermodels"
 )
 
 const (
-	InvalidCredentials = "Invalid username or password"
-	EmailNotConfirmed  = "Email is not confirmed"
+	InvalidCredentials  = "Invalid username or password"
+	EmailNotConfirmed   = "Email is not confirmed"
+	AccountDisabled     = "Your account has been disabled"
+	InvalidRefresh      = "Invalid refresh token"
+	InvalidReset        = "Invalid or expired password reset token"
+	EmailConfirmed      = "Email already confirmed"
+	ConfirmationExpired = "Confirmation token has expired, please request a new one"
+	ResendTooSoon       = "A confirmation email has been sent recently, please try again later"
 )
 
 type Users struct {
 	usersService          services.UsersService
 	encryptionService     services.EncryptionService
 	tokensService         services.TokensService
+	refreshTokensService  services.RefreshTokensService
 	emailsService         services.EmailsService
 	oauth2Service         services.OAuth2Service
 	redirectionEndpoint   string
 	skipEmailVerification bool
+	passwordResetValidFor time.Duration
+	confirmationValidFor  time.Duration
+	resendInterval        time.Duration
 	baseController
 }
 
@@ -31,10 +45,14 @@ func NewUsers(
 	usersService services.UsersService,
 	encryptionService services.EncryptionService,
 	tokensService services.TokensService,
+	refreshTokensService services.RefreshTokensService,
 	emailsService services.EmailsService,
 	oauth2Service services.OAuth2Service,
 	redirectionEndpoint string,
 	skipEmailVerification bool,
+	passwordResetValidFor time.Duration,
+	confirmationValidFor time.Duration,
+	resendInterval time.Duration,
 	logger log.Logger,
 	validator Validator,
 ) *Users {
@@ -42,10 +60,14 @@ func NewUsers(
 		usersService:          usersService,
 		encryptionService:     encryptionService,
 		tokensService:         tokensService,
+		refreshTokensService:  refreshTokensService,
 		emailsService:         emailsService,
 		oauth2Service:         oauth2Service,
 		redirectionEndpoint:   redirectionEndpoint,
 		skipEmailVerification: skipEmailVerification,
+		passwordResetValidFor: passwordResetValidFor,
+		confirmationValidFor:  confirmationValidFor,
+		resendInterval:        resendInterval,
 		baseController: baseController{
 			logger:    logger,
 			validator: validator,
@@ -56,12 +78,12 @@ func NewUsers(
 func (uc *Users) Register(res http.ResponseWriter, req *http.Request) {
 	userRequest := transfermodels.CreateUserRequest{}
 	if err := json.NewDecoder(req.Body).Decode(&userRequest); err != nil {
-		http.Error(res, ModelDecodeError, http.StatusBadRequest)
+		uc.decodeError(res)
 		return
 	}
 
 	if err := uc.validator.Struct(userRequest); err != nil {
-		http.Error(res, err.Error(), http.StatusBadRequest)
+		uc.validationError(res, http.StatusBadRequest, err)
 		return
 	}
 
@@ -69,30 +91,39 @@ func (uc *Users) Register(res http.ResponseWriter, req *http.Request) {
 	_, err := uc.usersService.GetByEmail(userRequest.Email)
 	if err != services.ErrUserNotFound {
 		if err == nil {
-			http.Error(res, "User already exists", http.StatusConflict)
+			uc.problem(res, http.StatusConflict, problems.CodeUserExists, "User already exists")
 			return
 		}
 
 		uc.logger.WithError(err).Warnln("could not get user by email")
-		http.Error(res, "Something went wrong", http.StatusInternalServerError)
+		uc.internalError(res)
 		return
 	}
 
 	saltedHash, err := uc.encryptionService.GenerateSaltedHash(&userRequest.Password)
 	if err != nil {
 		uc.logger.WithError(err).Warnln("Could not generate salted hash")
-		http.Error(res, "", http.StatusInternalServerError)
+		uc.internalError(res)
 		return
 	}
 
-	confirmationToken := uc.emailsService.GenerateRandomEmailToken()
+	confirmationToken, err := uc.emailsService.GenerateRandomEmailToken()
+	if err != nil {
+		uc.logger.WithError(err).Warnln("Could not generate email confirmation token")
+		uc.internalError(res)
+		return
+	}
+
+	confirmationTokenCreatedAt := time.Now().UTC()
 
 	user := &models.User{
-		Email:                  userRequest.Email,
-		EmailConfirmed:         false,
-		EmailConfirmationToken: &confirmationToken,
-		HashedPassword:         saltedHash,
-		Role:                   models.Regular,
+		Email:                           userRequest.Email,
+		EmailConfirmed:                  false,
+		EmailConfirmationToken:          &confirmationToken,
+		EmailConfirmationTokenCreatedAt: &confirmationTokenCreatedAt,
+		HashedPassword:                  saltedHash,
+		Role:                            models.Regular,
+		Locale:                          uc.emailsService.PreferredLocale(req.Header.Get("Accept-Language")),
 	}
 
 	if userRequest.IsOwner {
@@ -101,16 +132,14 @@ func (uc *Users) Register(res http.ResponseWriter, req *http.Request) {
 
 	if err = uc.usersService.CreateUser(user); err != nil {
 		uc.logger.WithError(err).Warnln("Could not create user")
-		http.Error(res, "a problem occurred while creating a user", http.StatusBadRequest)
+		uc.problem(res, http.StatusBadRequest, problems.CodeInvalidBody, "a problem occurred while creating a user")
 		return
 	}
 
-	// Send the confirmation email async. A functionality for resending emails should be implemented.
-	go func() {
-		if err = uc.emailsService.SendConfirmationEmail(user.Email, *user.EmailConfirmationToken); err != nil {
-			uc.logger.WithError(err).Warnln("could not send confirmation email")
-		}
-	}()
+	// The confirmation email is queued and sent in the background. If it fails, the user can request a new one through ResendConfirmationEmail.
+	if err = uc.emailsService.SendConfirmationEmail(user.Email, user.Locale, *user.EmailConfirmationToken); err != nil {
+		uc.logger.WithError(err).Warnln("could not queue confirmation email")
+	}
 
 	uc.returnJsonResponse(res, transfermodels.CreateUserResponse{
 		Ok: true,
@@ -120,50 +149,38 @@ func (uc *Users) Register(res http.ResponseWriter, req *http.Request) {
 func (uc *Users) Login(res http.ResponseWriter, req *http.Request) {
 	loginRequest := transfermodels.LoginRequest{}
 	if err := json.NewDecoder(req.Body).Decode(&loginRequest); err != nil {
-		http.Error(res, ModelDecodeError, http.StatusBadRequest)
+		uc.decodeError(res)
 		return
 	}
 
 	user, err := uc.usersService.GetByEmail(loginRequest.Email)
 	if err != nil {
 		if err == services.ErrUserNotFound {
-			http.Error(res, InvalidCredentials, http.StatusNotFound)
+			uc.problem(res, http.StatusNotFound, problems.CodeInvalidCredentials, InvalidCredentials)
 			return
 		}
 
 		uc.logger.WithError(err).Warnln("Could not get username by email")
-		http.Error(res, "Something went wrong", http.StatusInternalServerError)
+		uc.internalError(res)
 		return
 	}
 
 	if !uc.encryptionService.PasswordsMatch(&loginRequest.Password, &user.HashedPassword) {
-		http.Error(res, InvalidCredentials, http.StatusNotFound)
+		uc.problem(res, http.StatusNotFound, problems.CodeInvalidCredentials, InvalidCredentials)
 		return
 	}
 
 	if !uc.skipEmailVerification && !user.EmailConfirmed {
-		http.Error(res, EmailNotConfirmed, http.StatusBadRequest)
+		uc.problem(res, http.StatusBadRequest, problems.CodeEmailNotConfirmed, EmailNotConfirmed)
 		return
 	}
 
-	jwt, claims, err := uc.tokensService.GenerateSignedToken(&services.UserClaims{
-		Id:   user.Id,
-		Role: user.Role.String(),
-	})
-	if err != nil {
-		uc.logger.WithError(err).Warnln("Could not generate jwt")
-		http.Error(res, "Something went wrong", http.StatusInternalServerError)
+	if user.Disabled {
+		uc.problem(res, http.StatusForbidden, problems.CodeAccountDisabled, AccountDisabled)
 		return
 	}
 
-	resp := transfermodels.LoginResponse{
-		Token:   jwt,
-		Expires: time.Unix(claims.ExpiresAt.Unix(), 0),
-		Email:   user.Email,
-		Role:    claims.Role,
-	}
-
-	uc.returnJsonResponse(res, resp)
+	uc.issueTokens(res, user)
 }
 
 func (uc *Users) RedirectToFacebookAuth(res http.ResponseWriter, req *http.Request) {
@@ -172,26 +189,23 @@ func (uc *Users) RedirectToFacebookAuth(res http.ResponseWriter, req *http.Reque
 }
 
 func (uc *Users) FacebookLogin(res http.ResponseWriter, req *http.Request) {
-	fbLoginRequest := struct {
-		State string `json:"state"`
-		Code  string `json:"code"`
-	}{}
+	fbLoginRequest := transfermodels.FacebookLoginRequest{}
 	if err := json.NewDecoder(req.Body).Decode(&fbLoginRequest); err != nil {
-		http.Error(res, ModelDecodeError, http.StatusBadRequest)
+		uc.decodeError(res)
 		return
 	}
 
 	token, err := uc.oauth2Service.GetToken(fbLoginRequest.State, fbLoginRequest.Code)
 	if err != nil {
 		uc.logger.WithError(err).Warnln("Failed to get oauth2 token")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		uc.internalError(res)
 		return
 	}
 
 	userInfo, err := uc.oauth2Service.GetUserInfo(token)
 	if err != nil {
 		uc.logger.WithError(err).Warnln("Failed to get oauth2 user info")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		uc.internalError(res)
 		return
 	}
 
@@ -199,7 +213,7 @@ func (uc *Users) FacebookLogin(res http.ResponseWriter, req *http.Request) {
 	dbUser, err := uc.usersService.GetByEmail(userInfo.Email)
 	if err != nil && err != services.ErrUserNotFound {
 		uc.logger.WithError(err).Warnln("Failed to get user by email")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		uc.internalError(res)
 		return
 	}
 
@@ -211,12 +225,13 @@ func (uc *Users) FacebookLogin(res http.ResponseWriter, req *http.Request) {
 			EmailConfirmationToken: nil,
 			HashedPassword:         "",
 			Role:                   models.Regular,
+			Locale:                 uc.emailsService.PreferredLocale(req.Header.Get("Accept-Language")),
 		}
 
 		err = uc.usersService.CreateUser(user)
 		if err != nil {
 			uc.logger.WithError(err).Warnln("Failed to create new facebook user")
-			http.Error(res, InternalServerError, http.StatusInternalServerError)
+			uc.internalError(res)
 			return
 		}
 
@@ -224,67 +239,417 @@ func (uc *Users) FacebookLogin(res http.ResponseWriter, req *http.Request) {
 	} else { // The user is found
 		// The user is found but they haven't registered through facebook
 		if dbUser.HashedPassword != "" {
-			http.Error(res, "You have already registered with this email from the basic registration form!", http.StatusConflict)
+			uc.problem(res, http.StatusConflict, problems.CodeUserExists, "You have already registered with this email from the basic registration form!")
+			return
+		}
+
+		if dbUser.Disabled {
+			uc.problem(res, http.StatusForbidden, problems.CodeAccountDisabled, AccountDisabled)
+			return
+		}
+	}
+
+	uc.issueTokens(res, dbUser)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token. The used refresh token is revoked.
+// If a refresh token that has already been rotated is presented, it has most probably been stolen, so all sessions of the user are revoked.
+// Tokens revoked in another way, e.g. by logging out or resetting the password, are only rejected.
+func (uc *Users) Refresh(res http.ResponseWriter, req *http.Request) {
+	refreshRequest := transfermodels.RefreshTokenRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&refreshRequest); err != nil {
+		uc.decodeError(res)
+		return
+	}
+
+	if err := uc.validator.Struct(refreshRequest); err != nil {
+		uc.validationError(res, http.StatusBadRequest, err)
+		return
+	}
+
+	refreshToken, err := uc.refreshTokensService.GetByHash(uc.tokensService.HashRefreshToken(refreshRequest.RefreshToken))
+	if err != nil {
+		if err == services.ErrRefreshTokenNotFound {
+			uc.problem(res, http.StatusUnauthorized, problems.CodeInvalidRefreshToken, InvalidRefresh)
+			return
+		}
+
+		uc.logger.WithError(err).Warnln("Could not get refresh token")
+		uc.internalError(res)
+		return
+	}
+
+	if refreshToken.RevokedAt != nil {
+		if refreshToken.ReplacedBy != nil {
+			uc.revokeAllOnReuse(refreshToken.UserId)
+		}
+
+		uc.problem(res, http.StatusUnauthorized, problems.CodeInvalidRefreshToken, InvalidRefresh)
+		return
+	}
+
+	if time.Now().After(refreshToken.ExpiresAt) {
+		uc.problem(res, http.StatusUnauthorized, problems.CodeInvalidRefreshToken, InvalidRefresh)
+		return
+	}
+
+	user, err := uc.usersService.GetById(refreshToken.UserId)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			uc.problem(res, http.StatusUnauthorized, problems.CodeInvalidRefreshToken, InvalidRefresh)
+			return
+		}
+
+		uc.logger.WithError(err).Warnln("Could not get user by id")
+		uc.internalError(res)
+		return
+	}
+
+	if user.Disabled {
+		uc.problem(res, http.StatusForbidden, problems.CodeAccountDisabled, AccountDisabled)
+		return
+	}
+
+	newRefreshToken, newRefreshTokenRecord, err := uc.tokensService.GenerateRefreshToken(user.Id)
+	if err != nil {
+		uc.logger.WithError(err).Warnln("Could not generate refresh token")
+		uc.internalError(res)
+		return
+	}
+
+	if err = uc.refreshTokensService.Rotate(refreshToken.Id, newRefreshTokenRecord); err != nil {
+		if err == services.ErrRefreshTokenRevoked {
+			// The token has been revoked since it was loaded, which is reuse only if a concurrent request rotated it
+			if revoked, err := uc.refreshTokensService.GetByHash(refreshToken.HashedToken); err != nil {
+				uc.logger.WithError(err).Warnln("Could not get revoked refresh token")
+			} else if revoked.ReplacedBy != nil {
+				uc.revokeAllOnReuse(refreshToken.UserId)
+			}
+
+			uc.problem(res, http.StatusUnauthorized, problems.CodeInvalidRefreshToken, InvalidRefresh)
 			return
 		}
+
+		uc.logger.WithError(err).Warnln("Could not rotate refresh token")
+		uc.internalError(res)
+		return
 	}
 
+	uc.issueAccessToken(res, user, newRefreshToken, newRefreshTokenRecord)
+}
+
+// Revoke revokes a refresh token (logout). If all is set in the request, all sessions of the user are revoked (logout everywhere).
+func (uc *Users) Revoke(res http.ResponseWriter, req *http.Request) {
+	revokeRequest := transfermodels.RevokeTokenRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&revokeRequest); err != nil {
+		uc.decodeError(res)
+		return
+	}
+
+	if err := uc.validator.Struct(revokeRequest); err != nil {
+		uc.validationError(res, http.StatusBadRequest, err)
+		return
+	}
+
+	refreshToken, err := uc.refreshTokensService.GetByHash(uc.tokensService.HashRefreshToken(revokeRequest.RefreshToken))
+	if err != nil {
+		if err == services.ErrRefreshTokenNotFound {
+			uc.problem(res, http.StatusUnauthorized, problems.CodeInvalidRefreshToken, InvalidRefresh)
+			return
+		}
+
+		uc.logger.WithError(err).Warnln("Could not get refresh token")
+		uc.internalError(res)
+		return
+	}
+
+	if revokeRequest.All {
+		err = uc.refreshTokensService.RevokeAllForUser(refreshToken.UserId)
+	} else {
+		err = uc.refreshTokensService.Revoke(refreshToken.Id)
+	}
+
+	if err != nil {
+		uc.logger.WithError(err).Warnln("Could not revoke refresh token")
+		uc.internalError(res)
+		return
+	}
+
+	uc.returnJsonResponse(res, transfermodels.RevokeTokenResponse{OK: true})
+}
+
+// issueTokens generates a new refresh token for the user and returns it together with a new access token
+func (uc *Users) issueTokens(res http.ResponseWriter, user *models.User) {
+	refreshToken, refreshTokenRecord, err := uc.tokensService.GenerateRefreshToken(user.Id)
+	if err != nil {
+		uc.logger.WithError(err).Warnln("Could not generate refresh token")
+		uc.internalError(res)
+		return
+	}
+
+	if err = uc.refreshTokensService.Create(refreshTokenRecord); err != nil {
+		uc.logger.WithError(err).Warnln("Could not store refresh token")
+		uc.internalError(res)
+		return
+	}
+
+	uc.issueAccessToken(res, user, refreshToken, refreshTokenRecord)
+}
+
+// issueAccessToken generates a new access token for the user and returns it together with an already stored refresh token
+func (uc *Users) issueAccessToken(res http.ResponseWriter, user *models.User, refreshToken string, refreshTokenRecord *models.RefreshToken) {
 	jwt, claims, err := uc.tokensService.GenerateSignedToken(&services.UserClaims{
-		Id:   dbUser.Id,
-		Role: dbUser.Role.String(),
+		Id:      user.Id,
+		Role:    user.Role.String(),
+		Version: user.TokenVersion,
 	})
 	if err != nil {
-		uc.logger.WithError(err).Warnln("Failed to generate signed token")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		uc.logger.WithError(err).Warnln("Could not generate jwt")
+		uc.internalError(res)
 		return
 	}
 
 	resp := transfermodels.LoginResponse{
-		Token:   jwt,
-		Expires: time.Unix(claims.ExpiresAt.Unix(), 0),
-		Email:   dbUser.Email,
-		Role:    claims.Role,
+		Token:               jwt,
+		Expires:             time.Unix(claims.ExpiresAt.Unix(), 0),
+		RefreshToken:        refreshToken,
+		RefreshTokenExpires: refreshTokenRecord.ExpiresAt,
+		Email:               user.Email,
+		Role:                claims.Role,
 	}
 
 	uc.returnJsonResponse(res, resp)
 }
 
+// revokeAllOnReuse revokes all sessions of a user whose rotated refresh token has been presented again
+func (uc *Users) revokeAllOnReuse(userId string) {
+	uc.logger.WithField("userId", userId).Warnln("Revoked refresh token reused, revoking all sessions of the user")
+
+	if err := uc.refreshTokensService.RevokeAllForUser(userId); err != nil {
+		uc.logger.WithError(err).Warnln("Could not revoke all sessions of the user")
+	}
+}
+
 func (uc *Users) ConfirmEmail(res http.ResponseWriter, req *http.Request) {
 	email := req.URL.Query().Get("email")
 	token := req.URL.Query().Get("token")
 
 	if email == "" || token == "" {
-		http.NotFound(res, req)
+		uc.notFound(res)
 		return
 	}
 
 	user, err := uc.usersService.GetByEmail(email)
 	if err != nil {
 		if err == services.ErrUserNotFound {
-			http.NotFound(res, req)
+			uc.notFound(res)
 			return
 		}
 
 		uc.logger.WithError(err).Warnln("Could not get username by email")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		uc.internalError(res)
 		return
 	}
 
 	if user.EmailConfirmed {
-		http.Error(res, "Email already confirmed", http.StatusConflict)
+		uc.problem(res, http.StatusConflict, problems.CodeEmailAlreadyConfirmed, EmailConfirmed)
+		return
+	}
+
+	if user.EmailConfirmationToken == nil || *user.EmailConfirmationToken != token {
+		uc.notFound(res)
 		return
 	}
 
-	if *user.EmailConfirmationToken != token {
-		http.NotFound(res, req)
+	if user.EmailConfirmationTokenCreatedAt == nil || time.Now().UTC().After(user.EmailConfirmationTokenCreatedAt.Add(uc.confirmationValidFor)) {
+		uc.problem(res, http.StatusGone, problems.CodeConfirmationExpired, ConfirmationExpired)
 		return
 	}
 
 	if err = uc.usersService.ConfirmEmail(user.Id); err != nil {
 		uc.logger.WithError(err).Warnln("could not confirm email")
-		http.Error(res, InternalServerError, http.StatusInternalServerError)
+		uc.internalError(res)
 		return
 	}
 
 	http.Redirect(res, req, uc.redirectionEndpoint, http.StatusSeeOther)
 }
+
+// ResendConfirmationEmail generates a new confirmation token for the user and sends it by email. The previous token is no
+// longer valid. A new email can be requested for the same address only once per resend interval.
+func (uc *Users) ResendConfirmationEmail(res http.ResponseWriter, req *http.Request) {
+	resendRequest := transfermodels.ResendConfirmationEmailRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&resendRequest); err != nil {
+		uc.decodeError(res)
+		return
+	}
+
+	if err := uc.validator.Struct(resendRequest); err != nil {
+		uc.validationError(res, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := uc.usersService.GetByEmail(resendRequest.Email)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			uc.returnJsonResponse(res, transfermodels.CreateUserResponse{Ok: true})
+			return
+		}
+
+		uc.logger.WithError(err).Warnln("Could not get user by email")
+		uc.internalError(res)
+		return
+	}
+
+	// Confirmed users get the same response as unknown ones, so that the endpoint does not reveal which emails are registered
+	if user.EmailConfirmed {
+		uc.returnJsonResponse(res, transfermodels.CreateUserResponse{Ok: true})
+		return
+	}
+
+	token, err := uc.emailsService.GenerateRandomEmailToken()
+	if err != nil {
+		uc.logger.WithError(err).Warnln("Could not generate email confirmation token")
+		uc.internalError(res)
+		return
+	}
+
+	now := time.Now().UTC()
+	if err = uc.usersService.SetEmailConfirmationToken(user.Id, token, now, uc.resendInterval); err != nil {
+		if err == services.ErrEmailSentTooRecently {
+			retryAfter := uc.resendInterval
+			if user.EmailConfirmationTokenCreatedAt != nil && user.EmailConfirmationTokenCreatedAt.Add(uc.resendInterval).After(now) {
+				retryAfter = user.EmailConfirmationTokenCreatedAt.Add(uc.resendInterval).Sub(now)
+			}
+
+			res.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			uc.problem(res, http.StatusTooManyRequests, problems.CodeTooManyRequests, ResendTooSoon)
+			return
+		}
+
+		uc.logger.WithError(err).Warnln("Could not set email confirmation token")
+		uc.internalError(res)
+		return
+	}
+
+	if err = uc.emailsService.SendConfirmationEmail(user.Email, user.Locale, token); err != nil {
+		uc.logger.WithError(err).Warnln("could not queue confirmation email")
+		uc.internalError(res)
+		return
+	}
+
+	uc.returnJsonResponse(res, transfermodels.CreateUserResponse{Ok: true})
+}
+
+// RequestPasswordReset sends an email with a single-use password reset token to the user. The response is the same
+// whether the user exists or not, so that the endpoint cannot be used to find out which emails are registered.
+func (uc *Users) RequestPasswordReset(res http.ResponseWriter, req *http.Request) {
+	resetRequest := transfermodels.PasswordResetRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&resetRequest); err != nil {
+		uc.decodeError(res)
+		return
+	}
+
+	if err := uc.validator.Struct(resetRequest); err != nil {
+		uc.validationError(res, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := uc.usersService.GetByEmail(resetRequest.Email)
+	if err != nil && err != services.ErrUserNotFound {
+		uc.logger.WithError(err).Warnln("Could not get user by email")
+		uc.internalError(res)
+		return
+	}
+
+	// Users registered through facebook don't have a password that can be reset
+	if err == services.ErrUserNotFound || user.HashedPassword == "" || user.Disabled {
+		uc.returnJsonResponse(res, transfermodels.PasswordResetResponse{Ok: true})
+		return
+	}
+
+	token, err := uc.emailsService.GenerateRandomEmailToken()
+	if err != nil {
+		uc.logger.WithError(err).Warnln("Could not generate password reset token")
+		uc.internalError(res)
+		return
+	}
+
+	// Only one email per resend interval is sent to an address. The response does not tell that the email was not sent,
+	// as it would reveal that the user exists.
+	err = uc.usersService.SetPasswordResetToken(user.Id, token, time.Now().UTC().Add(uc.passwordResetValidFor), uc.resendInterval)
+	if err != nil {
+		if err == services.ErrEmailSentTooRecently {
+			uc.returnJsonResponse(res, transfermodels.PasswordResetResponse{Ok: true})
+			return
+		}
+
+		uc.logger.WithError(err).Warnln("Could not set password reset token")
+		uc.internalError(res)
+		return
+	}
+
+	if err = uc.emailsService.SendPasswordResetEmail(user.Email, user.Locale, token); err != nil {
+		uc.logger.WithError(err).Warnln("could not queue password reset email")
+		uc.internalError(res)
+		return
+	}
+
+	uc.returnJsonResponse(res, transfermodels.PasswordResetResponse{Ok: true})
+}
+
+// ConfirmPasswordReset sets a new password for the user if the password reset token is valid.
+// The token can be used only once and all existing sessions of the user are revoked.
+func (uc *Users) ConfirmPasswordReset(res http.ResponseWriter, req *http.Request) {
+	confirmRequest := transfermodels.PasswordResetConfirmRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&confirmRequest); err != nil {
+		uc.decodeError(res)
+		return
+	}
+
+	if err := uc.validator.Struct(confirmRequest); err != nil {
+		uc.validationError(res, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := uc.usersService.GetByEmail(confirmRequest.Email)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			uc.problem(res, http.StatusBadRequest, problems.CodeInvalidResetToken, InvalidReset)
+			return
+		}
+
+		uc.logger.WithError(err).Warnln("Could not get user by email")
+		uc.internalError(res)
+		return
+	}
+
+	// The token is checked here to avoid hashing the password for invalid tokens and again when the password is changed,
+	// so that concurrent requests cannot use it twice
+	if user.PasswordResetToken == nil || user.PasswordResetTokenExpiresAt == nil ||
+		subtle.ConstantTimeCompare([]byte(*user.PasswordResetToken), []byte(confirmRequest.Token)) != 1 ||
+		time.Now().UTC().After(*user.PasswordResetTokenExpiresAt) {
+		uc.problem(res, http.StatusBadRequest, problems.CodeInvalidResetToken, InvalidReset)
+		return
+	}
+
+	saltedHash, err := uc.encryptionService.GenerateSaltedHash(&confirmRequest.Password)
+	if err != nil {
+		uc.logger.WithError(err).Warnln("Could not generate salted hash")
+		uc.internalError(res)
+		return
+	}
+
+	if err = uc.usersService.ResetPassword(user.Id, confirmRequest.Token, saltedHash); err != nil {
+		if err == services.ErrInvalidPasswordResetToken {
+			uc.problem(res, http.StatusBadRequest, problems.CodeInvalidResetToken, InvalidReset)
+			return
+		}
+
+		uc.logger.WithError(err).Warnln("Could not reset password")
+		uc.internalError(res)
+		return
+	}
+
+	uc.returnJsonResponse(res, transfermodels.PasswordResetResponse{Ok: true})
+}
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/transfermodels"
+)
+
+// embeddedFieldName is the name of the embedded structs in the validation errors. Their fields are flattened in JSON,
+// so it is removed from the field paths.
+const embeddedFieldName = "_embedded"
+
+// JsonFieldName returns the name of a struct field in JSON, so that the validation errors refer to the fields the way the clients send them.
+// It is meant to be registered with validator.Validate.RegisterTagNameFunc. Fields without a JSON name keep their Go name.
+func JsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+
+	if name == "" && field.Anonymous {
+		return embeddedFieldName
+	}
+
+	return name
+}
+
+// dateTimeFormats explains the layouts used with the datetime rule
+var dateTimeFormats = map[string]string{
+	"15:04":      "HH:MM",
+	"2006-01-02": "YYYY-MM-DD",
+}
+
+// slugRegex matches lowercase words of letters and digits separated by single hyphens, e.g. middle-eastern
+var slugRegex = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// RegisterValidations registers the custom validation rules and the validations of the request bodies that cannot be expressed
+// with the tags of single fields
+func RegisterValidations(v *validator.Validate) error {
+	if err := v.RegisterValidation("slug", isSlug); err != nil {
+		return err
+	}
+
+	if err := v.RegisterValidation("weburl", isWebURL); err != nil {
+		return err
+	}
+
+	v.RegisterStructValidation(validateLocation, transfermodels.Location{})
+	v.RegisterStructValidation(validateScheduleException, transfermodels.CreateScheduleExceptionRequest{})
+	v.RegisterStructValidation(validateRestaurantUpdate, transfermodels.UpdateRestaurantRequest{})
+	return nil
+}
+
+func isSlug(fl validator.FieldLevel) bool {
+	return slugRegex.MatchString(fl.Field().String())
+}
+
+// isWebURL checks that the field is an absolute http or https URL. The optional parameter lists the allowed sites separated by spaces,
+// e.g. "twitter.com x.com", and then the host has to be one of them or their subdomain.
+func isWebURL(fl validator.FieldLevel) bool {
+	u, err := url.Parse(fl.Field().String())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+
+	sites := strings.Fields(fl.Param())
+	if len(sites) == 0 {
+		return true
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, site := range sites {
+		if host == site || strings.HasSuffix(host, "."+site) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateLocation requires the latitude and the longitude of a location to be sent together. This is not done with the
+// required_with tag, because the validator does not skip the other rules of nil pointers when omitempty comes after it.
+func validateLocation(sl validator.StructLevel) {
+	location := sl.Current().Interface().(transfermodels.Location)
+
+	if location.Latitude == nil && location.Longitude != nil {
+		sl.ReportError(location.Latitude, "latitude", "Latitude", "required_with", "longitude")
+	}
+
+	if location.Longitude == nil && location.Latitude != nil {
+		sl.ReportError(location.Longitude, "longitude", "Longitude", "required_with", "latitude")
+	}
+}
+
+// validateScheduleException requires the end date of an exception not to be before its start date. The dates are in ISO 8601 format,
+// so they can be compared as strings.
+func validateScheduleException(sl validator.StructLevel) {
+	exception := sl.Current().Interface().(transfermodels.CreateScheduleExceptionRequest)
+
+	if exception.EndDate != "" && exception.EndDate < exception.StartDate {
+		sl.ReportError(exception.EndDate, "end_date", "EndDate", "gtefield", "start_date")
+	}
+}
+
+// validateRestaurantUpdate applies the rules of CreateRestaurantRequest to the price level and the contacts of an update,
+// unless they are empty, which removes them. This is not done with the tags of the fields, because omitempty does not skip
+// empty strings behind non-nil pointers.
+func validateRestaurantUpdate(sl validator.StructLevel) {
+	update := sl.Current().Interface().(transfermodels.UpdateRestaurantRequest)
+
+	validateNonEmpty(sl, update.PriceLevel, "price_level", "PriceLevel", "oneof=$ $$ $$$ $$$$")
+	validateNonEmpty(sl, update.Phone, "phone", "Phone", "e164")
+	validateNonEmpty(sl, update.Website, "website", "Website", "max=200", "weburl")
+}
+
+// validateNonEmpty reports the first of the rules that a non-empty value does not satisfy
+func validateNonEmpty(sl validator.StructLevel, value *string, fieldName, structFieldName string, rules ...string) {
+	if value == nil || *value == "" {
+		return
+	}
+
+	for _, rule := range rules {
+		if sl.Validator().Var(*value, rule) != nil {
+			tag, param := rule, ""
+			if i := strings.Index(rule, "="); i >= 0 {
+				tag, param = rule[:i], rule[i+1:]
+			}
+
+			sl.ReportError(*value, fieldName, structFieldName, tag, param)
+			return
+		}
+	}
+}
+
+// fieldPath returns the path of the field that failed validation without the name of the validated struct, e.g. "hours.0.opens"
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		namespace = namespace[i+1:]
+	}
+
+	namespace = strings.NewReplacer("[", ".", "]", "").Replace(namespace)
+	return strings.ReplaceAll(namespace, embeddedFieldName+".", "")
+}
+
+// validationMessage returns a human-readable explanation of a failed validation rule
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_with":
+		return "is required together with " + fe.Param()
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "weburl":
+		if fe.Param() != "" {
+			return "must be a link to " + strings.Join(strings.Fields(fe.Param()), " or ")
+		}
+
+		return "must be a valid http or https URL"
+	case "e164":
+		return "must be a phone number in the E.164 format, e.g. +359888123456"
+	case "uuid":
+		return "must be a valid UUID"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min", "max", "len":
+		return lengthMessage(fe)
+	case "slug":
+		return "can contain only lowercase letters, digits and hyphens"
+	case "datetime":
+		return "must be formatted as " + dateTimeFormats[fe.Param()]
+	case "gtefield":
+		return "must not be before " + fe.Param()
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
+	case "lte":
+		return fmt.Sprintf("must be less than or equal to %s", fe.Param())
+	default:
+		return fmt.Sprintf("does not satisfy the %s rule", fe.Tag())
+	}
+}
+
+// lengthMessage explains the min, max, and len rules, which limit the length of strings and collections and the value of numbers
+func lengthMessage(fe validator.FieldError) string {
+	bound := map[string]string{"min": "at least", "max": "at most", "len": "exactly"}[fe.Tag()]
+
+	switch fe.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("must be %s %s characters long", bound, fe.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("must contain %s %s items", bound, fe.Param())
+	default:
+		return fmt.Sprintf("must be %s %s", bound, fe.Param())
+	}
+}
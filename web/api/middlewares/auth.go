@@ -9,6 +9,7 @@ import (
 	"github.com/hrist0stoichev/ReviewsSystem/db/models"
 	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
 	"github.com/hrist0stoichev/ReviewsSystem/services"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/problems"
 )
 
 const (
@@ -18,33 +19,49 @@ const (
 	bearerTokenPrefix              = "Bearer"
 	forbiddenErrorMessage          = "Forbidden"
 	unauthorizedErrorMessage       = "Unauthorized"
+	expiredTokenErrorMessage       = "Your token has expired"
+	revokedTokenErrorMessage       = "Your token has been revoked"
+	internalServerErrorMessage     = "Something went wrong"
 	unsupportedAuthorizationMethod = "Unsupported Authorization Method"
 )
 
 type authMiddleware struct {
 	tokensService services.TokensService
+	usersService  services.UsersService
 	logger        log.Logger
 }
 
-func NewAuth(tokensService services.TokensService, logger log.Logger) *authMiddleware {
+func NewAuth(tokensService services.TokensService, usersService services.UsersService, logger log.Logger) *authMiddleware {
 	return &authMiddleware{
 		tokensService: tokensService,
+		usersService:  usersService,
 		logger:        logger,
 	}
 }
 
+// authorizedHandler is a handler that can be accessed only by users with some roles.
+// The roles are exposed, so that they can be documented in the API specification.
+type authorizedHandler struct {
+	http.Handler
+	roles []string
+}
+
+func (ah *authorizedHandler) Roles() []string {
+	return ah.roles
+}
+
 func (ah *authMiddleware) AuthorizeForRoles(roles ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler := func(w http.ResponseWriter, r *http.Request) {
 			reqToken := r.Header.Get(authorizationHeader)
 			if reqToken == "" {
-				http.Error(w, unauthorizedErrorMessage, http.StatusUnauthorized)
+				problems.Error(w, http.StatusUnauthorized, problems.CodeUnauthorized, unauthorizedErrorMessage)
 				return
 			}
 
 			splitToken := strings.Split(reqToken, " ")
 			if len(splitToken) != 2 {
-				http.Error(w, unauthorizedErrorMessage, http.StatusUnauthorized)
+				problems.Error(w, http.StatusUnauthorized, problems.CodeUnauthorized, unauthorizedErrorMessage)
 				return
 			}
 
@@ -52,30 +69,53 @@ func (ah *authMiddleware) AuthorizeForRoles(roles ...string) func(http.Handler)
 			case bearerTokenPrefix:
 				userClaims, err := ah.tokensService.ParseSignedToken(splitToken[1])
 				if err != nil {
-					if err != services.ErrExpiredToken {
-						http.Error(w, "Your token has expired", http.StatusUnauthorized)
+					if err == services.ErrExpiredToken {
+						problems.Error(w, http.StatusUnauthorized, problems.CodeTokenExpired, expiredTokenErrorMessage)
 						return
 					}
 
 					ah.logger.WithError(err).Warnln("could not parse signed token")
-					http.Error(w, unauthorizedErrorMessage, http.StatusUnauthorized)
+					problems.Error(w, http.StatusUnauthorized, problems.CodeUnauthorized, unauthorizedErrorMessage)
+					return
+				}
+
+				// The token version of the user is incremented whenever their sessions are revoked or their role or status is changed
+				user, err := ah.usersService.GetById(userClaims.Id)
+				if err != nil {
+					if err == services.ErrUserNotFound {
+						problems.Error(w, http.StatusUnauthorized, problems.CodeTokenRevoked, revokedTokenErrorMessage)
+						return
+					}
+
+					ah.logger.WithError(err).Warnln("could not get user for token")
+					problems.Error(w, http.StatusInternalServerError, problems.CodeInternal, internalServerErrorMessage)
+					return
+				}
+
+				if user.Disabled || user.TokenVersion != userClaims.Version {
+					problems.Error(w, http.StatusUnauthorized, problems.CodeTokenRevoked, revokedTokenErrorMessage)
 					return
 				}
 
 				if !contains(userClaims.Role, roles...) {
-					http.Error(w, forbiddenErrorMessage, http.StatusForbidden)
+					problems.Error(w, http.StatusForbidden, problems.CodeForbidden, forbiddenErrorMessage)
 					return
 				}
 
 				r.Header.Add(UserIDHeader, userClaims.Id)
 				r.Header.Add(UserRoleHeader, userClaims.Role)
 			default:
-				http.Error(w, unsupportedAuthorizationMethod, http.StatusUnauthorized)
+				problems.Error(w, http.StatusUnauthorized, problems.CodeUnauthorized, unsupportedAuthorizationMethod)
 				return
 			}
 
 			next.ServeHTTP(w, r)
-		})
+		}
+
+		return &authorizedHandler{
+			Handler: http.HandlerFunc(handler),
+			roles:   roles,
+		}
 	}
 }
 
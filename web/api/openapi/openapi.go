@@ -0,0 +1,238 @@
+// Package openapi generates an OpenAPI 3 document from the routes registered in a gorilla/mux router.
+// The paths, the path parameters and the required roles are taken from the router itself, while everything else
+// is described by an Endpoint, whose request and response models are converted to schemas together with their validation rules.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/problems"
+)
+
+const (
+	jsonContentType    = "application/json"
+	bearerAuthScheme   = "bearerAuth"
+	problemSchemaName  = "Problem"
+	defaultDescription = "Error"
+)
+
+var pathParameterRegexp = regexp.MustCompile(`{([^}:]+)(:[^}]+)?}`)
+
+// Endpoint describes the parts of an API operation that cannot be derived from the router
+type Endpoint struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Query       []QueryParam
+	// Request is a value of the type of the request body, nil if the operation has no body
+	Request interface{}
+	// RequestContentType defaults to application/json
+	RequestContentType string
+	// Response is a value of the type of the response body, nil if the operation has no body
+	Response interface{}
+	// Status is the status of a successful response and defaults to 200
+	Status int
+}
+
+// QueryParam is a query parameter of an endpoint. Type is one of the schema types (string, integer, number, boolean).
+type QueryParam struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+}
+
+// RolesHandler is implemented by the handlers that can be accessed only by users with some roles
+type RolesHandler interface {
+	Roles() []string
+}
+
+// Generate builds an OpenAPI document from the routes registered under the prefix of the router. Every route must have a matching endpoint
+// and every endpoint must have a matching route, otherwise an error listing all mismatches is returned. This keeps the document in sync
+// with the router, as the API cannot start with an incomplete specification.
+func Generate(router *mux.Router, prefix string, info Info, endpoints []Endpoint) (*Document, error) {
+	endpointsByKey := make(map[string]Endpoint, len(endpoints))
+	for _, e := range endpoints {
+		endpointsByKey[e.Method+" "+e.Path] = e
+	}
+
+	schemas := newSchemaRegistry()
+	schemas.schemas[problemSchemaName] = schemas.objectSchema(reflect.TypeOf(problems.Problem{}))
+
+	document := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Servers: []Server{{URL: prefix}},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: schemas.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerAuthScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	var missing []string
+	documented := map[string]bool{}
+
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(template, prefix+"/") {
+			return nil
+		}
+
+		// Routes without methods are subrouters
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		path := strings.TrimPrefix(template, prefix)
+
+		for _, method := range methods {
+			if method == http.MethodOptions {
+				continue
+			}
+
+			key := method + " " + path
+
+			endpoint, ok := endpointsByKey[key]
+			if !ok {
+				missing = append(missing, key)
+				continue
+			}
+
+			documented[key] = true
+
+			// OpenAPI paths cannot contain the regular expressions of the mux path variables
+			specPath := pathParameterRegexp.ReplaceAllString(path, "{$1}")
+			if document.Paths[specPath] == nil {
+				document.Paths[specPath] = PathItem{}
+			}
+
+			document.Paths[specPath][strings.ToLower(method)] = newOperation(schemas, endpoint, path, route.GetHandler())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not walk routes")
+	}
+
+	var stale []string
+	for key := range endpointsByKey {
+		if !documented[key] {
+			stale = append(stale, key)
+		}
+	}
+
+	if len(missing) > 0 || len(stale) > 0 {
+		sort.Strings(missing)
+		sort.Strings(stale)
+		return nil, errors.Errorf("the API specification is out of sync with the router: routes without a spec entry [%s], spec entries without a route [%s]",
+			strings.Join(missing, ", "), strings.Join(stale, ", "))
+	}
+
+	return document, nil
+}
+
+func newOperation(schemas *schemaRegistry, endpoint Endpoint, path string, handler http.Handler) *Operation {
+	operation := &Operation{
+		OperationId: operationId(endpoint.Method, path),
+		Summary:     endpoint.Summary,
+		Description: endpoint.Description,
+		Tags:        []string{strings.Split(strings.TrimPrefix(path, "/"), "/")[0]},
+		Responses: map[string]Response{
+			"default": {
+				Description: defaultDescription,
+				Content: map[string]MediaType{
+					problems.ContentType: {Schema: &Schema{Ref: "#/components/schemas/" + problemSchemaName}},
+				},
+			},
+		},
+	}
+
+	for _, match := range pathParameterRegexp.FindAllStringSubmatch(path, -1) {
+		operation.Parameters = append(operation.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+
+	for _, q := range endpoint.Query {
+		operation.Parameters = append(operation.Parameters, Parameter{
+			Name:        q.Name,
+			In:          "query",
+			Description: q.Description,
+			Required:    q.Required,
+			Schema:      &Schema{Type: q.Type},
+		})
+	}
+
+	if endpoint.Request != nil {
+		contentType := endpoint.RequestContentType
+		if contentType == "" {
+			contentType = jsonContentType
+		}
+
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				contentType: {Schema: schemas.schemaOf(reflect.TypeOf(endpoint.Request))},
+			},
+		}
+	}
+
+	status := endpoint.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	response := Response{Description: http.StatusText(status)}
+	if endpoint.Response != nil {
+		response.Content = map[string]MediaType{
+			jsonContentType: {Schema: schemas.schemaOf(reflect.TypeOf(endpoint.Response))},
+		}
+	}
+
+	operation.Responses[strconv.Itoa(status)] = response
+
+	if rolesHandler, ok := handler.(RolesHandler); ok {
+		operation.Roles = rolesHandler.Roles()
+		operation.Security = []map[string][]string{{bearerAuthScheme: {}}}
+		operation.Description = strings.TrimSpace(operation.Description + "\n\nRequired roles: " + strings.Join(operation.Roles, ", "))
+	}
+
+	return operation
+}
+
+// operationId returns a unique id of the operation derived from its method and path, e.g. patchAdminUsersById for PATCH /admin/users/{id}
+func operationId(method, path string) string {
+	id := strings.Builder{}
+	id.WriteString(strings.ToLower(method))
+
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '.' }) {
+		if match := pathParameterRegexp.FindStringSubmatch(segment); match != nil {
+			segment = "by-" + match[1]
+		}
+
+		for _, word := range strings.Split(segment, "-") {
+			if word != "" {
+				id.WriteString(strings.ToUpper(word[:1]) + word[1:])
+			}
+		}
+	}
+
+	return id.String()
+}
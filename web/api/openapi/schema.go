@@ -0,0 +1,188 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaRegistry converts Go types to schemas. Named structs are added to the components of the document and referenced from the other schemas.
+type schemaRegistry struct {
+	schemas map[string]*Schema
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		schemas: map[string]*Schema{},
+	}
+}
+
+func (sr *schemaRegistry) schemaOf(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := sr.schemaOf(t.Elem())
+		// Siblings of $ref are ignored, so references cannot be marked as nullable
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+
+		return schema
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: sr.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: sr.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return sr.objectSchema(t)
+		}
+
+		if _, ok := sr.schemas[t.Name()]; !ok {
+			// Register the name before building the schema, so that recursive types terminate
+			sr.schemas[t.Name()] = nil
+			sr.schemas[t.Name()] = sr.objectSchema(t)
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	default:
+		// interface{} can hold any value
+		return &Schema{}
+	}
+}
+
+func (sr *schemaRegistry) objectSchema(t reflect.Type) *Schema {
+	schema := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{},
+	}
+
+	sr.addFields(schema, t)
+	return schema
+}
+
+// addFields adds the exported fields of the struct to the object schema the way encoding/json serializes them.
+// The fields of embedded structs are promoted to the object itself.
+func (sr *schemaRegistry) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		jsonName := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && jsonName == "" {
+			sr.addFields(schema, field.Type)
+			continue
+		}
+
+		if field.PkgPath != "" || jsonName == "-" {
+			continue
+		}
+
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+
+		fieldSchema := sr.schemaOf(field.Type)
+		if applyValidationRules(fieldSchema, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, jsonName)
+		}
+
+		schema.Properties[jsonName] = fieldSchema
+	}
+}
+
+// applyValidationRules translates the go-playground/validator rules of a field to schema constraints and reports whether the field is required.
+// Rules after dive apply to the items of the field. Rules without an equivalent in the schema (e.g. eqfield, containsany) are not documented.
+func applyValidationRules(schema *Schema, rules string) bool {
+	required := false
+
+	ruleList := strings.Split(rules, ",")
+
+	for i, rule := range ruleList {
+		name, param := rule, ""
+		if eq := strings.Index(rule, "="); eq >= 0 {
+			name, param = rule[:eq], rule[eq+1:]
+		}
+
+		// References cannot have constraints next to them
+		if schema.Ref != "" && name != "required" {
+			continue
+		}
+
+		switch name {
+		case "required":
+			required = true
+		case "dive":
+			if schema.Items != nil {
+				applyValidationRules(schema.Items, strings.Join(ruleList[i+1:], ","))
+			}
+
+			return required
+		case "min", "gte":
+			setBound(schema, param, &schema.Minimum, &schema.MinLength, &schema.MinItems)
+		case "max", "lte":
+			setBound(schema, param, &schema.Maximum, &schema.MaxLength, &schema.MaxItems)
+		case "len":
+			setBound(schema, param, &schema.Minimum, &schema.MinLength, &schema.MinItems)
+			setBound(schema, param, &schema.Maximum, &schema.MaxLength, &schema.MaxItems)
+		case "email":
+			schema.Format = "email"
+		case "url", "uri":
+			schema.Format = "uri"
+		case "uuid", "uuid4":
+			schema.Format = "uuid"
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				if schema.Type == "integer" || schema.Type == "number" {
+					if number, err := strconv.ParseFloat(value, 64); err == nil {
+						schema.Enum = append(schema.Enum, number)
+					}
+
+					continue
+				}
+
+				schema.Enum = append(schema.Enum, value)
+			}
+		}
+	}
+
+	return required
+}
+
+// setBound sets the numeric, length, or items bound of the schema depending on its type
+func setBound(schema *Schema, param string, number **float64, length, items **uint64) {
+	switch schema.Type {
+	case "integer", "number":
+		if value, err := strconv.ParseFloat(param, 64); err == nil {
+			*number = &value
+		}
+	case "string":
+		if value, err := strconv.ParseUint(param, 10, 64); err == nil {
+			*length = &value
+		}
+	case "array":
+		if value, err := strconv.ParseUint(param, 10, 64); err == nil {
+			*items = &value
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package problems
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of the problem details defined in RFC 7807
+const ContentType = "application/problem+json"
+
+// Machine-readable codes of the problems returned by the API. Clients should rely on them instead of the human-readable details.
+const (
+	CodeInternal               = "internal_error"
+	CodeNotFound               = "not_found"
+	CodeInvalidBody            = "invalid_body"
+	CodeValidationFailed       = "validation_failed"
+	CodeInvalidParameter       = "invalid_parameter"
+	CodeMissingParameter       = "missing_parameter"
+	CodeInvalidCursor          = "invalid_cursor"
+	CodeUnauthorized           = "unauthorized"
+	CodeTokenExpired           = "token_expired"
+	CodeTokenRevoked           = "token_revoked"
+	CodeForbidden              = "forbidden"
+	CodeUserExists             = "user_exists"
+	CodeInvalidCredentials     = "invalid_credentials"
+	CodeEmailNotConfirmed      = "email_not_confirmed"
+	CodeEmailAlreadyConfirmed  = "email_already_confirmed"
+	CodeConfirmationExpired    = "confirmation_expired"
+	CodeAccountDisabled        = "account_disabled"
+	CodeInvalidRefreshToken    = "invalid_refresh_token"
+	CodeInvalidResetToken      = "invalid_reset_token"
+	CodeTooManyRequests        = "too_many_requests"
+	CodeAlreadyReviewed        = "already_reviewed"
+	CodeAlreadyAnswered        = "already_answered"
+	CodeCannotChangeOwnAccount = "cannot_change_own_account"
+	CodeUnsupportedMediaType   = "unsupported_media_type"
+	CodeImageTooLarge          = "image_too_large"
+	CodeTooManyImages          = "too_many_images"
+	CodeTagExists              = "tag_exists"
+)
+
+// Problem is an RFC 7807 problem details object. Type is always about:blank, so Title is the HTTP status text,
+// while Code identifies the problem and Detail explains this particular occurrence of it.
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Code   string       `json:"code"`
+	Detail string       `json:"detail,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why a single field of the request body is not valid. Code is the name of the failed validation rule
+// (e.g. required, min, email) and Param is its parameter if it has one.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// New returns a problem with the given status, code and detail
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// Write writes the problem as the response. It replaces the content type set by the middlewares.
+func Write(w http.ResponseWriter, problem *Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+
+	// The status is already sent, so nothing else can be done if the encoding fails
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+// Error writes a problem with the given status, code and detail as the response
+func Error(w http.ResponseWriter, status int, code, detail string) {
+	Write(w, New(status, code, detail))
+}
@@ -1,25 +1,38 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 
 	"github.com/hrist0stoichev/ReviewsSystem/db/models"
 	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
 	"github.com/hrist0stoichev/ReviewsSystem/services"
 	"github.com/hrist0stoichev/ReviewsSystem/web/api/controllers"
 	"github.com/hrist0stoichev/ReviewsSystem/web/api/middlewares"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/openapi"
 )
 
+// apiV1Prefix is the path prefix of the routes documented in the API specification
+const apiV1Prefix = "/api/v1"
+
 func NewRouter(
 	tokensService services.TokensService,
+	usersService services.UsersService,
 	usersController *controllers.Users,
 	restaurantsController *controllers.Restaurants,
 	reviewsController *controllers.Reviews,
+	restaurantImagesController *controllers.RestaurantImages,
+	reviewMediaController *controllers.ReviewMedia,
+	openingHoursController *controllers.OpeningHours,
+	tagsController *controllers.Tags,
+	favoritesController *controllers.Favorites,
+	adminController *controllers.Admin,
 	logger log.Logger,
-) *mux.Router {
-	authMiddleware := middlewares.NewAuth(tokensService, logger)
+) (*mux.Router, error) {
+	authMiddleware := middlewares.NewAuth(tokensService, usersService, logger)
 
 	router := mux.NewRouter()
 	router.Path("/").HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
@@ -34,21 +47,74 @@ func NewRouter(
 	apiV1Router := apiRouter.PathPrefix("/v1").Subrouter()
 	apiV1Router.Use(middlewares.SetCORS, middlewares.SetJsonContentType)
 
+	var spec []byte
+	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/openapi.json").HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		_, _ = res.Write(spec)
+	})
+
 	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/users").HandlerFunc(usersController.Register)
 	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/users/confirm-email").HandlerFunc(usersController.ConfirmEmail)
+	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/users/confirm-email/resend").HandlerFunc(usersController.ResendConfirmationEmail)
+	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/users/password-reset").HandlerFunc(usersController.RequestPasswordReset)
+	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/users/password-reset/confirm").HandlerFunc(usersController.ConfirmPasswordReset)
 	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/token").HandlerFunc(usersController.Login)
+	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/token/refresh").HandlerFunc(usersController.Refresh)
+	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/token/revoke").HandlerFunc(usersController.Revoke)
 
 	apiV1Router.Methods(http.MethodGet).Path("/facebookauth").HandlerFunc(usersController.RedirectToFacebookAuth)
 	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/token/facebook").HandlerFunc(usersController.FacebookLogin)
 
-	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/restaurants").HandlerFunc(authMiddleware.AuthorizeForRoles(models.Owner.String())(http.HandlerFunc(restaurantsController.Create)).ServeHTTP)
-	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/restaurants").HandlerFunc(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Owner.String(), models.Admin.String())(http.HandlerFunc(restaurantsController.ListByRating)).ServeHTTP)
-	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/restaurants/{id}").HandlerFunc(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Owner.String(), models.Admin.String())(http.HandlerFunc(restaurantsController.GetSingle)).ServeHTTP)
-	apiV1Router.Methods(http.MethodDelete, http.MethodOptions).Path("/restaurants/{id}").HandlerFunc(authMiddleware.AuthorizeForRoles(models.Admin.String())(http.HandlerFunc(restaurantsController.Delete)).ServeHTTP)
+	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/restaurants").Handler(authMiddleware.AuthorizeForRoles(models.Owner.String())(http.HandlerFunc(restaurantsController.Create)))
+	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/restaurants").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Owner.String(), models.Admin.String())(http.HandlerFunc(restaurantsController.ListByRating)))
+	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/restaurants/search").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Owner.String(), models.Admin.String())(http.HandlerFunc(restaurantsController.Search)))
+	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/restaurants/nearby").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Owner.String(), models.Admin.String())(http.HandlerFunc(restaurantsController.Nearby)))
+	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/restaurants/{id}").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Owner.String(), models.Admin.String())(http.HandlerFunc(restaurantsController.GetSingle)))
+	apiV1Router.Methods(http.MethodPatch, http.MethodOptions).Path("/restaurants/{id}").Handler(authMiddleware.AuthorizeForRoles(models.Owner.String(), models.Admin.String())(http.HandlerFunc(restaurantsController.Update)))
+	apiV1Router.Methods(http.MethodDelete, http.MethodOptions).Path("/restaurants/{id}").Handler(authMiddleware.AuthorizeForRoles(models.Admin.String())(http.HandlerFunc(restaurantsController.Delete)))
+
+	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/restaurants/{id}/images").Handler(authMiddleware.AuthorizeForRoles(models.Owner.String(), models.Admin.String())(http.HandlerFunc(restaurantImagesController.Upload)))
+	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/restaurants/{id}/images").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Owner.String(), models.Admin.String())(http.HandlerFunc(restaurantImagesController.List)))
+	apiV1Router.Methods(http.MethodPut, http.MethodOptions).Path("/restaurants/{id}/images/{imageId}/cover").Handler(authMiddleware.AuthorizeForRoles(models.Owner.String(), models.Admin.String())(http.HandlerFunc(restaurantImagesController.SetCover)))
+	apiV1Router.Methods(http.MethodDelete, http.MethodOptions).Path("/restaurants/{id}/images/{imageId}").Handler(authMiddleware.AuthorizeForRoles(models.Owner.String(), models.Admin.String())(http.HandlerFunc(restaurantImagesController.Delete)))
+	apiV1Router.Methods(http.MethodGet).Path("/images/{key:.+}").HandlerFunc(restaurantImagesController.Serve)
+
+	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/restaurants/{id}/hours").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Owner.String(), models.Admin.String())(http.HandlerFunc(openingHoursController.Get)))
+	apiV1Router.Methods(http.MethodPut, http.MethodOptions).Path("/restaurants/{id}/hours").Handler(authMiddleware.AuthorizeForRoles(models.Owner.String(), models.Admin.String())(http.HandlerFunc(openingHoursController.Set)))
+	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/restaurants/{id}/hours/exceptions").Handler(authMiddleware.AuthorizeForRoles(models.Owner.String(), models.Admin.String())(http.HandlerFunc(openingHoursController.AddException)))
+	apiV1Router.Methods(http.MethodDelete, http.MethodOptions).Path("/restaurants/{id}/hours/exceptions/{exceptionId}").Handler(authMiddleware.AuthorizeForRoles(models.Owner.String(), models.Admin.String())(http.HandlerFunc(openingHoursController.DeleteException)))
+
+	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/tags").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Owner.String(), models.Admin.String())(http.HandlerFunc(tagsController.List)))
+	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/tags").Handler(authMiddleware.AuthorizeForRoles(models.Admin.String())(http.HandlerFunc(tagsController.Create)))
+	apiV1Router.Methods(http.MethodPatch, http.MethodOptions).Path("/tags/{id}").Handler(authMiddleware.AuthorizeForRoles(models.Admin.String())(http.HandlerFunc(tagsController.Update)))
+	apiV1Router.Methods(http.MethodDelete, http.MethodOptions).Path("/tags/{id}").Handler(authMiddleware.AuthorizeForRoles(models.Admin.String())(http.HandlerFunc(tagsController.Delete)))
+
+	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/me/favorites").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String())(http.HandlerFunc(favoritesController.List)))
+	apiV1Router.Methods(http.MethodPut, http.MethodOptions).Path("/me/favorites/{restaurantId}").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String())(http.HandlerFunc(favoritesController.Add)))
+	apiV1Router.Methods(http.MethodDelete, http.MethodOptions).Path("/me/favorites/{restaurantId}").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String())(http.HandlerFunc(favoritesController.Remove)))
+
+	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/reviews").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String())(http.HandlerFunc(reviewsController.Create)))
+	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/reviews").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Owner.String(), models.Admin.String())(http.HandlerFunc(reviewsController.ListForRestaurant)))
+	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/reviews/search").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Owner.String(), models.Admin.String())(http.HandlerFunc(reviewsController.Search)))
+	apiV1Router.Methods(http.MethodPut, http.MethodOptions).Path("/reviews/{id}").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String())(http.HandlerFunc(reviewsController.Edit)))
+	apiV1Router.Methods(http.MethodDelete, http.MethodOptions).Path("/reviews/{id}").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Admin.String())(http.HandlerFunc(reviewsController.Delete)))
+	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/reviews/{id}/media").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String())(http.HandlerFunc(reviewMediaController.Upload)))
+	apiV1Router.Methods(http.MethodDelete, http.MethodOptions).Path("/reviews/{id}/media/{mediaId}").Handler(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Admin.String())(http.HandlerFunc(reviewMediaController.Delete)))
+	apiV1Router.Methods(http.MethodPut, http.MethodOptions).Path("/reviews/{id}/answer").Handler(authMiddleware.AuthorizeForRoles(models.Owner.String())(http.HandlerFunc(reviewsController.Answer)))
+
+	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/admin/users").Handler(authMiddleware.AuthorizeForRoles(models.Admin.String())(http.HandlerFunc(adminController.ListUsers)))
+	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/admin/users/{id}").Handler(authMiddleware.AuthorizeForRoles(models.Admin.String())(http.HandlerFunc(adminController.GetUser)))
+	apiV1Router.Methods(http.MethodPatch, http.MethodOptions).Path("/admin/users/{id}").Handler(authMiddleware.AuthorizeForRoles(models.Admin.String())(http.HandlerFunc(adminController.UpdateUser)))
+	apiV1Router.Methods(http.MethodDelete, http.MethodOptions).Path("/admin/users/{id}").Handler(authMiddleware.AuthorizeForRoles(models.Admin.String())(http.HandlerFunc(adminController.DeleteUser)))
+
+	// The document is generated after all routes are registered, so that it fails when any of them is not described in the endpoints
+	document, err := openapi.Generate(router, apiV1Prefix, openapi.Info{Title: "ReviewsSystem API", Version: "1.0.0"}, endpoints)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not generate API specification")
+	}
 
-	apiV1Router.Methods(http.MethodPost, http.MethodOptions).Path("/reviews").HandlerFunc(authMiddleware.AuthorizeForRoles(models.Regular.String())(http.HandlerFunc(reviewsController.Create)).ServeHTTP)
-	apiV1Router.Methods(http.MethodGet, http.MethodOptions).Path("/reviews").HandlerFunc(authMiddleware.AuthorizeForRoles(models.Regular.String(), models.Owner.String(), models.Admin.String())(http.HandlerFunc(reviewsController.ListForRestaurant)).ServeHTTP)
-	apiV1Router.Methods(http.MethodPut, http.MethodOptions).Path("/reviews/{id}/answer").HandlerFunc(authMiddleware.AuthorizeForRoles(models.Owner.String())(http.HandlerFunc(reviewsController.Answer)).ServeHTTP)
+	if spec, err = json.Marshal(document); err != nil {
+		return nil, errors.Wrap(err, "could not marshal API specification")
+	}
 
-	return router
+	return router, nil
 }
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hrist0stoichev/ReviewsSystem/lib/log"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/openapi"
+)
+
+// newTestRouter builds the router without services and controllers. The handlers are only registered, never called,
+// so nothing else is needed to check the routes against the specification.
+func newTestRouter(t *testing.T) *mux.Router {
+	logger, err := log.NewLogrus(&log.Config{Level: "error", Format: "text", Output: "stdout"})
+	if err != nil {
+		t.Fatalf("could not create logger: %v", err)
+	}
+
+	router, err := NewRouter(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, logger)
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	return router
+}
+
+func TestSpecificationMatchesRoutes(t *testing.T) {
+	router := newTestRouter(t)
+
+	if _, err := openapi.Generate(router, apiV1Prefix, openapi.Info{}, endpoints); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest(http.MethodGet, apiV1Prefix+"/openapi.json", nil))
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json status = %d, want %d", res.Code, http.StatusOK)
+	}
+
+	document := openapi.Document{}
+	if err := json.Unmarshal(res.Body.Bytes(), &document); err != nil {
+		t.Fatalf("could not decode the served document: %v", err)
+	}
+
+	if len(document.Paths) == 0 {
+		t.Error("the served document has no paths")
+	}
+}
+
+func TestSpecificationRejectsRouteWithoutEndpoint(t *testing.T) {
+	router := newTestRouter(t)
+	removed := endpoints[len(endpoints)-1]
+
+	_, err := openapi.Generate(router, apiV1Prefix, openapi.Info{}, endpoints[:len(endpoints)-1])
+	if err == nil {
+		t.Fatalf("Generate() succeeded without the endpoint of %s %s", removed.Method, removed.Path)
+	}
+
+	if !strings.Contains(err.Error(), removed.Method+" "+removed.Path) {
+		t.Errorf("Generate() error = %v, want it to name %s %s", err, removed.Method, removed.Path)
+	}
+}
+
+func TestSpecificationRejectsEndpointWithoutRoute(t *testing.T) {
+	router := newTestRouter(t)
+	extra := openapi.Endpoint{Method: http.MethodGet, Path: "/not-routed", Summary: "Is not registered in the router"}
+
+	_, err := openapi.Generate(router, apiV1Prefix, openapi.Info{}, append(append([]openapi.Endpoint{}, endpoints...), extra))
+	if err == nil {
+		t.Fatal("Generate() succeeded with an endpoint without a route")
+	}
+
+	if !strings.Contains(err.Error(), "GET /not-routed") {
+		t.Errorf("Generate() error = %v, want it to name GET /not-routed", err)
+	}
+}
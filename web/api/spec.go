@@ -0,0 +1,409 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/hrist0stoichev/ReviewsSystem/db/models"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/controllers"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/openapi"
+	"github.com/hrist0stoichev/ReviewsSystem/web/api/transfermodels"
+)
+
+var paginationParams = []openapi.QueryParam{
+	{Name: "top", Type: "integer", Description: "Number of items to return (1-50, 20 by default)"},
+	{Name: "skip", Type: "integer", Description: "Number of items to skip"},
+}
+
+var cursorParam = openapi.QueryParam{
+	Name:        "cursor",
+	Type:        "string",
+	Description: "Opaque cursor from next_cursor of the previous page. When present (even empty), the response is a CursorPageResponse and skip is ignored",
+}
+
+// subRatingRangeParams limit the average ratings of the listed restaurants per dimension
+var subRatingRangeParams = newSubRatingRangeParams()
+
+func newSubRatingRangeParams() []openapi.QueryParam {
+	var params []openapi.QueryParam
+	for _, dimension := range models.RatingDimensions {
+		minParam, maxParam := controllers.SubRatingParams(dimension)
+		params = append(params,
+			openapi.QueryParam{Name: minParam, Type: "number", Description: "Excludes the restaurants without " + string(dimension) + " ratings"},
+			openapi.QueryParam{Name: maxParam, Type: "number", Description: "Excludes the restaurants without " + string(dimension) + " ratings"},
+		)
+	}
+
+	return params
+}
+
+// restaurantsFilterParams are the filters shared by the listings of restaurants
+var restaurantsFilterParams = append([]openapi.QueryParam{
+	{Name: "minRating", Type: "number"},
+	{Name: "maxRating", Type: "number"},
+	{Name: "city", Type: "string", Description: "Case-insensitive city"},
+	{Name: "cityPrefix", Type: "string", Description: "Case-insensitive beginning of the city"},
+	{Name: "minReviews", Type: "integer"},
+	{Name: "unanswered", Type: "boolean", Description: "Only restaurants with unanswered reviews (owners and admins)"},
+	{Name: "openNow", Type: "boolean", Description: "Only restaurants that are open now in their time zones"},
+	{Name: "openAt", Type: "string", Description: "Only restaurants that are open at an RFC 3339 time, e.g. 2020-06-01T19:30:00+03:00. Overrides openNow"},
+	{Name: "priceLevel", Type: "string", Description: "Comma-separated price levels from $ to $$$$, e.g. $,$$"},
+	{Name: "amenities", Type: "string", Description: "Comma-separated amenities that the restaurants have to offer: wheelchair_access, outdoor_seating, vegan_options, parking"},
+	{Name: "tags", Type: "string", Description: "Comma-separated slugs of cuisines and tags"},
+	{Name: "tagsMatch", Type: "string", Description: "One of any (default), all. Whether the restaurants need any or all of the tags"},
+}, subRatingRangeParams...)
+
+const listEnvelopeDescription = "Clients that accept application/vnd.reviewssystem.list.v2+json get the items wrapped in a ListResponse with the total count and Link headers."
+
+// endpoints describe every route of the v1 API. NewRouter fails if a route is registered without an endpoint here.
+var endpoints = []openapi.Endpoint{
+	{
+		Method:   http.MethodGet,
+		Path:     "/openapi.json",
+		Summary:  "Returns this OpenAPI document",
+		Response: map[string]interface{}{},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/users",
+		Summary:  "Registers a new user and sends them a confirmation email",
+		Request:  transfermodels.CreateUserRequest{},
+		Response: transfermodels.CreateUserResponse{},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/users/confirm-email",
+		Summary: "Confirms the email of a user and redirects to the front-end",
+		Query: []openapi.QueryParam{
+			{Name: "email", Type: "string", Required: true},
+			{Name: "token", Type: "string", Required: true},
+		},
+		Status: http.StatusSeeOther,
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/users/confirm-email/resend",
+		Summary:  "Sends a new confirmation email",
+		Request:  transfermodels.ResendConfirmationEmailRequest{},
+		Response: transfermodels.CreateUserResponse{},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/users/password-reset",
+		Summary:  "Sends a password reset email",
+		Request:  transfermodels.PasswordResetRequest{},
+		Response: transfermodels.PasswordResetResponse{},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/users/password-reset/confirm",
+		Summary:  "Sets a new password using the token from the password reset email",
+		Request:  transfermodels.PasswordResetConfirmRequest{},
+		Response: transfermodels.PasswordResetResponse{},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/token",
+		Summary:  "Logs in with email and password",
+		Request:  transfermodels.LoginRequest{},
+		Response: transfermodels.LoginResponse{},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/token/refresh",
+		Summary:  "Exchanges a refresh token for a new access token and a new refresh token",
+		Request:  transfermodels.RefreshTokenRequest{},
+		Response: transfermodels.LoginResponse{},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/token/revoke",
+		Summary:  "Revokes a refresh token or all sessions of its owner",
+		Request:  transfermodels.RevokeTokenRequest{},
+		Response: transfermodels.RevokeTokenResponse{},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/facebookauth",
+		Summary: "Redirects to the Facebook login page",
+		Status:  http.StatusTemporaryRedirect,
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/token/facebook",
+		Summary:  "Logs in with the code returned by Facebook",
+		Request:  transfermodels.FacebookLoginRequest{},
+		Response: transfermodels.LoginResponse{},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/restaurants",
+		Summary:  "Creates a restaurant owned by the caller",
+		Request:  transfermodels.CreateRestaurantRequest{},
+		Response: transfermodels.RestaurantSimpleResponse{},
+		Status:   http.StatusCreated,
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/restaurants",
+		Summary:     "Lists restaurants. Owners see only their own restaurants",
+		Description: listEnvelopeDescription,
+		Query: append(append(append([]openapi.QueryParam{}, paginationParams...),
+			cursorParam,
+			openapi.QueryParam{Name: "sortBy", Type: "string", Description: "One of rating, reviews, name, newest"},
+			openapi.QueryParam{Name: "sortAsc", Type: "boolean"},
+			openapi.QueryParam{Name: "facets", Type: "boolean", Description: "Adds the counts of the matching restaurants per tag and per city to the response. Without a cursor, the restaurants are then returned in a ListResponse regardless of the Accept header"},
+		), restaurantsFilterParams...),
+		Response: []transfermodels.RestaurantSimpleResponse{},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/restaurants/search",
+		Summary: "Searches restaurants by name, city, and description ordered by relevance",
+		Query: append([]openapi.QueryParam{
+			{Name: "q", Type: "string", Required: true, Description: "Query in web search syntax"},
+			{Name: "city", Type: "string"},
+		}, paginationParams...),
+		Response: []transfermodels.RestaurantSearchResponse{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/restaurants/nearby",
+		Summary:     "Lists the restaurants around a location ordered by distance, the closest first",
+		Description: listEnvelopeDescription,
+		Query: append(append([]openapi.QueryParam{
+			{Name: "lat", Type: "number", Required: true, Description: "Latitude between -90 and 90"},
+			{Name: "lng", Type: "number", Required: true, Description: "Longitude between -180 and 180"},
+			{Name: "radiusKm", Type: "number", Description: "Radius in kilometers, 5 by default and at most 100"},
+		}, paginationParams...), restaurantsFilterParams...),
+		Response: []transfermodels.RestaurantNearbyResponse{},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/restaurants/{id}",
+		Summary:  "Returns a restaurant with its lowest and highest rated reviews",
+		Response: transfermodels.RestaurantDetailedResponse{},
+	},
+	{
+		Method:   http.MethodPatch,
+		Path:     "/restaurants/{id}",
+		Summary:  "Updates the fields of a restaurant that are present in the request",
+		Request:  transfermodels.UpdateRestaurantRequest{},
+		Response: transfermodels.RestaurantDetailedResponse{},
+	},
+	{
+		Method:  http.MethodDelete,
+		Path:    "/restaurants/{id}",
+		Summary: "Deletes a restaurant with its reviews or hides it when soft is true",
+		Query: []openapi.QueryParam{
+			{Name: "soft", Type: "boolean"},
+		},
+		Response: transfermodels.RestaurantDeleteResponse{},
+	},
+	{
+		Method:             http.MethodPost,
+		Path:               "/restaurants/{id}/images",
+		Summary:            "Uploads a JPEG, PNG or GIF photo of a restaurant",
+		Description:        "The image is re-encoded without its metadata and a thumbnail is generated. The first image of a restaurant becomes its cover image.",
+		Request:            transfermodels.UploadRestaurantImageRequest{},
+		RequestContentType: "multipart/form-data",
+		Response:           transfermodels.RestaurantImageResponse{},
+		Status:             http.StatusCreated,
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/restaurants/{id}/images",
+		Summary:  "Returns the photos of a restaurant with the cover image first",
+		Response: []transfermodels.RestaurantImageResponse{},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/restaurants/{id}/images/{imageId}/cover",
+		Summary:  "Makes a photo the cover image of its restaurant",
+		Response: transfermodels.RestaurantImageResponse{},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/restaurants/{id}/images/{imageId}",
+		Summary:  "Deletes a photo of a restaurant",
+		Response: transfermodels.RestaurantImageDeleteResponse{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/images/{key:.+}",
+		Summary:     "Returns an image file",
+		Description: "The URLs of the images and their thumbnails are returned by the restaurant images endpoints. The files never change, so they can be cached.",
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/restaurants/{id}/hours",
+		Summary:  "Returns the time zone, the weekly opening hours and the schedule exceptions of a restaurant",
+		Response: transfermodels.OpeningHoursResponse{},
+	},
+	{
+		Method:      http.MethodPut,
+		Path:        "/restaurants/{id}/hours",
+		Summary:     "Replaces the weekly opening hours of a restaurant and sets its time zone",
+		Description: "Times are in the HH:MM format. An interval that does not close after it opens ends on the next day, e.g. 18:00-02:00, and one that closes when it opens lasts 24 hours.",
+		Request:     transfermodels.SetOpeningHoursRequest{},
+		Response:    transfermodels.OpeningHoursResponse{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        "/restaurants/{id}/hours/exceptions",
+		Summary:     "Replaces the weekly opening hours between two dates, e.g. for holidays or temporary closures",
+		Description: "The restaurant is closed on these dates unless opens and closes are sent. The hours of all exceptions covering a date are combined.",
+		Request:     transfermodels.CreateScheduleExceptionRequest{},
+		Response:    transfermodels.ScheduleExceptionResponse{},
+		Status:      http.StatusCreated,
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/restaurants/{id}/hours/exceptions/{exceptionId}",
+		Summary:  "Deletes a schedule exception of a restaurant",
+		Response: transfermodels.ScheduleExceptionDeleteResponse{},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/tags",
+		Summary:  "Lists the cuisines and tags that restaurants can be categorized with",
+		Response: []transfermodels.TagResponse{},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/tags",
+		Summary:  "Adds a cuisine or a tag to the taxonomy",
+		Request:  transfermodels.CreateTagRequest{},
+		Response: transfermodels.TagResponse{},
+		Status:   http.StatusCreated,
+	},
+	{
+		Method:   http.MethodPatch,
+		Path:     "/tags/{id}",
+		Summary:  "Edits a cuisine or a tag",
+		Request:  transfermodels.UpdateTagRequest{},
+		Response: transfermodels.TagResponse{},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/tags/{id}",
+		Summary:  "Deletes a cuisine or a tag and removes it from all restaurants",
+		Response: transfermodels.TagDeleteResponse{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/me/favorites",
+		Summary:     "Lists the favorite restaurants of the caller, the most recently saved first",
+		Description: listEnvelopeDescription,
+		Query:       paginationParams,
+		Response:    []transfermodels.RestaurantFavoriteResponse{},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/me/favorites/{restaurantId}",
+		Summary:  "Saves a restaurant as a favorite of the caller. Saving it again has no effect",
+		Response: transfermodels.FavoriteResponse{},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/me/favorites/{restaurantId}",
+		Summary:  "Removes a restaurant from the favorites of the caller. Removing a restaurant that is not a favorite has no effect",
+		Response: transfermodels.FavoriteResponse{},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/reviews",
+		Summary:  "Reviews a restaurant",
+		Request:  transfermodels.CreateReviewRequest{},
+		Response: transfermodels.ReviewSimpleResponse{},
+		Status:   http.StatusCreated,
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/reviews",
+		Summary:     "Lists the reviews of a restaurant",
+		Description: listEnvelopeDescription,
+		Query: append(append([]openapi.QueryParam{
+			{Name: "restaurantId", Type: "string", Required: true},
+		}, paginationParams...),
+			cursorParam,
+			openapi.QueryParam{Name: "unanswered", Type: "boolean"},
+			openapi.QueryParam{Name: "orderBy", Type: "string", Description: "One of timestamp, rating, hasAnswer"},
+			openapi.QueryParam{Name: "orderByAsc", Type: "boolean"},
+		),
+		Response: []transfermodels.ReviewSimpleResponse{},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/reviews/search",
+		Summary: "Searches the reviews of a restaurant ordered by relevance",
+		Query: append([]openapi.QueryParam{
+			{Name: "restaurantId", Type: "string", Required: true},
+			{Name: "q", Type: "string", Required: true, Description: "Query in web search syntax"},
+		}, paginationParams...),
+		Response: []transfermodels.ReviewSearchResponse{},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/reviews/{id}",
+		Summary:  "Edits a review of the caller",
+		Request:  transfermodels.EditReviewRequest{},
+		Response: transfermodels.ReviewSimpleResponse{},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/reviews/{id}",
+		Summary:  "Deletes a review",
+		Response: transfermodels.ReviewDeleteResponse{},
+	},
+	{
+		Method:             http.MethodPost,
+		Path:               "/reviews/{id}/media",
+		Summary:            "Attaches a JPEG, PNG or GIF photo to a review of the user",
+		Description:        "The photo is re-encoded without its metadata and a thumbnail is generated.",
+		Request:            transfermodels.UploadReviewMediaRequest{},
+		RequestContentType: "multipart/form-data",
+		Response:           transfermodels.ReviewMediaResponse{},
+		Status:             http.StatusCreated,
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/reviews/{id}/media/{mediaId}",
+		Summary:  "Removes a photo from a review",
+		Response: transfermodels.ReviewMediaDeleteResponse{},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/reviews/{id}/answer",
+		Summary:  "Answers a review of a restaurant of the caller",
+		Request:  transfermodels.AnswerReviewRequest{},
+		Response: transfermodels.ReviewSimpleResponse{},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/admin/users",
+		Summary: "Lists users",
+		Query: append([]openapi.QueryParam{
+			{Name: "email", Type: "string", Description: "Part of the email"},
+		}, paginationParams...),
+		Response: []transfermodels.UserResponse{},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/admin/users/{id}",
+		Summary:  "Returns a user",
+		Response: transfermodels.UserResponse{},
+	},
+	{
+		Method:   http.MethodPatch,
+		Path:     "/admin/users/{id}",
+		Summary:  "Changes the role of a user or disables their account",
+		Request:  transfermodels.UpdateUserRequest{},
+		Response: transfermodels.UserResponse{},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/admin/users/{id}",
+		Summary:  "Deletes a user with their restaurants and reviews",
+		Response: transfermodels.UserDeleteResponse{},
+	},
+}
@@ -0,0 +1,45 @@
+package transfermodels
+
+// SetOpeningHoursRequest replaces the weekly schedule of a restaurant. The times are in the given IANA time zone, e.g. Europe/Sofia.
+type SetOpeningHoursRequest struct {
+	TimeZone string                 `json:"time_zone" validate:"required,max=64"`
+	Hours    []OpeningHoursInterval `json:"hours" validate:"max=50,dive"`
+}
+
+// OpeningHoursInterval is an interval of the weekly schedule. When it does not close after it opens, it ends on the next day,
+// e.g. 18:00-02:00, and when it closes when it opens, it lasts 24 hours.
+type OpeningHoursInterval struct {
+	Day    string `json:"day" validate:"required,oneof=sunday monday tuesday wednesday thursday friday saturday"`
+	Opens  string `json:"opens" validate:"required,datetime=15:04"`
+	Closes string `json:"closes" validate:"required,datetime=15:04"`
+}
+
+// CreateScheduleExceptionRequest replaces the weekly schedule between two dates, e.g. for holidays or temporary closures.
+// The restaurant is closed on these dates unless opens and closes are sent. The end date is the start date by default.
+type CreateScheduleExceptionRequest struct {
+	StartDate string `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate   string `json:"end_date" validate:"omitempty,datetime=2006-01-02"`
+	Opens     string `json:"opens" validate:"required_with=Closes,omitempty,datetime=15:04"`
+	Closes    string `json:"closes" validate:"required_with=Opens,omitempty,datetime=15:04"`
+	Reason    string `json:"reason" validate:"max=100"`
+}
+
+type ScheduleExceptionResponse struct {
+	Id        string  `json:"id"`
+	StartDate string  `json:"start_date"`
+	EndDate   string  `json:"end_date"`
+	Opens     *string `json:"opens"`
+	Closes    *string `json:"closes"`
+	Reason    string  `json:"reason"`
+}
+
+type OpeningHoursResponse struct {
+	TimeZone string                 `json:"time_zone"`
+	Hours    []OpeningHoursInterval `json:"hours"`
+	// Exceptions are ordered by their start date
+	Exceptions []ScheduleExceptionResponse `json:"exceptions"`
+}
+
+type ScheduleExceptionDeleteResponse struct {
+	OK bool `json:"ok"`
+}
@@ -0,0 +1,22 @@
+package transfermodels
+
+// CursorPageResponse is returned by the list endpoints when the client paginates with cursors.
+// NextCursor is null on the last page.
+type CursorPageResponse struct {
+	Items      interface{} `json:"items"`
+	NextCursor *string     `json:"next_cursor"`
+	// Facets are the same as in ListResponse
+	Facets interface{} `json:"facets,omitempty"`
+}
+
+// ListResponse is the versioned envelope of the list endpoints, returned when the client opts in for it with the Accept header
+// or asks for facets.
+// Total is the number of items matching the filters of the request regardless of the pagination.
+type ListResponse struct {
+	Items interface{} `json:"items"`
+	Total int64       `json:"total"`
+	Top   uint64      `json:"top"`
+	Skip  uint64      `json:"skip"`
+	// Facets summarize all items matching the filters, e.g. the numbers of restaurants per tag. Only some endpoints return them.
+	Facets interface{} `json:"facets,omitempty"`
+}
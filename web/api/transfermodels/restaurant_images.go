@@ -0,0 +1,22 @@
+package transfermodels
+
+// UploadRestaurantImageRequest describes the multipart form of an image upload. It is not decoded from JSON.
+type UploadRestaurantImageRequest struct {
+	// Image is a JPEG, PNG or GIF file
+	Image []byte `json:"image" validate:"required"`
+	// Cover makes the image the cover image of the restaurant. The first image of a restaurant is always its cover.
+	Cover bool `json:"cover"`
+}
+
+type RestaurantImageResponse struct {
+	Id           string `json:"id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	IsCover      bool   `json:"is_cover"`
+}
+
+type RestaurantImageDeleteResponse struct {
+	OK bool `json:"ok"`
+}
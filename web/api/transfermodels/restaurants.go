@@ -1,35 +1,135 @@
 package transfermodels
 
+import (
+	"time"
+)
+
 type CreateRestaurantRequest struct {
 	Name        string `json:"name" validate:"required,min=5,max=60"`
 	City        string `json:"city" validate:"required,min=5,max=30"`
 	Address     string `json:"address" validate:"required,min=5,max=100"`
-	Img         string `json:"img" validate:"required,url"`
+	Img         string `json:"img" validate:"omitempty,url"`
 	Description string `json:"description" validate:"required,min=30,max=500"`
+	Location
+	// Tags are the slugs of the tags of the restaurant
+	Tags       []string  `json:"tags" validate:"max=20,dive,required,max=40"`
+	PriceLevel string    `json:"price_level" validate:"omitempty,oneof=$ $$ $$$ $$$$"`
+	Amenities  Amenities `json:"amenities"`
+	ContactDetails
+}
+
+// UpdateRestaurantRequest has the same validation rules as CreateRestaurantRequest, but all fields are optional.
+// Only the fields that are present in the request are changed. The location cannot be removed once it is set.
+type UpdateRestaurantRequest struct {
+	Name        *string `json:"name" validate:"omitempty,min=5,max=60"`
+	City        *string `json:"city" validate:"omitempty,min=5,max=30"`
+	Address     *string `json:"address" validate:"omitempty,min=5,max=100"`
+	Img         *string `json:"img" validate:"omitempty,url"`
+	Description *string `json:"description" validate:"omitempty,min=30,max=500"`
+	Location
+	// Tags replace all tags of the restaurant when present
+	Tags *[]string `json:"tags" validate:"omitempty,max=20,dive,required,max=40"`
+	// PriceLevel, Phone and Website are removed when they are empty. Otherwise, they are validated by a struct-level validation,
+	// as the validator does not skip empty values of pointers.
+	PriceLevel *string `json:"price_level"`
+	Phone      *string `json:"phone"`
+	Website    *string `json:"website"`
+	// Amenities and SocialLinks replace all amenities and social links of the restaurant when present
+	Amenities   *Amenities   `json:"amenities"`
+	SocialLinks *SocialLinks `json:"social_links"`
+}
+
+// Location is the optional location of a restaurant. The latitude and the longitude have to be sent together.
+type Location struct {
+	Latitude  *float64 `json:"latitude" validate:"omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" validate:"omitempty,min=-180,max=180"`
+}
+
+// Amenities tell which amenities a restaurant offers
+type Amenities struct {
+	WheelchairAccess bool `json:"wheelchair_access"`
+	OutdoorSeating   bool `json:"outdoor_seating"`
+	VeganOptions     bool `json:"vegan_options"`
+	Parking          bool `json:"parking"`
+}
+
+// ContactDetails are the optional contacts of a restaurant. The phone is in the E.164 format, e.g. +359888123456.
+type ContactDetails struct {
+	Phone       string      `json:"phone" validate:"omitempty,e164"`
+	Website     string      `json:"website" validate:"omitempty,max=200,weburl"`
+	SocialLinks SocialLinks `json:"social_links"`
+}
+
+// SocialLinks are the profiles of a restaurant in social networks. Each link has to point to the site of its network.
+type SocialLinks struct {
+	Facebook  string `json:"facebook" validate:"omitempty,max=200,weburl=facebook.com"`
+	Instagram string `json:"instagram" validate:"omitempty,max=200,weburl=instagram.com"`
+	Twitter   string `json:"twitter" validate:"omitempty,max=200,weburl=twitter.com x.com"`
 }
 
 type RestaurantSimpleResponse struct {
-	Id            string  `json:"id"`
-	Name          string  `json:"name"`
-	City          string  `json:"city"`
-	Address       string  `json:"address"`
-	Img           string  `json:"img"`
-	Description   string  `json:"description"`
-	AverageRating float32 `json:"average_rating"`
+	Id            string   `json:"id"`
+	Name          string   `json:"name"`
+	City          string   `json:"city"`
+	Address       string   `json:"address"`
+	Img           string   `json:"img"`
+	Description   string   `json:"description"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+	PriceLevel    *string  `json:"price_level"`
+	AverageRating float32  `json:"average_rating"`
+	SubRatingAverages
+	Tags       []TagResponse `json:"tags"`
+	IsFavorite bool          `json:"is_favorite"`
+}
+
+type RestaurantSearchResponse struct {
+	RestaurantSimpleResponse
+	Rank float32 `json:"rank"`
+	// Snippet contains HTML escaped fragments of the description with the matched words surrounded by <mark> tags
+	Snippet string `json:"snippet"`
+}
+
+type RestaurantNearbyResponse struct {
+	RestaurantSimpleResponse
+	DistanceKm float64 `json:"distance_km"`
 }
 
 type RestaurantDetailedResponse struct {
-	Id            string                `json:"id"`
-	Name          string                `json:"name"`
-	City          string                `json:"city"`
-	Address       string                `json:"address"`
-	Img           string                `json:"img"`
-	Description   string                `json:"description"`
-	AverageRating float32               `json:"average_rating"`
-	MinReview     *ReviewSimpleResponse `json:"min_review"`
-	MaxReview     *ReviewSimpleResponse `json:"max_review"`
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	City        string    `json:"city"`
+	Address     string    `json:"address"`
+	Img         string    `json:"img"`
+	Description string    `json:"description"`
+	Latitude    *float64  `json:"latitude"`
+	Longitude   *float64  `json:"longitude"`
+	TimeZone    string    `json:"time_zone"`
+	PriceLevel  *string   `json:"price_level"`
+	Amenities   Amenities `json:"amenities"`
+	ContactDetails
+	AverageRating float32 `json:"average_rating"`
+	SubRatingAverages
+	Tags       []TagResponse         `json:"tags"`
+	IsFavorite bool                  `json:"is_favorite"`
+	MinReview  *ReviewSimpleResponse `json:"min_review"`
+	MaxReview  *ReviewSimpleResponse `json:"max_review"`
+	// Images are ordered with the cover image first
+	Images []RestaurantImageResponse `json:"images"`
+}
+
+type RestaurantFavoriteResponse struct {
+	RestaurantSimpleResponse
+	FavoritedAt time.Time `json:"favorited_at"`
+}
+
+type FavoriteResponse struct {
+	RestaurantId string `json:"restaurant_id"`
+	IsFavorite   bool   `json:"is_favorite"`
 }
 
 type RestaurantDeleteResponse struct {
-	OK bool `json:"ok"`
+	OK             bool  `json:"ok"`
+	Soft           bool  `json:"soft"`
+	DeletedReviews int64 `json:"deleted_reviews"`
 }
@@ -0,0 +1,19 @@
+package transfermodels
+
+// UploadReviewMediaRequest describes the multipart form of a review photo upload. It is not decoded from JSON.
+type UploadReviewMediaRequest struct {
+	// Image is a JPEG, PNG or GIF file
+	Image []byte `json:"image" validate:"required"`
+}
+
+type ReviewMediaResponse struct {
+	Id           string `json:"id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+type ReviewMediaDeleteResponse struct {
+	OK bool `json:"ok"`
+}
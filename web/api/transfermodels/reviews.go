@@ -8,17 +8,39 @@ type CreateReviewRequest struct {
 	RestaurantId string `json:"restaurant_id" validate:"required,uuid"`
 	Rating       uint8  `json:"rating" validate:"required,min=1,max=5"`
 	Comment      string `json:"comment" validate:"required,min=30,max=300"`
+	SubRatings
+}
+
+// EditReviewRequest replaces all ratings of the review, so the sub-ratings that are not present are removed
+type EditReviewRequest struct {
+	Rating  uint8  `json:"rating" validate:"required,min=1,max=5"`
+	Comment string `json:"comment" validate:"required,min=30,max=300"`
+	SubRatings
 }
 
 type ReviewSimpleResponse struct {
-	Id        string    `json:"id"`
-	Reviewer  string    `json:"reviewer"`
-	Rating    uint8     `json:"rating"`
+	Id       string `json:"id"`
+	Reviewer string `json:"reviewer"`
+	Rating   uint8  `json:"rating"`
+	SubRatings
 	Timestamp time.Time `json:"timestamp"`
 	Comment   string    `json:"comment"`
 	Answer    *string   `json:"answer"`
+	// Media are returned only in the reviews of a restaurant and in its min and max reviews. They are omitted when there are none.
+	Media []ReviewMediaResponse `json:"media,omitempty"`
+}
+
+type ReviewSearchResponse struct {
+	ReviewSimpleResponse
+	Rank float32 `json:"rank"`
+	// Snippet contains HTML escaped fragments of the comment with the matched words surrounded by <mark> tags
+	Snippet string `json:"snippet"`
 }
 
 type AnswerReviewRequest struct {
 	Answer string `json:"answer" validate:"required,min=30,max=300"`
 }
+
+type ReviewDeleteResponse struct {
+	OK bool `json:"ok"`
+}
@@ -0,0 +1,17 @@
+package transfermodels
+
+// SubRatings are the optional ratings of a review per dimension. They are null when the reviewer did not rate the dimension.
+type SubRatings struct {
+	FoodRating     *uint8 `json:"food_rating" validate:"omitempty,min=1,max=5"`
+	ServiceRating  *uint8 `json:"service_rating" validate:"omitempty,min=1,max=5"`
+	AmbienceRating *uint8 `json:"ambience_rating" validate:"omitempty,min=1,max=5"`
+	ValueRating    *uint8 `json:"value_rating" validate:"omitempty,min=1,max=5"`
+}
+
+// SubRatingAverages are the average ratings of a restaurant per dimension. They are null when no review has rated the dimension.
+type SubRatingAverages struct {
+	FoodAverageRating     *float32 `json:"food_average_rating"`
+	ServiceAverageRating  *float32 `json:"service_average_rating"`
+	AmbienceAverageRating *float32 `json:"ambience_average_rating"`
+	ValueAverageRating    *float32 `json:"value_average_rating"`
+}
@@ -0,0 +1,44 @@
+package transfermodels
+
+// CreateTagRequest adds a tag to the taxonomy. The slug identifies the tag in the restaurant requests and filters,
+// so it can contain only lowercase letters, digits and hyphens.
+type CreateTagRequest struct {
+	Slug string `json:"slug" validate:"required,max=40,slug"`
+	Name string `json:"name" validate:"required,max=60"`
+	Kind string `json:"kind" validate:"required,oneof=cuisine tag"`
+}
+
+// UpdateTagRequest has the same validation rules as CreateTagRequest, but all fields are optional.
+type UpdateTagRequest struct {
+	Slug *string `json:"slug" validate:"omitempty,max=40,slug"`
+	Name *string `json:"name" validate:"omitempty,max=60"`
+	Kind *string `json:"kind" validate:"omitempty,oneof=cuisine tag"`
+}
+
+type TagResponse struct {
+	Id   string `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+type TagDeleteResponse struct {
+	OK bool `json:"ok"`
+}
+
+// RestaurantFacetsResponse contains the numbers of restaurants matching the filters of a listing per tag and per city,
+// the most common first. Only the 50 most common cities are returned.
+type RestaurantFacetsResponse struct {
+	Tags   []TagFacetResponse  `json:"tags"`
+	Cities []CityFacetResponse `json:"cities"`
+}
+
+type TagFacetResponse struct {
+	TagResponse
+	Count int64 `json:"count"`
+}
+
+type CityFacetResponse struct {
+	City  string `json:"city"`
+	Count int64  `json:"count"`
+}
@@ -21,9 +21,70 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+type FacebookLoginRequest struct {
+	State string `json:"state"`
+	Code  string `json:"code"`
+}
+
 type LoginResponse struct {
-	Token   string    `json:"token"`
-	Expires time.Time `json:"expires"`
-	Email   string    `json:"email"`
-	Role    string    `json:"role"`
+	Token               string    `json:"token"`
+	Expires             time.Time `json:"expires"`
+	RefreshToken        string    `json:"refresh_token"`
+	RefreshTokenExpires time.Time `json:"refresh_token_expires"`
+	Email               string    `json:"email"`
+	Role                string    `json:"role"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// RevokeTokenRequest revokes the given refresh token. If All is set, all sessions of the token owner are revoked,
+// including the access tokens that have already been issued.
+type RevokeTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+	All          bool   `json:"all"`
+}
+
+type RevokeTokenResponse struct {
+	OK bool `json:"ok"`
+}
+
+type UserResponse struct {
+	Id             string `json:"id"`
+	Email          string `json:"email"`
+	EmailConfirmed bool   `json:"email_confirmed"`
+	Role           string `json:"role"`
+	Disabled       bool   `json:"disabled"`
+}
+
+// UpdateUserRequest is used by admins to change the role of a user or to disable their account.
+// Only the fields that are present in the request are changed.
+type UpdateUserRequest struct {
+	Role     *string `json:"role" validate:"omitempty,oneof=regular owner admin"`
+	Disabled *bool   `json:"disabled"`
+}
+
+type UserDeleteResponse struct {
+	OK bool `json:"ok"`
+}
+
+type ResendConfirmationEmailRequest struct {
+	Email string `json:"email" validate:"required,email,max=64"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email,max=64"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Email string `json:"email" validate:"required,email,max=64"`
+	Token string `json:"token" validate:"required"`
+	// The password has the same requirements as the one in CreateUserRequest
+	Password        string `json:"password" validate:"required,min=8,max=64,containsany=abcdefghijklmnopqrstuvwxyz,containsany=ABCDEFGHIJKLMNOPQRSTUVWXYZ,containsany=!@#$%^&*()_-+<>?,containsany=0123456789,eqfield=ConfirmPassword"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+type PasswordResetResponse struct {
+	Ok bool `json:"ok"`
 }